@@ -0,0 +1,5 @@
+// Package api is the gRPC API of mini-mnist-serving, which is served by controller.GRPCServer.
+// The messages and the client are generated from proto/mnist_serving.proto.
+package api
+
+//go:generate protoc -I proto --go_out=. --go_opt=module=github.com/josh9191/mini-mnist-serving/api --go-grpc_out=. --go-grpc_opt=module=github.com/josh9191/mini-mnist-serving/api mnist_serving.proto
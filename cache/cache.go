@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/josh9191/mini-mnist-serving/clients"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+// subscriberChanSize is the number of changed objects buffered for each subscriber
+const subscriberChanSize = 100
+
+// Cache keeps Deployments, ReplicaSets, Services, Ingresses, Pods and ConfigMaps of the model namespaces in memory
+// so that the status of models is read without calling the API server.
+type Cache struct {
+	factories         map[string]informers.SharedInformerFactory
+	ingressAPIVersion string
+
+	subscribersMu sync.Mutex
+	subscribers   map[chan metav1.Object]bool
+}
+
+// NewCache returns Cache of namespaces which reads Ingress objects of ingressAPIVersion
+// Start should be called before reading the cache.
+func NewCache(kubeClientSet kubernetes.Interface, ingressAPIVersion string, namespaces ...string) *Cache {
+	c := &Cache{
+		factories:         make(map[string]informers.SharedInformerFactory),
+		ingressAPIVersion: ingressAPIVersion,
+		subscribers:       make(map[chan metav1.Object]bool),
+	}
+	handler := toolscache.ResourceEventHandlerFuncs{
+		AddFunc: c.notify,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			c.notify(newObj)
+		},
+		DeleteFunc: c.notify,
+	}
+
+	for _, namespace := range namespaces {
+		// resync is not needed because the cache is never modified by the server
+		factory := informers.NewSharedInformerFactoryWithOptions(kubeClientSet, 0, informers.WithNamespace(namespace))
+		factory.Apps().V1().Deployments().Informer().AddEventHandler(handler)
+		factory.Apps().V1().ReplicaSets().Informer().AddEventHandler(handler)
+		factory.Core().V1().Services().Informer().AddEventHandler(handler)
+		factory.Core().V1().Pods().Informer().AddEventHandler(handler)
+		factory.Core().V1().ConfigMaps().Informer().AddEventHandler(handler)
+		c.ingressInformer(factory).AddEventHandler(handler)
+		c.factories[namespace] = factory
+	}
+	return c
+}
+
+// Start lists and watches the objects until stopCh is closed
+func (c *Cache) Start(stopCh <-chan struct{}) {
+	for _, factory := range c.factories {
+		factory.Start(stopCh)
+	}
+}
+
+// WaitForCacheSync waits until the objects are listed, and reports whether every informer is synced
+func (c *Cache) WaitForCacheSync(stopCh <-chan struct{}) bool {
+	for _, factory := range c.factories {
+		for _, synced := range factory.WaitForCacheSync(stopCh) {
+			if !synced {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// GetDeployment returns the cached Deployment, which must not be modified
+func (c *Cache) GetDeployment(namespace string, name string) (*appsv1.Deployment, error) {
+	factory, err := c.factory(namespace)
+	if err != nil {
+		return nil, err
+	}
+	return factory.Apps().V1().Deployments().Lister().Deployments(namespace).Get(name)
+}
+
+// ListReplicaSets returns the cached ReplicaSets matching selector, which must not be modified
+func (c *Cache) ListReplicaSets(namespace string, selector labels.Selector) ([]*appsv1.ReplicaSet, error) {
+	factory, err := c.factory(namespace)
+	if err != nil {
+		return nil, err
+	}
+	return factory.Apps().V1().ReplicaSets().Lister().ReplicaSets(namespace).List(selector)
+}
+
+// ListPods returns the cached Pods matching selector, which must not be modified
+func (c *Cache) ListPods(namespace string, selector labels.Selector) ([]*apiv1.Pod, error) {
+	factory, err := c.factory(namespace)
+	if err != nil {
+		return nil, err
+	}
+	return factory.Core().V1().Pods().Lister().Pods(namespace).List(selector)
+}
+
+// GetConfigMap returns the cached ConfigMap, which must not be modified
+func (c *Cache) GetConfigMap(namespace string, name string) (*apiv1.ConfigMap, error) {
+	factory, err := c.factory(namespace)
+	if err != nil {
+		return nil, err
+	}
+	return factory.Core().V1().ConfigMaps().Lister().ConfigMaps(namespace).Get(name)
+}
+
+// GetIngress returns the fields of cached Ingress
+func (c *Cache) GetIngress(namespace string, name string) (*clients.Ingress, error) {
+	factory, err := c.factory(namespace)
+	if err != nil {
+		return nil, err
+	}
+	if c.ingressAPIVersion == clients.ExtensionsV1beta1 {
+		result, err := factory.Extensions().V1beta1().Ingresses().Lister().Ingresses(namespace).Get(name)
+		if err != nil {
+			return nil, err
+		}
+		return clients.IngressFromExtensionsV1beta1(result), nil
+	}
+	result, err := factory.Networking().V1().Ingresses().Lister().Ingresses(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return clients.IngressFromNetworkingV1(result), nil
+}
+
+// IngressClient returns IngressClient reading Ingress objects from the cache
+// Objects are still created, updated and deleted through client.
+func (c *Cache) IngressClient(client clients.IngressClient) clients.IngressClient {
+	return &cachedIngressClient{client, c}
+}
+
+// Subscribe returns the channel receiving the objects added, updated or deleted in the cache
+// until unsubscribe is called. Objects are dropped while the channel is full.
+func (c *Cache) Subscribe() (objects <-chan metav1.Object, unsubscribe func()) {
+	ch := make(chan metav1.Object, subscriberChanSize)
+	c.subscribersMu.Lock()
+	c.subscribers[ch] = true
+	c.subscribersMu.Unlock()
+
+	return ch, func() {
+		c.subscribersMu.Lock()
+		delete(c.subscribers, ch)
+		c.subscribersMu.Unlock()
+	}
+}
+
+func (c *Cache) notify(obj interface{}) {
+	// the final state of object deleted while the watch is disconnected
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	object, ok := obj.(metav1.Object)
+	if !ok {
+		return
+	}
+
+	c.subscribersMu.Lock()
+	defer c.subscribersMu.Unlock()
+	for ch := range c.subscribers {
+		select {
+		case ch <- object:
+		default:
+		}
+	}
+}
+
+func (c *Cache) factory(namespace string) (informers.SharedInformerFactory, error) {
+	factory, ok := c.factories[namespace]
+	if !ok {
+		return nil, fmt.Errorf("The namespace %v is not cached.", namespace)
+	}
+	return factory, nil
+}
+
+func (c *Cache) ingressInformer(factory informers.SharedInformerFactory) toolscache.SharedIndexInformer {
+	if c.ingressAPIVersion == clients.ExtensionsV1beta1 {
+		return factory.Extensions().V1beta1().Ingresses().Informer()
+	}
+	return factory.Networking().V1().Ingresses().Informer()
+}
+
+type cachedIngressClient struct {
+	clients.IngressClient
+	cache *Cache
+}
+
+func (c *cachedIngressClient) Get(ctx context.Context, namespace string, name string) (*clients.Ingress, error) {
+	return c.cache.GetIngress(namespace, name)
+}
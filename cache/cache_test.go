@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/clients"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	exv1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	testNamespace      = "mnist-prod"
+	testOtherNamespace = "default"
+)
+
+var testLabels = map[string]string{"app": "mnist-cnn"}
+
+// startCache returns the synced cache of testNamespace until the test is finished
+func startCache(t *testing.T, ingressAPIVersion string, objects ...runtime.Object) (*Cache, *fake.Clientset) {
+	kubeClientSet := fake.NewSimpleClientset(objects...)
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+
+	c := NewCache(kubeClientSet, ingressAPIVersion, testNamespace)
+	c.Start(stopCh)
+	if !c.WaitForCacheSync(stopCh) {
+		t.Fatal("The cache is not synced.")
+	}
+	return c, kubeClientSet
+}
+
+func TestCache(t *testing.T) {
+	c, _ := startCache(t, clients.NetworkingV1,
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "mnist-cnn-deployment", Namespace: testNamespace}},
+		&apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "mnist-cnn-1", Namespace: testNamespace, Labels: testLabels}},
+		&apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other-1", Namespace: testNamespace}},
+		&apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "mnist-cnn-2", Namespace: testOtherNamespace, Labels: testLabels}},
+		&apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "mnist-model-registry", Namespace: testNamespace}, Data: map[string]string{"mnist-cnn": "{}"}},
+		&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{
+			Name:        "mnist-cnn-ingress",
+			Namespace:   testNamespace,
+			Annotations: map[string]string{"nginx.ingress.kubernetes.io/canary": "true"},
+		}},
+	)
+
+	if _, err := c.GetDeployment(testNamespace, "mnist-cnn-deployment"); err != nil {
+		t.Errorf("Deployment is not cached: %v", err)
+	}
+	if _, err := c.GetDeployment(testNamespace, "unknown"); !errors.IsNotFound(err) {
+		t.Errorf("Unknown deployment: %v", err)
+	}
+	if _, err := c.GetDeployment(testOtherNamespace, "mnist-cnn-deployment"); err == nil {
+		t.Errorf("Namespace %v is not cached", testOtherNamespace)
+	}
+
+	pods, err := c.ListPods(testNamespace, labels.SelectorFromSet(testLabels))
+	if err != nil || len(pods) != 1 || pods[0].Name != "mnist-cnn-1" {
+		t.Errorf("Wrong pods: %v, %v", pods, err)
+	}
+
+	configMap, err := c.GetConfigMap(testNamespace, "mnist-model-registry")
+	if err != nil || configMap.Data["mnist-cnn"] != "{}" {
+		t.Errorf("Wrong config map: %v, %v", configMap, err)
+	}
+
+	// the annotations are copied from the cached object
+	ingress, err := c.IngressClient(nil).Get(context.TODO(), testNamespace, "mnist-cnn-ingress")
+	if err != nil || ingress.Annotations["nginx.ingress.kubernetes.io/canary"] != "true" {
+		t.Fatalf("Wrong ingress: %+v, %v", ingress, err)
+	}
+	ingress.Annotations["nginx.ingress.kubernetes.io/canary"] = "false"
+	ingress, _ = c.GetIngress(testNamespace, "mnist-cnn-ingress")
+	if ingress.Annotations["nginx.ingress.kubernetes.io/canary"] != "true" {
+		t.Errorf("Cached ingress is modified: %+v", ingress)
+	}
+}
+
+func TestCacheExtensionsV1beta1Ingress(t *testing.T) {
+	c, _ := startCache(t, clients.ExtensionsV1beta1, &exv1beta1.Ingress{ObjectMeta: metav1.ObjectMeta{
+		Name:        "mnist-cnn-ingress",
+		Namespace:   testNamespace,
+		Annotations: map[string]string{"kubernetes.io/ingress.class": "nginx"},
+	}})
+
+	ingress, err := c.GetIngress(testNamespace, "mnist-cnn-ingress")
+	if err != nil || ingress.ClassName != "nginx" {
+		t.Errorf("Wrong ingress: %+v, %v", ingress, err)
+	}
+}
+
+func TestCacheSubscribe(t *testing.T) {
+	c, kubeClientSet := startCache(t, clients.NetworkingV1)
+	objects, unsubscribe := c.Subscribe()
+
+	pod := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "mnist-cnn-1", Namespace: testNamespace, Labels: testLabels}}
+	if _, err := kubeClientSet.CoreV1().Pods(testNamespace).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case object := <-objects:
+		if object.GetName() != pod.Name {
+			t.Errorf("Wrong object: %v", object.GetName())
+		}
+		// the cache is updated before the subscribers are notified
+		if pods, _ := c.ListPods(testNamespace, labels.Everything()); len(pods) != 1 {
+			t.Errorf("Pod is not cached: %v", pods)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("The added Pod is not notified.")
+	}
+
+	unsubscribe()
+	if err := kubeClientSet.CoreV1().Pods(testNamespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case object := <-objects:
+		t.Errorf("Notified after unsubscribe: %v", object.GetName())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,310 @@
+package clients
+
+import (
+	"context"
+
+	exv1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// NetworkingV1 is the Ingress API version since Kubernetes 1.19
+	NetworkingV1 = "networking.k8s.io/v1"
+	// ExtensionsV1beta1 is the Ingress API version removed in Kubernetes 1.22
+	ExtensionsV1beta1 = "extensions/v1beta1"
+)
+
+// ingressClassAnnotation selects the ingress controller when IngressClassName is not supported
+const ingressClassAnnotation = "kubernetes.io/ingress.class"
+
+// Ingress stores the fields of Ingress object managed by mini-mnist-serving
+// regardless of the API version served by the cluster
+type Ingress struct {
+	Name        string
+	Namespace   string
+	Annotations map[string]string
+	ClassName   string
+	Host        string
+	Path        string
+	ServiceName string
+	ServicePort int32
+}
+
+// IngressClient manages Ingress objects which route a single path to a service
+type IngressClient interface {
+	// APIVersion returns the Ingress API version used by the client
+	APIVersion() string
+	Get(ctx context.Context, namespace string, name string) (*Ingress, error)
+	Create(ctx context.Context, ingress *Ingress) error
+	// Update overwrites annotations and spec of the existing Ingress object
+	Update(ctx context.Context, ingress *Ingress) error
+	Delete(ctx context.Context, namespace string, name string) error
+}
+
+// NewIngressClient returns IngressClient of the newest Ingress API version supported by the server
+func NewIngressClient(kubeClientSet kubernetes.Interface) (IngressClient, error) {
+	supported, err := isNetworkingV1IngressSupported(kubeClientSet)
+	if err != nil {
+		return nil, err
+	}
+
+	if supported {
+		return NewNetworkingV1IngressClient(kubeClientSet), nil
+	}
+	return NewExtensionsV1beta1IngressClient(kubeClientSet), nil
+}
+
+// NewNetworkingV1IngressClient returns IngressClient using networking.k8s.io/v1
+func NewNetworkingV1IngressClient(kubeClientSet kubernetes.Interface) IngressClient {
+	return &networkingV1IngressClient{kubeClientSet}
+}
+
+// NewExtensionsV1beta1IngressClient returns IngressClient using extensions/v1beta1
+func NewExtensionsV1beta1IngressClient(kubeClientSet kubernetes.Interface) IngressClient {
+	return &extensionsV1beta1IngressClient{kubeClientSet}
+}
+
+func isNetworkingV1IngressSupported(kubeClientSet kubernetes.Interface) (bool, error) {
+	// networking.k8s.io/v1 exists since Kubernetes 1.8 (NetworkPolicy), so check the resource as well
+	groups, err := kubeClientSet.Discovery().ServerGroups()
+	if err != nil {
+		return false, err
+	}
+
+	groupVersionFound := false
+	for _, group := range groups.Groups {
+		for _, version := range group.Versions {
+			if version.GroupVersion == NetworkingV1 {
+				groupVersionFound = true
+			}
+		}
+	}
+	if !groupVersionFound {
+		return false, nil
+	}
+
+	resources, err := kubeClientSet.Discovery().ServerResourcesForGroupVersion(NetworkingV1)
+	if err != nil {
+		return false, err
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Name == "ingresses" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// IngressFromNetworkingV1 returns the fields of networking.k8s.io/v1 Ingress object
+// The annotations are copied, so result can be shared with an informer cache.
+func IngressFromNetworkingV1(result *networkingv1.Ingress) *Ingress {
+	ingress := &Ingress{
+		Name:        result.Name,
+		Namespace:   result.Namespace,
+		Annotations: make(map[string]string),
+	}
+	for key, value := range result.Annotations {
+		ingress.Annotations[key] = value
+	}
+	if result.Spec.IngressClassName != nil {
+		ingress.ClassName = *result.Spec.IngressClassName
+	}
+	if len(result.Spec.Rules) > 0 {
+		rule := result.Spec.Rules[0]
+		ingress.Host = rule.Host
+		if rule.HTTP != nil && len(rule.HTTP.Paths) > 0 {
+			path := rule.HTTP.Paths[0]
+			ingress.Path = path.Path
+			if path.Backend.Service != nil {
+				ingress.ServiceName = path.Backend.Service.Name
+				ingress.ServicePort = path.Backend.Service.Port.Number
+			}
+		}
+	}
+	return ingress
+}
+
+// IngressFromExtensionsV1beta1 returns the fields of extensions/v1beta1 Ingress object
+func IngressFromExtensionsV1beta1(result *exv1beta1.Ingress) *Ingress {
+	ingress := &Ingress{
+		Name:        result.Name,
+		Namespace:   result.Namespace,
+		Annotations: make(map[string]string),
+	}
+	// the ingress class is exposed as a field, not as an annotation
+	for key, value := range result.Annotations {
+		if key == ingressClassAnnotation {
+			ingress.ClassName = value
+		} else {
+			ingress.Annotations[key] = value
+		}
+	}
+	if len(result.Spec.Rules) > 0 {
+		rule := result.Spec.Rules[0]
+		ingress.Host = rule.Host
+		if rule.HTTP != nil && len(rule.HTTP.Paths) > 0 {
+			path := rule.HTTP.Paths[0]
+			ingress.Path = path.Path
+			ingress.ServiceName = path.Backend.ServiceName
+			ingress.ServicePort = path.Backend.ServicePort.IntVal
+		}
+	}
+	return ingress
+}
+
+type networkingV1IngressClient struct {
+	kubeClientSet kubernetes.Interface
+}
+
+func (c *networkingV1IngressClient) APIVersion() string {
+	return NetworkingV1
+}
+
+func (c *networkingV1IngressClient) Get(ctx context.Context, namespace string, name string) (*Ingress, error) {
+	result, err := c.kubeClientSet.NetworkingV1().Ingresses(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return IngressFromNetworkingV1(result), nil
+}
+
+func (c *networkingV1IngressClient) Create(ctx context.Context, ingress *Ingress) error {
+	result := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: ingress.Name,
+		},
+	}
+	c.apply(ingress, result)
+
+	_, err := c.kubeClientSet.NetworkingV1().Ingresses(ingress.Namespace).Create(ctx, result, metav1.CreateOptions{})
+	return err
+}
+
+func (c *networkingV1IngressClient) Update(ctx context.Context, ingress *Ingress) error {
+	ingressesClient := c.kubeClientSet.NetworkingV1().Ingresses(ingress.Namespace)
+	result, err := ingressesClient.Get(ctx, ingress.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	c.apply(ingress, result)
+
+	_, err = ingressesClient.Update(ctx, result, metav1.UpdateOptions{})
+	return err
+}
+
+func (c *networkingV1IngressClient) Delete(ctx context.Context, namespace string, name string) error {
+	return c.kubeClientSet.NetworkingV1().Ingresses(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// apply copies annotations and spec of ingress to the networking.k8s.io/v1 object
+func (c *networkingV1IngressClient) apply(ingress *Ingress, result *networkingv1.Ingress) {
+	pathType := networkingv1.PathTypePrefix
+
+	result.ObjectMeta.Annotations = ingress.Annotations
+	result.Spec.IngressClassName = nil
+	if ingress.ClassName != "" {
+		className := ingress.ClassName
+		result.Spec.IngressClassName = &className
+	}
+	result.Spec.Rules = []networkingv1.IngressRule{
+		{
+			Host: ingress.Host,
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{
+						{
+							Path:     ingress.Path,
+							PathType: &pathType,
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: ingress.ServiceName,
+									Port: networkingv1.ServiceBackendPort{
+										Number: ingress.ServicePort,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+type extensionsV1beta1IngressClient struct {
+	kubeClientSet kubernetes.Interface
+}
+
+func (c *extensionsV1beta1IngressClient) APIVersion() string {
+	return ExtensionsV1beta1
+}
+
+func (c *extensionsV1beta1IngressClient) Get(ctx context.Context, namespace string, name string) (*Ingress, error) {
+	result, err := c.kubeClientSet.ExtensionsV1beta1().Ingresses(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return IngressFromExtensionsV1beta1(result), nil
+}
+
+func (c *extensionsV1beta1IngressClient) Create(ctx context.Context, ingress *Ingress) error {
+	result := &exv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: ingress.Name,
+		},
+	}
+	c.apply(ingress, result)
+
+	_, err := c.kubeClientSet.ExtensionsV1beta1().Ingresses(ingress.Namespace).Create(ctx, result, metav1.CreateOptions{})
+	return err
+}
+
+func (c *extensionsV1beta1IngressClient) Update(ctx context.Context, ingress *Ingress) error {
+	ingressesClient := c.kubeClientSet.ExtensionsV1beta1().Ingresses(ingress.Namespace)
+	result, err := ingressesClient.Get(ctx, ingress.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	c.apply(ingress, result)
+
+	_, err = ingressesClient.Update(ctx, result, metav1.UpdateOptions{})
+	return err
+}
+
+func (c *extensionsV1beta1IngressClient) Delete(ctx context.Context, namespace string, name string) error {
+	return c.kubeClientSet.ExtensionsV1beta1().Ingresses(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// apply copies annotations and spec of ingress to the extensions/v1beta1 object
+func (c *extensionsV1beta1IngressClient) apply(ingress *Ingress, result *exv1beta1.Ingress) {
+	annotations := make(map[string]string)
+	for key, value := range ingress.Annotations {
+		annotations[key] = value
+	}
+	if ingress.ClassName != "" {
+		annotations[ingressClassAnnotation] = ingress.ClassName
+	}
+
+	result.ObjectMeta.Annotations = annotations
+	result.Spec.Rules = []exv1beta1.IngressRule{
+		{
+			Host: ingress.Host,
+			IngressRuleValue: exv1beta1.IngressRuleValue{
+				HTTP: &exv1beta1.HTTPIngressRuleValue{
+					Paths: []exv1beta1.HTTPIngressPath{
+						{
+							Path: ingress.Path,
+							Backend: exv1beta1.IngressBackend{
+								ServiceName: ingress.ServiceName,
+								ServicePort: intstr.FromInt(int(ingress.ServicePort)),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,163 @@
+package clients
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testIngress() *Ingress {
+	return &Ingress{
+		Name:        "mnist-ingress",
+		Namespace:   "mnist-prod",
+		Annotations: map[string]string{"nginx.ingress.kubernetes.io/rewrite-target": "/v1/models/model:predict"},
+		ClassName:   "nginx",
+		Host:        "mini-serving.example.com",
+		Path:        "/predict",
+		ServiceName: "mnist-svc",
+		ServicePort: 8501,
+	}
+}
+
+func TestIngressClient(t *testing.T) {
+	for _, newIngressClient := range []func(kubernetes.Interface) IngressClient{
+		NewNetworkingV1IngressClient,
+		NewExtensionsV1beta1IngressClient,
+	} {
+		kubeClientSet := fake.NewSimpleClientset()
+		ingressClient := newIngressClient(kubeClientSet)
+
+		ingress := testIngress()
+		if err := ingressClient.Create(context.TODO(), ingress); err != nil {
+			t.Fatalf("%v: %v", ingressClient.APIVersion(), err)
+		}
+		result, err := ingressClient.Get(context.TODO(), ingress.Namespace, ingress.Name)
+		if err != nil {
+			t.Fatalf("%v: %v", ingressClient.APIVersion(), err)
+		}
+		if !reflect.DeepEqual(result, ingress) {
+			t.Errorf("%v: got %+v, want %+v", ingressClient.APIVersion(), result, ingress)
+		}
+
+		ingress.Annotations = map[string]string{"nginx.ingress.kubernetes.io/canary": "true"}
+		ingress.Host = "other.example.com"
+		if err := ingressClient.Update(context.TODO(), ingress); err != nil {
+			t.Fatalf("%v: %v", ingressClient.APIVersion(), err)
+		}
+		result, err = ingressClient.Get(context.TODO(), ingress.Namespace, ingress.Name)
+		if err != nil {
+			t.Fatalf("%v: %v", ingressClient.APIVersion(), err)
+		}
+		if !reflect.DeepEqual(result, ingress) {
+			t.Errorf("%v: got %+v, want %+v", ingressClient.APIVersion(), result, ingress)
+		}
+
+		if err := ingressClient.Delete(context.TODO(), ingress.Namespace, ingress.Name); err != nil {
+			t.Fatalf("%v: %v", ingressClient.APIVersion(), err)
+		}
+		if _, err := ingressClient.Get(context.TODO(), ingress.Namespace, ingress.Name); !errors.IsNotFound(err) {
+			t.Errorf("%v: ingress is not deleted: %v", ingressClient.APIVersion(), err)
+		}
+	}
+}
+
+func TestNetworkingV1IngressShape(t *testing.T) {
+	kubeClientSet := fake.NewSimpleClientset()
+	ingress := testIngress()
+	if err := NewNetworkingV1IngressClient(kubeClientSet).Create(context.TODO(), ingress); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := kubeClientSet.NetworkingV1().Ingresses(ingress.Namespace).Get(context.TODO(), ingress.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Spec.IngressClassName == nil || *result.Spec.IngressClassName != "nginx" {
+		t.Errorf("IngressClassName is not set: %v", result.Spec.IngressClassName)
+	}
+	if _, ok := result.Annotations[ingressClassAnnotation]; ok {
+		t.Errorf("Deprecated ingress class annotation is set")
+	}
+	path := result.Spec.Rules[0].HTTP.Paths[0]
+	if path.PathType == nil || *path.PathType != networkingv1.PathTypePrefix {
+		t.Errorf("Wrong path type: %v", path.PathType)
+	}
+	if path.Backend.Service == nil || path.Backend.Service.Name != "mnist-svc" || path.Backend.Service.Port.Number != 8501 {
+		t.Errorf("Wrong backend: %+v", path.Backend)
+	}
+}
+
+func TestExtensionsV1beta1IngressShape(t *testing.T) {
+	kubeClientSet := fake.NewSimpleClientset()
+	ingress := testIngress()
+	if err := NewExtensionsV1beta1IngressClient(kubeClientSet).Create(context.TODO(), ingress); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := kubeClientSet.ExtensionsV1beta1().Ingresses(ingress.Namespace).Get(context.TODO(), ingress.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Spec.IngressClassName != nil {
+		t.Errorf("IngressClassName should not be set")
+	}
+	if result.Annotations[ingressClassAnnotation] != "nginx" {
+		t.Errorf("Ingress class annotation is not set: %v", result.Annotations)
+	}
+	path := result.Spec.Rules[0].HTTP.Paths[0]
+	if path.Backend.ServiceName != "mnist-svc" || path.Backend.ServicePort.IntVal != 8501 {
+		t.Errorf("Wrong backend: %+v", path.Backend)
+	}
+}
+
+func TestNewIngressClient(t *testing.T) {
+	tests := []struct {
+		name       string
+		resources  []*metav1.APIResourceList
+		apiVersion string
+	}{
+		{
+			name: "Kubernetes 1.19 or later",
+			resources: []*metav1.APIResourceList{
+				{GroupVersion: ExtensionsV1beta1, APIResources: []metav1.APIResource{{Name: "ingresses"}}},
+				{GroupVersion: NetworkingV1, APIResources: []metav1.APIResource{{Name: "networkpolicies"}, {Name: "ingresses"}}},
+			},
+			apiVersion: NetworkingV1,
+		},
+		{
+			name: "Kubernetes 1.18 or earlier",
+			resources: []*metav1.APIResourceList{
+				{GroupVersion: ExtensionsV1beta1, APIResources: []metav1.APIResource{{Name: "ingresses"}}},
+				{GroupVersion: NetworkingV1, APIResources: []metav1.APIResource{{Name: "networkpolicies"}}},
+			},
+			apiVersion: ExtensionsV1beta1,
+		},
+		{
+			name: "Kubernetes 1.7 or earlier",
+			resources: []*metav1.APIResourceList{
+				{GroupVersion: ExtensionsV1beta1, APIResources: []metav1.APIResource{{Name: "ingresses"}}},
+			},
+			apiVersion: ExtensionsV1beta1,
+		},
+	}
+
+	for _, test := range tests {
+		kubeClientSet := fake.NewSimpleClientset()
+		kubeClientSet.Discovery().(*fakediscovery.FakeDiscovery).Resources = test.resources
+
+		ingressClient, err := NewIngressClient(kubeClientSet)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if ingressClient.APIVersion() != test.apiVersion {
+			t.Errorf("%v: got %v, want %v", test.name, ingressClient.APIVersion(), test.apiVersion)
+		}
+	}
+}
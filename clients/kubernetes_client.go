@@ -1,23 +1,27 @@
 package clients
 
 import (
+	"os"
 	"sync"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
-// KubernetesClient stores kubernetes.Clientset
+// KubernetesClient stores kubernetes.Interface and dynamic.Interface
 type KubernetesClient struct {
-	clientSet *kubernetes.Clientset
+	clientSet     kubernetes.Interface
+	dynamicClient dynamic.Interface
 }
 
 var client *KubernetesClient
 var once sync.Once
 
-// GetKubernetesClientSet returns singleton instance of KubernetesClient
-func GetKubernetesClientSet() *kubernetes.Clientset {
+// GetKubernetesClientSet returns the client set of singleton instance of KubernetesClient
+func GetKubernetesClientSet() kubernetes.Interface {
 	// Because the client must have been initialized, use panic
 	if client == nil {
 		panic("The Kubernetes client has not been initialized.")
@@ -26,10 +30,37 @@ func GetKubernetesClientSet() *kubernetes.Clientset {
 	return client.clientSet
 }
 
-// InitKubernetesClient initialize singleton instance of KubernetesClient
+// GetDynamicClient returns the dynamic client of singleton instance of KubernetesClient,
+// which manages the custom resources of traffic backends
+func GetDynamicClient() dynamic.Interface {
+	if client == nil {
+		panic("The Kubernetes client has not been initialized.")
+	}
+
+	return client.dynamicClient
+}
+
+// InitKubernetesClient initialize singleton instance of KubernetesClient using kubeconfig file
 func InitKubernetesClient(kubeconfig string) {
+	initKubernetesClient(func() (*rest.Config, error) {
+		return clientcmd.BuildConfigFromFlags("", kubeconfig)
+	})
+}
+
+// InitInClusterKubernetesClient initialize singleton instance of KubernetesClient
+// using the service account token mounted to the Pod
+func InitInClusterKubernetesClient() {
+	initKubernetesClient(rest.InClusterConfig)
+}
+
+// IsInCluster reports whether the process is running inside a Kubernetes Pod
+func IsInCluster() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != "" && os.Getenv("KUBERNETES_SERVICE_PORT") != ""
+}
+
+func initKubernetesClient(buildConfig func() (*rest.Config, error)) {
 	once.Do(func() {
-		config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+		config, err := buildConfig()
 		if err != nil {
 			panic(err.Error())
 		}
@@ -37,6 +68,10 @@ func InitKubernetesClient(kubeconfig string) {
 		if err != nil {
 			panic(err.Error())
 		}
-		client = &KubernetesClient{clientSet}
+		dynamicClient, err := dynamic.NewForConfig(config)
+		if err != nil {
+			panic(err.Error())
+		}
+		client = &KubernetesClient{clientSet, dynamicClient}
 	})
 }
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/josh9191/mini-mnist-serving/rbac"
+)
+
+func main() {
+	output := flag.String("o", "", "(optional) output file path of the manifest (default: stdout)")
+	flag.Parse()
+
+	manifest, err := rbac.Manifest()
+	if err != nil {
+		log.Fatalf("Failed to generate RBAC manifest: %v", err)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(manifest)
+		return
+	}
+
+	if err := ioutil.WriteFile(*output, manifest, 0644); err != nil {
+		log.Fatalf("Failed to write RBAC manifest: %v", err)
+	}
+}
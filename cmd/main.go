@@ -3,35 +3,74 @@ package main
 import (
 	"flag"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 
 	"github.com/gorilla/mux"
+	"github.com/josh9191/mini-mnist-serving/api"
+	"github.com/josh9191/mini-mnist-serving/cache"
 	"github.com/josh9191/mini-mnist-serving/clients"
+	"github.com/josh9191/mini-mnist-serving/constants"
 	"github.com/josh9191/mini-mnist-serving/controller"
+	"github.com/josh9191/mini-mnist-serving/tfserving"
+	"github.com/josh9191/mini-mnist-serving/traffic"
+	"google.golang.org/grpc"
 	"k8s.io/client-go/util/homedir"
 )
 
 func main() {
 	// parse command-line arguments
 	var kubeconfig *string
+	var inCluster *bool
 	var googleAppCreds *string
 	var ingressHost *string
+	var routing *string
+	var slotHosts *string
+	var trafficBackend *string
+	var gateway *string
+	var maxBatchSize *int
+	var predictionProtocol *string
+	var grpcSlotHosts *string
+	var grpcInputName *string
+	var grpcAddr *string
+	var nginxUpstreamHeader *bool
+	var gatewayUpstreamHeader *bool
+	var trustedProxyUser *bool
 
 	if home := homedir.HomeDir(); home != "" {
 		kubeconfig = flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
 	} else {
 		kubeconfig = flag.String("kubeconfig", "", "absolute path to the kubeconfig file")
 	}
+	inCluster = flag.Bool("in-cluster", false, "(optional) use the service account of the Pod instead of the kubeconfig file (selected automatically if the kubeconfig file doesn't exist in a Pod)")
 	googleAppCreds = flag.String("google-app-creds", "", "absolute path to the google application credentials json file")
 	ingressHost = flag.String("ingress-host", "", "Kubernetes Nginx ingress host (should be a domain name)")
+	routing = flag.String("routing", "ingress", "(optional) \"ingress\" to split predictions by nginx ingress canary annotations, or \"direct\" to split them in the server")
+	trafficBackend = flag.String("traffic-backend", traffic.Nginx, "(optional) \"nginx\" (Ingress), \"gateway\" (Gateway API HTTPRoute) or \"istio\" (VirtualService) routing predictions to the current and new models")
+	nginxUpstreamHeader = flag.Bool("nginx-upstream-header", false, "(optional) add the configuration snippet reporting the serving model to the current model ingress, which needs allow-snippet-annotations of ingress-nginx (otherwise the server selects the model of each prediction)")
+	gatewayUpstreamHeader = flag.Bool("gateway-upstream-header", false, "(optional) add the ResponseHeaderModifier filter reporting the serving model to the backendRef of the new model, which needs an implementation of Gateway API supporting backendRef filters (otherwise the server selects the model of each prediction)")
+	gateway = flag.String("gateway", "", "<namespace>/<name> of the Gateway which routes are attached to (required by gateway and istio traffic backends)")
+	maxBatchSize = flag.Int("max-batch-size", controller.DefaultMaxBatchSize, "(optional) maximum number of images sent to Tensorflow Serving in one request by batch prediction")
+	predictionProtocol = flag.String("prediction-protocol", "rest", "(optional) \"rest\" to send predictions to the REST API of Tensorflow Serving through the traffic backend, or \"grpc\" to send them to the gRPC API of the model selected by the server")
+	grpcSlotHosts = flag.String("grpc-slot-hosts", "", "(optional) comma-separated <model>/<prod|canary>=<host:port> of the gRPC API of Tensorflow Serving used instead of the service DNS names")
+	grpcInputName = flag.String("grpc-input-name", tfserving.DefaultInputName, "(optional) name of the input tensor of the serving signature used by gRPC predictions")
+	grpcAddr = flag.String("grpc-addr", ":9090", "(optional) address of the gRPC API of the server, or empty to serve only the HTTP API")
+	trustedProxyUser = flag.Bool("trusted-proxy-user", false, "(optional) record the user of X-Forwarded-User or X-Remote-User header of the HTTP API in the revision history, which should be set only when every request passes an authenticating proxy (otherwise, and always with the gRPC API, the client address is recorded)")
+	slotHosts = flag.String("slot-hosts", "", "(optional) comma-separated <model>/<prod|canary>=<host:port> of Tensorflow Serving used instead of the service DNS names (e.g. ports forwarded by kubectl)")
 
 	flag.Parse()
 
-	if _, err := os.Stat(*kubeconfig); os.IsNotExist(err) {
-		flag.PrintDefaults()
-		log.Fatalf("Kubernetes config file doesn't exist: %v", err)
+	if !*inCluster {
+		if _, err := os.Stat(*kubeconfig); os.IsNotExist(err) {
+			if !clients.IsInCluster() {
+				flag.PrintDefaults()
+				log.Fatalf("Kubernetes config file doesn't exist: %v", err)
+			}
+			log.Printf("Kubernetes config file doesn't exist. Using in-cluster configuration.")
+			*inCluster = true
+		}
 	}
 
 	if _, err := os.Stat(*googleAppCreds); os.IsNotExist(err) {
@@ -39,25 +78,151 @@ func main() {
 		log.Fatalf("Google Application Credentials file doesn't exist: %v", err)
 	}
 
-	if *ingressHost == "" {
+	if *routing != "ingress" && *routing != "direct" {
+		flag.PrintDefaults()
+		log.Fatalf("Unknown routing: %v", *routing)
+	}
+
+	// the ingress is optional with direct routing
+	if *ingressHost == "" && *routing == "ingress" {
 		flag.PrintDefaults()
 		log.Fatalf("Kubernetes Ingress Host flag (-ingress-host) is missing.")
 	}
 
+	if *trafficBackend != traffic.Nginx && *trafficBackend != traffic.Gateway && *trafficBackend != traffic.Istio {
+		flag.PrintDefaults()
+		log.Fatalf("Unknown traffic backend: %v", *trafficBackend)
+	}
+
+	if *maxBatchSize < 1 {
+		flag.PrintDefaults()
+		log.Fatalf("Invalid max batch size flag (-max-batch-size): %v", *maxBatchSize)
+	}
+
+	slotHost, err := controller.ParseSlotHosts(*slotHosts)
+	if err != nil {
+		flag.PrintDefaults()
+		log.Fatalf("Invalid slot hosts flag (-slot-hosts): %v", err)
+	}
+
+	if *predictionProtocol != "rest" && *predictionProtocol != "grpc" {
+		flag.PrintDefaults()
+		log.Fatalf("Unknown prediction protocol: %v", *predictionProtocol)
+	}
+
+	grpcSlotHost, err := controller.ParseGRPCSlotHosts(*grpcSlotHosts)
+	if err != nil {
+		flag.PrintDefaults()
+		log.Fatalf("Invalid gRPC slot hosts flag (-grpc-slot-hosts): %v", err)
+	}
+
 	// Initialize clients to connect to external services
-	clients.InitKubernetesClient(*kubeconfig)
+	if *inCluster {
+		clients.InitInClusterKubernetesClient()
+	} else {
+		clients.InitKubernetesClient(*kubeconfig)
+	}
+	kubeClientSet := clients.GetKubernetesClientSet()
+	// Ingress API version depends on the version of the cluster
+	ingressClient, err := clients.NewIngressClient(kubeClientSet)
+	if err != nil {
+		log.Fatalf("Failed to discover Ingress API version: %v", err)
+	}
+	log.Printf("Using Ingress API version %v", ingressClient.APIVersion())
+	server := controller.NewServer(kubeClientSet, ingressClient)
+	if *trafficBackend == traffic.Nginx && *nginxUpstreamHeader {
+		backend := traffic.NewNginxBackend(ingressClient)
+		backend.EnableUpstreamHeader()
+		server.SetTrafficBackend(backend)
+	}
+	if *trafficBackend != traffic.Nginx {
+		var backend traffic.Backend
+		if *trafficBackend == traffic.Gateway {
+			var gatewayBackend *traffic.GatewayBackend
+			gatewayBackend, err = traffic.NewGatewayBackend(clients.GetDynamicClient(), *gateway)
+			if err == nil && *gatewayUpstreamHeader {
+				gatewayBackend.EnableUpstreamHeader()
+			}
+			backend = gatewayBackend
+		} else {
+			backend, err = traffic.NewIstioBackend(clients.GetDynamicClient(), *gateway)
+		}
+		if err != nil {
+			log.Fatalf("Invalid gateway flag (-gateway): %v", err)
+		}
+		server.SetTrafficBackend(backend)
+	}
+	log.Printf("Using traffic backend %v", *trafficBackend)
+
+	// the status of models is read from the objects cached by informers
+	clusterCache := cache.NewCache(kubeClientSet, ingressClient.APIVersion(), constants.ProdNamespace, constants.CanaryNamespace)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	clusterCache.Start(stopCh)
+	if !clusterCache.WaitForCacheSync(stopCh) {
+		log.Fatalf("Failed to sync the cache of cluster state")
+	}
+	server.EnableCache(clusterCache)
+	if *routing == "direct" {
+		server.EnableDirectRouting(slotHost)
+		log.Printf("Routing predictions in the server")
+	} else if *slotHosts != "" {
+		// the server selects the model of each prediction unless the traffic backend reports it
+		server.SetSlotHosts(slotHost)
+	}
+	if *trustedProxyUser {
+		server.EnableTrustedProxyUser()
+	}
+	if *predictionProtocol == "grpc" {
+		predictionClient := tfserving.NewClient(*grpcInputName)
+		defer predictionClient.Close()
+		server.EnableGRPCPrediction(predictionClient, grpcSlotHost)
+		log.Printf("Sending predictions to the gRPC API of Tensorflow Serving")
+	}
 
 	r := mux.NewRouter()
 	// Root page
-	r.HandleFunc("/", controller.RootController).Methods(http.MethodGet)
+	r.HandleFunc("/", server.RootController).Methods(http.MethodGet)
 
 	s := http.StripPrefix("/static/", http.FileServer(http.Dir("./static/")))
 	r.PathPrefix("/static/").Handler(s)
 
+	// Model registry controllers
+	r.HandleFunc("/models", server.ListModelsController).Methods(http.MethodGet)
+	r.HandleFunc("/models", server.CreateModelController).Methods(http.MethodPost)
+	r.HandleFunc("/models/{name}", server.DeleteModelController).Methods(http.MethodDelete)
+
 	// Model controllers
-	r.HandleFunc("/model:deploy", controller.DeployControllerWrapper(*googleAppCreds, *ingressHost)).Methods(http.MethodPost)
-	r.HandleFunc("/model/strategy", controller.ModelStrategyController).Methods(http.MethodPut)
-	r.HandleFunc("/model:predict", controller.ModelPredictControllerWrapper(*ingressHost)).Methods(http.MethodPost)
+	r.HandleFunc("/model:deploy", server.DeployControllerWrapper(*googleAppCreds, *ingressHost)).Methods(http.MethodPost)
+	r.HandleFunc("/jobs", server.ListJobsController).Methods(http.MethodGet)
+	r.HandleFunc("/jobs/{id:[0-9a-f]+}", server.GetJobController).Methods(http.MethodGet)
+	r.HandleFunc("/jobs/{id:[0-9a-f]+}:cancel", server.CancelJobController).Methods(http.MethodPost)
+	r.HandleFunc("/model/status", server.ModelStatusController).Methods(http.MethodGet)
+	r.HandleFunc("/model/events", server.ModelEventsController).Methods(http.MethodGet)
+	r.HandleFunc("/model/rollout", server.RolloutStatusController).Methods(http.MethodGet)
+	r.HandleFunc("/model:promote", server.PromoteController).Methods(http.MethodPost)
+	r.HandleFunc("/model:rollback", server.RollbackController).Methods(http.MethodPost)
+	r.HandleFunc("/model:canary", server.CanaryController).Methods(http.MethodPost)
+	r.HandleFunc("/model/canary", server.CanaryAnalysisController).Methods(http.MethodGet)
+	r.HandleFunc("/model:undeploy", server.UndeployController).Methods(http.MethodDelete)
+	r.HandleFunc("/model/strategy", server.ModelStrategyController).Methods(http.MethodPut)
+	r.HandleFunc("/model/shadow", server.ShadowController).Methods(http.MethodGet)
+	r.HandleFunc("/model:predict", server.ModelPredictControllerWrapper(*ingressHost)).Methods(http.MethodPost)
+	r.HandleFunc("/model:predictBatch", server.ModelPredictBatchControllerWrapper(*ingressHost, *maxBatchSize)).Methods(http.MethodPost)
+	r.HandleFunc("/model:compare", server.CompareControllerWrapper(slotHost)).Methods(http.MethodPost)
+
+	// the gRPC API shares the controllers with the HTTP API
+	if *grpcAddr != "" {
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatalf("Failed to listen on gRPC address (-grpc-addr): %v", err)
+		}
+		grpcServer := grpc.NewServer()
+		api.RegisterMnistServingServer(grpcServer, controller.NewGRPCServer(server, *googleAppCreds, *ingressHost))
+		go grpcServer.Serve(listener)
+		defer grpcServer.Stop()
+		log.Printf("Serving the gRPC API on %v", *grpcAddr)
+	}
 
 	http.ListenAndServe(":8080", r)
 }
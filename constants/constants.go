@@ -6,16 +6,21 @@ const (
 )
 
 const (
-	IngressName     = "mnist-ingress"
 	ModelSecretName = "mnist-secret"
-	DeploymentName  = "mnist-deploy"
-	ServiceName     = "mnist-svc"
 )
 
 const (
 	LabelAppSelector = "mnist"
 )
 
+const (
+	DefaultModelName = "model"
+)
+
+const (
+	IngressClassName = "nginx"
+)
+
 type Strategy int
 
 const (
@@ -23,8 +28,20 @@ const (
 	CurrentModelOnly
 	NewModelOnly
 	Canary
+	Shadow
+	Targeted
 )
 
 const (
 	CanaryHeader = "UseCanary"
 )
+
+const (
+	// CanaryUpstreamHeader is set by the ingress to the canary backend name when the new model serves the request
+	CanaryUpstreamHeader = "X-Canary-Upstream"
+)
+
+const (
+	// RoutingKeyHeader selects the model of the request consistently with direct routing
+	RoutingKeyHeader = "X-Routing-Key"
+)
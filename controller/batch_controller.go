@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/metrics"
+	"github.com/josh9191/mini-mnist-serving/preprocess"
+)
+
+// DefaultMaxBatchSize is the number of images sent to TF Serving in one request by default
+const DefaultMaxBatchSize = 32
+
+// maxBatchImageBytes limits the size of the batch prediction request to this size of each image in one chunk
+const maxBatchImageBytes = 1 << 20
+
+// BatchPredictResponse stores the predictions of images in the order of the request
+type BatchPredictResponse struct {
+	ModelName   string           `json:"model-name"`
+	Predictions []SlotPrediction `json:"predictions"`
+}
+
+// ModelPredictBatchControllerWrapper handles prediction of multiple images
+// The request is a JSON array of images, each of which is 784 pixels or ImageInput. Valid images are sent to the model selected by the strategy
+// in chunks of at most maxBatchSize images, and invalid images or failed chunks are reported in the error of each prediction.
+// The request larger than maxBatchSize images of maxBatchImageBytes is rejected.
+func (s *Server) ModelPredictBatchControllerWrapper(ingressHost string, maxBatchSize int) http.HandlerFunc {
+	maxBytes := int64(maxBatchSize) * maxBatchImageBytes
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+		if err != nil {
+			// the reader stops at the limit when the request is too large
+			if int64(len(body)) >= maxBytes {
+				http.Error(w, fmt.Sprintf("The request is larger than %d bytes.", maxBytes), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var images []json.RawMessage
+		if err := json.Unmarshal(body, &images); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(images) == 0 {
+			http.Error(w, "No image is given.", http.StatusBadRequest)
+			return
+		}
+
+		modelName, err := s.getRequestModelName(r.URL.Query().Get("model-name"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		batchResponse := BatchPredictResponse{
+			ModelName:   modelName,
+			Predictions: make([]SlotPrediction, len(images)),
+		}
+		// the indexes of valid images in the request
+		var indexes []int
+		var validImages [][]float32
+		options, err := imageOptions(r.URL.Query(), preprocess.Options{})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for i, image := range images {
+			pixels, err := decodeJSONImage(image, options)
+			if err != nil {
+				batchResponse.Predictions[i] = SlotPrediction{Argmax: -1, Error: err.Error()}
+				continue
+			}
+			indexes = append(indexes, i)
+			validImages = append(validImages, pixels)
+		}
+
+		routing := s.getRoutingStrategy(r.Context(), modelName)
+		for start := 0; start < len(validImages); start += maxBatchSize {
+			end := start + maxBatchSize
+			if end > len(validImages) {
+				end = len(validImages)
+			}
+			predictions := s.predictBatch(r.Context(), ingressHost, modelName, validImages[start:end], routing, r)
+			for i, prediction := range predictions {
+				batchResponse.Predictions[indexes[start+i]] = prediction
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(batchResponse)
+	}
+}
+
+// predictBatch sends images in one request to the model selected by routing and returns their predictions
+// Every prediction reports the error when the request fails.
+func (s *Server) predictBatch(ctx context.Context, ingressHost string, modelName string, images [][]float32, routing routingStrategy, r *http.Request) []SlotPrediction {
+	predictions := make([]SlotPrediction, len(images))
+	if s.grpcClient != nil {
+		slot, probabilities, latency, err := s.sendGRPCPrediction(ctx, modelName, images, routing, r)
+		if err != nil {
+			return setBatchError(predictions, err)
+		}
+		return s.newBatchPredictions(ctx, modelName, probabilities, slot, latency)
+	}
+
+	requestJson, err := newPredictRequest(images)
+	if err != nil {
+		return setBatchError(predictions, err)
+	}
+
+	start := time.Now()
+	slot, resp, body, err := s.routePrediction(ctx, ingressHost, modelName, requestJson, routing, r)
+	if err != nil {
+		s.recordUnreachable(ctx, modelName, slot, start)
+		return setBatchError(predictions, err)
+	}
+	latency := time.Since(start)
+	s.recordPrediction(modelName, slot, metrics.Observation{
+		Time:    start,
+		Latency: latency,
+		Failed:  resp.StatusCode >= 500,
+	})
+	if resp.StatusCode != http.StatusOK {
+		return setBatchError(predictions, fmt.Errorf("Status Code: %d, %v", resp.StatusCode, string(body)))
+	}
+	var predResp PredictResponse
+	err = json.Unmarshal(body, &predResp)
+	if err != nil || len(predResp.Predictions) != len(images) {
+		return setBatchError(predictions, fmt.Errorf("Invalid prediction response from the model server."))
+	}
+
+	return s.newBatchPredictions(ctx, modelName, predResp.Predictions, slot, latency)
+}
+
+// newBatchPredictions returns the predictions of the chunk served by the same model
+func (s *Server) newBatchPredictions(ctx context.Context, modelName string, probabilities [][]float32, slot string, latency time.Duration) []SlotPrediction {
+	slotModel := newSlotPrediction(nil, slot, latency)
+	s.setSlotModel(ctx, &slotModel, modelName)
+	predictions := make([]SlotPrediction, len(probabilities))
+	for i := range probabilities {
+		predictions[i] = newSlotPrediction(probabilities[i], slot, latency)
+		predictions[i].ModelBasePath = slotModel.ModelBasePath
+		predictions[i].Revision = slotModel.Revision
+	}
+	return predictions
+}
+
+// setBatchError sets err to every prediction of the failed chunk
+func setBatchError(predictions []SlotPrediction, err error) []SlotPrediction {
+	for i := range predictions {
+		predictions[i] = SlotPrediction{Argmax: -1, Error: err.Error()}
+	}
+	return predictions
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+)
+
+// newBatchModelServer returns TF Serving predicting the first pixel of each instance as its class
+// and records the number of instances of each request. The requests of failedChunk fail.
+func newBatchModelServer(t *testing.T, failedChunk int) (*httptest.Server, func() []int) {
+	var mu sync.Mutex
+	var chunkSizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Instances [][][][]float32 `json:"instances"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		chunkSizes = append(chunkSizes, len(req.Instances))
+		chunk := len(chunkSizes) - 1
+		mu.Unlock()
+		if chunk == failedChunk {
+			http.Error(w, "OOM", http.StatusInternalServerError)
+			return
+		}
+
+		predictions := make([][]float32, len(req.Instances))
+		for i, instance := range req.Instances {
+			predictions[i] = make([]float32, 10)
+			predictions[i][int(instance[0][0][0])] = 1
+		}
+		json.NewEncoder(w).Encode(PredictResponse{Predictions: predictions})
+	}))
+	return server, func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return chunkSizes
+	}
+}
+
+// batchImage returns the image whose first pixel is class
+func batchImage(class int) []float32 {
+	pixels := make([]float32, 784)
+	pixels[0] = float32(class)
+	return pixels
+}
+
+func predictBatch(t *testing.T, s *Server, modelServer *httptest.Server, maxBatchSize int, images []interface{}) BatchPredictResponse {
+	modelServerUrl, _ := url.Parse(modelServer.URL)
+	body, _ := json.Marshal(images)
+	w := httptest.NewRecorder()
+	s.ModelPredictBatchControllerWrapper(modelServerUrl.Host, maxBatchSize).ServeHTTP(w, httptest.NewRequest("POST", "/model:predictBatch?model-name="+constants.DefaultModelName, bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Error - Status Code: %d, %v", w.Code, w.Body.String())
+	}
+
+	var batchResponse BatchPredictResponse
+	if err := json.NewDecoder(w.Body).Decode(&batchResponse); err != nil {
+		t.Fatal(err)
+	}
+	if len(batchResponse.Predictions) != len(images) {
+		t.Fatalf("Wrong number of predictions: %+v", batchResponse.Predictions)
+	}
+	return batchResponse
+}
+
+func TestModelPredictBatchController(t *testing.T) {
+	modelServer, chunkSizes := newBatchModelServer(t, -1)
+	defer modelServer.Close()
+	s, _ := newTestServer()
+	enableTestUpstreamHeader(s)
+	registerModels(t, s, constants.DefaultModelName)
+
+	// invalid images are not sent to the model
+	images := []interface{}{batchImage(3), make([]float32, 10), batchImage(1), batchImage(4), "pixels", batchImage(1), batchImage(5)}
+	batchResponse := predictBatch(t, s, modelServer, 2, images)
+	if !reflect.DeepEqual(chunkSizes(), []int{2, 2, 1}) {
+		t.Errorf("Wrong chunks: %v", chunkSizes())
+	}
+	for i, class := range []int{3, -1, 1, 4, -1, 1, 5} {
+		prediction := batchResponse.Predictions[i]
+		if class < 0 {
+			if prediction.Error == "" {
+				t.Errorf("%d: invalid image is predicted: %+v", i, prediction)
+			}
+			continue
+		}
+		if prediction.Error != "" || prediction.Argmax != class || prediction.Confidence != 1 || prediction.Slot != ProdSlot {
+			t.Errorf("%d: wrong prediction: %+v, want class %d", i, prediction, class)
+		}
+	}
+}
+
+func TestModelPredictBatchControllerFailedChunk(t *testing.T) {
+	modelServer, _ := newBatchModelServer(t, 1)
+	defer modelServer.Close()
+	s, _ := newTestServer()
+	enableTestUpstreamHeader(s)
+	registerModels(t, s, constants.DefaultModelName)
+
+	batchResponse := predictBatch(t, s, modelServer, 2, []interface{}{batchImage(0), batchImage(1), batchImage(2), batchImage(3), batchImage(4)})
+	for i, prediction := range batchResponse.Predictions {
+		// only the second chunk fails
+		failed := i == 2 || i == 3
+		if (prediction.Error != "") != failed || (!failed && prediction.Argmax != i) {
+			t.Errorf("%d: wrong prediction: %+v", i, prediction)
+		}
+	}
+}
+
+func TestModelPredictBatchControllerInvalidRequest(t *testing.T) {
+	s, _ := newTestServer()
+	registerModels(t, s, constants.DefaultModelName)
+	handler := s.ModelPredictBatchControllerWrapper(testIngressHost, DefaultMaxBatchSize)
+
+	for _, body := range []string{"[]", "{}", `[[0.1]`} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/model:predictBatch?model-name="+constants.DefaultModelName, strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%v: Status Code: %d", body, w.Code)
+		}
+	}
+}
+
+func TestModelPredictBatchControllerTooLarge(t *testing.T) {
+	s, _ := newTestServer()
+	registerModels(t, s, constants.DefaultModelName)
+	// the request may have at most 2 images of maxBatchImageBytes
+	handler := s.ModelPredictBatchControllerWrapper(testIngressHost, 2)
+
+	body := `["` + strings.Repeat("a", 2*maxBatchImageBytes) + `"]`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/model:predictBatch?model-name="+constants.DefaultModelName, strings.NewReader(body)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Status Code: %d, %v", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,415 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/jobs"
+	"github.com/josh9191/mini-mnist-serving/metrics"
+	"github.com/josh9191/mini-mnist-serving/registry"
+	"github.com/josh9191/mini-mnist-serving/traffic"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CanaryState is the state of progressive canary
+type CanaryState string
+
+const (
+	CanaryPending  CanaryState = "Pending"
+	CanaryRunning  CanaryState = "Running"
+	CanaryPromoted CanaryState = "Promoted"
+	CanaryAborted  CanaryState = "Aborted"
+)
+
+var defaultCanaryWeights = []int{5, 25, 50, 100}
+
+const (
+	defaultCanaryInterval       = time.Minute
+	defaultMinRequests          = 10
+	defaultMaxErrorRateIncrease = 0.01
+	defaultMaxLatencyRatio      = 1.5
+)
+
+// CanaryRequest stores progressive canary request JSON data
+type CanaryRequest struct {
+	ModelName string `json:"model-name"`
+	// Weights are the percentages of requests sent to the new model, applied one by one
+	Weights         []int `json:"weights,omitempty"`
+	IntervalSeconds int   `json:"interval-seconds,omitempty"`
+	// MinRequests is the number of requests the new model should serve in each step
+	MinRequests int `json:"min-requests,omitempty"`
+	// MaxErrorRateIncrease is the allowed excess of the error rate of the new model over the current model
+	MaxErrorRateIncrease *float64 `json:"max-error-rate-increase,omitempty"`
+	// MaxLatencyRatio is the allowed ratio of p95 latency of the new model to the current model
+	MaxLatencyRatio float64 `json:"max-latency-ratio,omitempty"`
+	ScaleDownCanary bool    `json:"scale-down-canary"`
+	TimeoutSeconds  int     `json:"timeout-seconds,omitempty"`
+}
+
+// CanaryStep stores the analysis of a canary weight
+type CanaryStep struct {
+	Weight     int           `json:"weight"`
+	StartedAt  time.Time     `json:"started-at"`
+	FinishedAt *time.Time    `json:"finished-at,omitempty"`
+	Prod       metrics.Stats `json:"prod"`
+	Canary     metrics.Stats `json:"canary"`
+	Passed     bool          `json:"passed"`
+	Reason     string        `json:"reason,omitempty"`
+}
+
+// CanaryAnalysis stores the progress of progressive canary of a model
+type CanaryAnalysis struct {
+	JobID      string           `json:"job-id"`
+	ModelName  string           `json:"model-name"`
+	State      CanaryState      `json:"state"`
+	Weight     int              `json:"weight"`
+	Request    CanaryRequest    `json:"request"`
+	Steps      []CanaryStep     `json:"steps"`
+	Reason     string           `json:"reason,omitempty"`
+	Promotion  *PromoteResponse `json:"promotion,omitempty"`
+	StartedAt  *time.Time       `json:"started-at,omitempty"`
+	FinishedAt *time.Time       `json:"finished-at,omitempty"`
+}
+
+// Finished reports whether the new model is promoted or aborted
+func (a *CanaryAnalysis) Finished() bool {
+	return a.State == CanaryPromoted || a.State == CanaryAborted
+}
+
+// CanaryController starts a job stepping the canary weight and promoting the new model
+// when its error rate and latency are as good as the current model in every step
+func (s *Server) CanaryController(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+
+	var canaryRequest CanaryRequest
+	err := decoder.Decode(&canaryRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	modelName, err := s.getRequestModelName(canaryRequest.ModelName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	canaryRequest.ModelName = modelName
+
+	err = setCanaryDefaults(&canaryRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the traffic of each model can't be analyzed when the traffic backend splits predictions without telling the serving model
+	if !s.attributesPredictions() {
+		http.Error(w, "The serving model of predictions is unknown. Set -slot-hosts, -routing=direct or the upstream header flag of the traffic backend.", http.StatusBadRequest)
+		return
+	}
+
+	// both of slots and their routes should be deployed
+	for _, isNewModel := range []bool{true, false} {
+		_, err = s.kubeClientSet.AppsV1().Deployments(getNamespace(isNewModel)).Get(context.TODO(), registry.DeploymentName(modelName), metav1.GetOptions{})
+		if err == nil {
+			err = s.traffic.CheckSlot(context.TODO(), modelName, isNewModel)
+		}
+		if err != nil {
+			if errors.IsNotFound(err) && isNewModel {
+				http.Error(w, "The new model is not deployed.", http.StatusBadRequest)
+			} else if errors.IsNotFound(err) {
+				http.Error(w, "The current model is not deployed.", http.StatusBadRequest)
+			} else {
+				http.Error(w, err.Error(), 500)
+			}
+			return
+		}
+	}
+
+	stepNames := []string{}
+	for _, weight := range canaryRequest.Weights {
+		stepNames = append(stepNames, fmt.Sprintf("weight-%d", weight), fmt.Sprintf("analyze-%d", weight))
+	}
+	stepNames = append(stepNames, "promote")
+
+	analysis := &CanaryAnalysis{
+		ModelName: modelName,
+		State:     CanaryPending,
+		Request:   canaryRequest,
+		Steps:     []CanaryStep{},
+	}
+	deployedBy := s.requestUser(r)
+
+	// the analysis is stored before the job updates it
+	s.canariesMu.Lock()
+	// the analysis of the pending or running canary is kept
+	if err := s.checkCanaryNotRunningLocked(modelName); err != nil {
+		s.canariesMu.Unlock()
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	// the canary is run one by one with deploys of the new model
+	key := getNamespace(true) + "/" + modelName
+	job := s.jobs.Start("canary", key, stepNames, func(ctx context.Context, progress *jobs.Progress) (interface{}, error) {
+		result, err := s.runCanary(ctx, progress, analysis, deployedBy)
+		return result, err
+	})
+	analysis.JobID = job.ID
+	s.canaries[modelName] = analysis
+	s.canariesMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// CanaryAnalysisController returns the last canary analysis of model
+func (s *Server) CanaryAnalysisController(w http.ResponseWriter, r *http.Request) {
+	modelName, err := s.getRequestModelName(r.URL.Query().Get("model-name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	analysis := s.getCanaryAnalysis(modelName)
+	if analysis == nil {
+		http.Error(w, "The canary of the model is never started.", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(analysis)
+}
+
+// setCanaryDefaults fills the default values of canary request and validates it
+func setCanaryDefaults(canaryRequest *CanaryRequest) error {
+	if len(canaryRequest.Weights) == 0 {
+		canaryRequest.Weights = defaultCanaryWeights
+	}
+	prevWeight := 0
+	for _, weight := range canaryRequest.Weights {
+		if weight <= prevWeight || weight > 100 {
+			return fmt.Errorf("Weights should increase between 1 and 100: %v", canaryRequest.Weights)
+		}
+		prevWeight = weight
+	}
+	if canaryRequest.IntervalSeconds < 0 || canaryRequest.MinRequests < 0 || canaryRequest.MaxLatencyRatio < 0 ||
+		(canaryRequest.MaxErrorRateIncrease != nil && *canaryRequest.MaxErrorRateIncrease < 0) {
+		return fmt.Errorf("Interval, minimum requests, maximum error rate increase and maximum latency ratio should not be negative.")
+	}
+	if canaryRequest.IntervalSeconds == 0 {
+		canaryRequest.IntervalSeconds = int(defaultCanaryInterval / time.Second)
+	}
+	if canaryRequest.MinRequests == 0 {
+		canaryRequest.MinRequests = defaultMinRequests
+	}
+	if canaryRequest.MaxErrorRateIncrease == nil {
+		maxErrorRateIncrease := defaultMaxErrorRateIncrease
+		canaryRequest.MaxErrorRateIncrease = &maxErrorRateIncrease
+	}
+	if canaryRequest.MaxLatencyRatio == 0 {
+		canaryRequest.MaxLatencyRatio = defaultMaxLatencyRatio
+	}
+	return nil
+}
+
+// runCanary steps the canary weight and analyzes the traffic of each step
+// Every request is sent to the current model again when any step fails or the job is canceled.
+func (s *Server) runCanary(ctx context.Context, progress *jobs.Progress, analysis *CanaryAnalysis, deployedBy string) (*CanaryAnalysis, error) {
+	canaryRequest := analysis.Request
+	modelName := canaryRequest.ModelName
+	interval := time.Duration(canaryRequest.IntervalSeconds) * time.Second
+
+	startedAt := time.Now().UTC()
+	s.updateCanaryAnalysis(analysis, func(a *CanaryAnalysis) {
+		a.State = CanaryRunning
+		a.StartedAt = &startedAt
+	})
+
+	for i := range canaryRequest.Weights {
+		weight := canaryRequest.Weights[i]
+		err := progress.Run(fmt.Sprintf("weight-%d", weight), func() (string, error) {
+			strategyStr, err := s.setStrategy(ctx, modelName, constants.Canary, &weight, nil)
+			return fmt.Sprintf("Changed to strategy: %v (%d%%)", strategyStr, weight), err
+		})
+		if err != nil {
+			return s.abortCanary(ctx, progress, analysis, err)
+		}
+
+		stepStartedAt := time.Now().UTC()
+		s.updateCanaryAnalysis(analysis, func(a *CanaryAnalysis) {
+			a.Weight = weight
+			a.Steps = append(a.Steps, CanaryStep{Weight: weight, StartedAt: stepStartedAt})
+		})
+
+		err = progress.Run(fmt.Sprintf("analyze-%d", weight), func() (string, error) {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+
+			// both models are compared over the same step, so the current model serving no request with 100% weight
+			// leaves only the absolute error rate to check
+			prodStats := s.metrics.Stats(getNamespace(false)+"/"+modelName, stepStartedAt)
+			canaryStats := s.metrics.Stats(getNamespace(true)+"/"+modelName, stepStartedAt)
+			reason := analyzeCanary(canaryRequest, prodStats, canaryStats)
+
+			finishedAt := time.Now().UTC()
+			s.updateCanaryAnalysis(analysis, func(a *CanaryAnalysis) {
+				step := &a.Steps[len(a.Steps)-1]
+				step.FinishedAt = &finishedAt
+				step.Prod = prodStats
+				step.Canary = canaryStats
+				step.Passed = reason == ""
+				step.Reason = reason
+			})
+			if reason != "" {
+				return "", fmt.Errorf("%v", reason)
+			}
+			return fmt.Sprintf("%d requests, error rate %.3f (current %.3f), p95 latency %.1fms (current %.1fms)",
+				canaryStats.Requests, canaryStats.ErrorRate, prodStats.ErrorRate, canaryStats.P95LatencyMs, prodStats.P95LatencyMs), nil
+		})
+		if err != nil {
+			return s.abortCanary(ctx, progress, analysis, err)
+		}
+	}
+
+	err := progress.Run("promote", func() (string, error) {
+		timeout := defaultRolloutTimeout
+		if canaryRequest.TimeoutSeconds > 0 {
+			timeout = time.Duration(canaryRequest.TimeoutSeconds) * time.Second
+		}
+		// the steps of promotion are reported in the analysis instead of the job
+		promoteResponse := s.promote(ctx, nil, modelName, canaryRequest.ScaleDownCanary, timeout, deployedBy)
+		s.updateCanaryAnalysis(analysis, func(a *CanaryAnalysis) {
+			a.Promotion = promoteResponse
+		})
+		if err := promoteResponse.err(); err != nil {
+			return "", err
+		}
+		return "Promoted " + promoteResponse.ModelBaseDir, nil
+	})
+	if err != nil {
+		return s.abortCanary(ctx, progress, analysis, err)
+	}
+
+	finishedAt := time.Now().UTC()
+	return s.updateCanaryAnalysis(analysis, func(a *CanaryAnalysis) {
+		a.State = CanaryPromoted
+		a.FinishedAt = &finishedAt
+	}), nil
+}
+
+// abortCanary sends every request to the current model and records the reason
+func (s *Server) abortCanary(ctx context.Context, progress *jobs.Progress, analysis *CanaryAnalysis, reason error) (*CanaryAnalysis, error) {
+	modelName := analysis.ModelName
+	abort := func() (string, error) {
+		// the strategy is reset even when the job is canceled
+		strategyStr, err := s.setStrategy(context.Background(), modelName, constants.CurrentModelOnly, nil, nil)
+		return "Changed to strategy: " + strategyStr, err
+	}
+
+	var err error
+	if ctx.Err() == nil {
+		err = progress.Run("abort", abort)
+	} else {
+		_, err = abort()
+	}
+	if err != nil {
+		log.Printf("Failed to abort the canary of %v: %v", modelName, err)
+	}
+
+	finishedAt := time.Now().UTC()
+	return s.updateCanaryAnalysis(analysis, func(a *CanaryAnalysis) {
+		a.State = CanaryAborted
+		a.Weight = 0
+		a.Reason = reason.Error()
+		a.FinishedAt = &finishedAt
+	}), reason
+}
+
+// analyzeCanary compares the traffic of the new model with the current model
+// and returns the reason of failure, or an empty string when the new model is good enough
+func analyzeCanary(canaryRequest CanaryRequest, prodStats metrics.Stats, canaryStats metrics.Stats) string {
+	// the new model is never passed without being evaluated
+	if canaryStats.Requests == 0 {
+		return "The new model served no request."
+	}
+	if canaryStats.Requests < canaryRequest.MinRequests {
+		return fmt.Sprintf("The new model served %d requests, less than %d.", canaryStats.Requests, canaryRequest.MinRequests)
+	}
+	if canaryStats.ErrorRate > prodStats.ErrorRate+*canaryRequest.MaxErrorRateIncrease {
+		return fmt.Sprintf("The error rate of the new model %.3f exceeds %.3f of the current model by more than %.3f.",
+			canaryStats.ErrorRate, prodStats.ErrorRate, *canaryRequest.MaxErrorRateIncrease)
+	}
+	// latency can't be compared without the traffic of both models
+	if prodStats.Requests > 0 && canaryStats.Requests > 0 && canaryStats.P95LatencyMs > prodStats.P95LatencyMs*canaryRequest.MaxLatencyRatio {
+		return fmt.Sprintf("The p95 latency of the new model %.1fms is more than %.1f times %.1fms of the current model.",
+			canaryStats.P95LatencyMs, canaryRequest.MaxLatencyRatio, prodStats.P95LatencyMs)
+	}
+	return ""
+}
+
+// setStrategy changes the strategy of model through the traffic backend
+func (s *Server) setStrategy(ctx context.Context, modelName string, strategy constants.Strategy, weight *int, target *registry.TargetRule) (string, error) {
+	trafficStrategy := traffic.Strategy{Strategy: strategy, Target: target}
+	if strategy == constants.Canary {
+		if weight == nil {
+			return "", fmt.Errorf("Weight missing.")
+		}
+		trafficStrategy.Weight = *weight
+	}
+
+	strategyStr, err := s.traffic.SetStrategy(ctx, modelName, trafficStrategy)
+	if err != nil {
+		return "", err
+	}
+	return strategyStr, s.storeStrategy(ctx, modelName, strategy, weight, target)
+}
+
+// storeStrategy stores the strategy to the registry, which is used by direct routing
+func (s *Server) storeStrategy(ctx context.Context, modelName string, strategy constants.Strategy, weight *int, target *registry.TargetRule) error {
+	storedWeight := 0
+	if strategy == constants.Canary && weight != nil {
+		storedWeight = *weight
+	}
+	if strategy != constants.Targeted {
+		target = nil
+	}
+	return s.registry.SetStrategy(ctx, modelName, strategy, storedWeight, target)
+}
+
+// updateCanaryAnalysis modifies the analysis and returns its copy
+func (s *Server) updateCanaryAnalysis(analysis *CanaryAnalysis, modify func(a *CanaryAnalysis)) *CanaryAnalysis {
+	s.canariesMu.Lock()
+	defer s.canariesMu.Unlock()
+
+	modify(analysis)
+	return copyCanaryAnalysis(analysis)
+}
+
+// getCanaryAnalysis returns the copy of the last canary analysis of model, or nil
+func (s *Server) getCanaryAnalysis(modelName string) *CanaryAnalysis {
+	s.canariesMu.Lock()
+	defer s.canariesMu.Unlock()
+
+	analysis, ok := s.canaries[modelName]
+	if !ok {
+		return nil
+	}
+	return copyCanaryAnalysis(analysis)
+}
+
+func copyCanaryAnalysis(analysis *CanaryAnalysis) *CanaryAnalysis {
+	copied := *analysis
+	copied.Steps = append([]CanaryStep{}, analysis.Steps...)
+	return &copied
+}
@@ -0,0 +1,285 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/jobs"
+	"github.com/josh9191/mini-mnist-serving/metrics"
+	"github.com/josh9191/mini-mnist-serving/registry"
+)
+
+func startCanary(t *testing.T, s *Server, canaryReqBody map[string]interface{}) (*http.Response, jobs.Job) {
+	body, _ := json.Marshal(canaryReqBody)
+
+	r, err := http.NewRequest("POST", "/model:canary", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	handler := http.HandlerFunc(s.CanaryController)
+	handler.ServeHTTP(w, r)
+
+	var job jobs.Job
+	json.NewDecoder(w.Result().Body).Decode(&job)
+	return w.Result(), job
+}
+
+func getCanaryAnalysis(t *testing.T, s *Server, modelName string) (*http.Response, CanaryAnalysis) {
+	w := httptest.NewRecorder()
+	s.CanaryAnalysisController(w, httptest.NewRequest("GET", "/model/canary?model-name="+modelName, nil))
+
+	var analysis CanaryAnalysis
+	json.NewDecoder(w.Result().Body).Decode(&analysis)
+	return w.Result(), analysis
+}
+
+// sendPredictions sends predictions of mnist-cnn through the server until the returned function is called
+// The stub ingress sends every other request to the new model, which answers with canaryStatusCode.
+func sendPredictions(t *testing.T, s *Server, canaryStatusCode int) func() {
+	var count int32
+	modelServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1)%2 == 0 {
+			w.Header().Set(constants.CanaryUpstreamHeader, "mnist-canary-mnist-cnn-svc-8501")
+			w.WriteHeader(canaryStatusCode)
+		}
+		w.Write([]byte(`{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.9, 0.1, 0.0, 0.0, 0.0]]}`))
+	}))
+	modelServerUrl, _ := url.Parse(modelServer.URL)
+	handler := s.ModelPredictControllerWrapper(modelServerUrl.Host)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		body, _ := json.Marshal(make([]float32, 784))
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/model:predict?model-name=mnist-cnn", bytes.NewReader(body)))
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		modelServer.Close()
+	}
+}
+
+func TestCanaryController(t *testing.T) {
+	s, kubeClientSet := newTestServer()
+	enableTestUpstreamHeader(s)
+	deployBothSlots(t, s)
+	rollOutDeployments(kubeClientSet, constants.ProdNamespace)
+
+	stop := sendPredictions(t, s, http.StatusOK)
+	defer stop()
+
+	// latencies of the stub are too short to compare
+	resp, job := startCanary(t, s, map[string]interface{}{"model-name": "mnist-cnn", "weights": []int{50, 100}, "interval-seconds": 1, "min-requests": 1, "max-latency-ratio": 100})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+
+	// the strategy is changed only by the canary
+	resp = setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": constants.NewModelOnly})
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Strategy is changed during canary: Status Code: %d", resp.StatusCode)
+	}
+	// the second canary doesn't replace the analysis of the running one
+	resp, _ = startCanary(t, s, map[string]interface{}{"model-name": "mnist-cnn"})
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Second canary is started: Status Code: %d", resp.StatusCode)
+	}
+	if analysis := s.getCanaryAnalysis("mnist-cnn"); analysis.JobID != job.ID {
+		t.Errorf("Analysis is replaced: %+v", analysis)
+	}
+
+	job, err := s.jobs.Wait(context.TODO(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != jobs.Succeeded {
+		t.Fatalf("Error - Job: %+v", job)
+	}
+
+	resp, analysis := getCanaryAnalysis(t, s, "mnist-cnn")
+	if resp.StatusCode != http.StatusOK || analysis.State != CanaryPromoted || analysis.JobID != job.ID {
+		t.Fatalf("Wrong analysis: %d, %+v", resp.StatusCode, analysis)
+	}
+	if len(analysis.Steps) != 2 || analysis.Steps[1].Weight != 100 {
+		t.Fatalf("Wrong steps: %+v", analysis.Steps)
+	}
+	for _, step := range analysis.Steps {
+		if !step.Passed || step.Canary.Requests == 0 || step.Prod.Requests == 0 || step.FinishedAt == nil {
+			t.Errorf("Wrong step: %+v", step)
+		}
+	}
+	if analysis.Promotion == nil || !analysis.Promotion.Promoted {
+		t.Errorf("Wrong promotion: %+v", analysis.Promotion)
+	}
+
+	if getModelBasePath(getProdDeployment(t, s, "mnist-cnn")) != "gs://my-bucket/v2" {
+		t.Errorf("Current model is not promoted")
+	}
+	ingress, err := s.ingressClient.Get(context.TODO(), constants.CanaryNamespace, registry.IngressName("mnist-cnn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingress.Annotations["nginx.ingress.kubernetes.io/canary"] != "false" {
+		t.Errorf("Strategy is not changed: %v", ingress.Annotations)
+	}
+}
+
+func TestCanaryControllerAbort(t *testing.T) {
+	s, _ := newTestServer()
+	enableTestUpstreamHeader(s)
+	deployBothSlots(t, s)
+
+	// the new model fails
+	stop := sendPredictions(t, s, http.StatusInternalServerError)
+	defer stop()
+
+	_, job := startCanary(t, s, map[string]interface{}{"model-name": "mnist-cnn", "weights": []int{5, 100}, "interval-seconds": 1})
+	job, err := s.jobs.Wait(context.TODO(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != jobs.Failed || job.Steps[len(job.Steps)-1].Name != "abort" {
+		t.Fatalf("Wrong job: %+v", job)
+	}
+
+	_, analysis := getCanaryAnalysis(t, s, "mnist-cnn")
+	if analysis.State != CanaryAborted || len(analysis.Steps) != 1 || analysis.Steps[0].Passed || analysis.Reason == "" {
+		t.Fatalf("Wrong analysis: %+v", analysis)
+	}
+	if analysis.Steps[0].Canary.ErrorRate != 1 || analysis.Steps[0].Prod.ErrorRate != 0 {
+		t.Errorf("Wrong error rates: %+v", analysis.Steps[0])
+	}
+
+	ingress, err := s.ingressClient.Get(context.TODO(), constants.CanaryNamespace, registry.IngressName("mnist-cnn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingress.Annotations["nginx.ingress.kubernetes.io/canary"] != "false" {
+		t.Errorf("Strategy is not reset: %v", ingress.Annotations)
+	}
+	if getModelBasePath(getProdDeployment(t, s, "mnist-cnn")) != "gs://my-bucket/v1" {
+		t.Errorf("Current model is changed")
+	}
+}
+
+func TestCanaryControllerCancel(t *testing.T) {
+	s, _ := newTestServer()
+	deployBothSlots(t, s)
+
+	// nginx splits predictions without telling the serving model
+	resp, _ := startCanary(t, s, map[string]interface{}{"model-name": "mnist-cnn"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Canary is started without the serving model of predictions: Status Code: %d", resp.StatusCode)
+	}
+
+	enableTestUpstreamHeader(s)
+	_, job := startCanary(t, s, map[string]interface{}{"model-name": "mnist-cnn", "interval-seconds": 60})
+	if err := s.jobs.Cancel(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	job, err := s.jobs.Wait(context.TODO(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != jobs.Canceled {
+		t.Errorf("Wrong job: %+v", job)
+	}
+
+	_, analysis := getCanaryAnalysis(t, s, "mnist-cnn")
+	if analysis.State != CanaryAborted {
+		t.Errorf("Wrong analysis: %+v", analysis)
+	}
+	ingress, err := s.ingressClient.Get(context.TODO(), constants.CanaryNamespace, registry.IngressName("mnist-cnn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingress.Annotations["nginx.ingress.kubernetes.io/canary"] != "false" {
+		t.Errorf("Strategy is not reset: %v", ingress.Annotations)
+	}
+
+	resp = setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": constants.NewModelOnly})
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Strategy is not changed after canary: Status Code: %d", resp.StatusCode)
+	}
+}
+
+func TestCanaryControllerInvalidRequest(t *testing.T) {
+	s, _ := newTestServer()
+	registerModels(t, s, "mnist-cnn")
+
+	for _, canaryReqBody := range []map[string]interface{}{
+		{"model-name": "mnist-rnn"},
+		{"model-name": "mnist-cnn", "weights": []int{50, 25}},
+		{"model-name": "mnist-cnn", "weights": []int{0, 100}},
+		{"model-name": "mnist-cnn", "weights": []int{50, 120}},
+		{"model-name": "mnist-cnn", "interval-seconds": -1},
+		{"model-name": "mnist-cnn", "max-error-rate-increase": -0.1},
+		// not deployed
+		{"model-name": "mnist-cnn"},
+	} {
+		resp, _ := startCanary(t, s, canaryReqBody)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%v: Status Code: %d", canaryReqBody, resp.StatusCode)
+		}
+	}
+
+	resp, _ := getCanaryAnalysis(t, s, "mnist-cnn")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Analysis of model never started: Status Code: %d", resp.StatusCode)
+	}
+}
+
+func TestSetCanaryDefaults(t *testing.T) {
+	var canaryRequest CanaryRequest
+	if err := setCanaryDefaults(&canaryRequest); err != nil {
+		t.Fatal(err)
+	}
+	if canaryRequest.MinRequests != defaultMinRequests || *canaryRequest.MaxErrorRateIncrease != defaultMaxErrorRateIncrease {
+		t.Errorf("Wrong defaults: %+v", canaryRequest)
+	}
+}
+
+func TestAnalyzeCanary(t *testing.T) {
+	var canaryRequest CanaryRequest
+	setCanaryDefaults(&canaryRequest)
+
+	tests := []struct {
+		name   string
+		prod   metrics.Stats
+		canary metrics.Stats
+		passed bool
+	}{
+		{"good", metrics.Stats{Requests: 100, ErrorRate: 0.01, P95LatencyMs: 10}, metrics.Stats{Requests: 10, ErrorRate: 0.02, P95LatencyMs: 15}, true},
+		{"not enough requests", metrics.Stats{Requests: 100}, metrics.Stats{Requests: 9}, false},
+		{"no traffic of new model", metrics.Stats{}, metrics.Stats{}, false},
+		{"error rate", metrics.Stats{Requests: 100, ErrorRate: 0.01}, metrics.Stats{Requests: 10, ErrorRate: 0.1}, false},
+		{"latency", metrics.Stats{Requests: 100, P95LatencyMs: 10}, metrics.Stats{Requests: 10, P95LatencyMs: 16}, false},
+		{"no traffic of current model", metrics.Stats{}, metrics.Stats{Requests: 10, P95LatencyMs: 100}, true},
+	}
+	for _, test := range tests {
+		reason := analyzeCanary(canaryRequest, test.prod, test.canary)
+		if (reason == "") != test.passed {
+			t.Errorf("%v: %q", test.name, reason)
+		}
+	}
+}
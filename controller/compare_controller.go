@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/metrics"
+	"github.com/josh9191/mini-mnist-serving/registry"
+)
+
+// SlotHostFunc returns the host (with port) of TF Serving of the current or new model, bypassing the ingress
+type SlotHostFunc func(modelName string, isNewModel bool) string
+
+// ServiceHost returns the cluster DNS name of the service of model slot, which is resolved only inside the cluster
+func ServiceHost(modelName string, isNewModel bool) string {
+	return fmt.Sprintf("%s.%s.svc:8501", registry.ServiceName(modelName), getNamespace(isNewModel))
+}
+
+const (
+	// ProdSlot is the slot of the current model
+	ProdSlot = "prod"
+	// CanarySlot is the slot of the new model
+	CanarySlot = "canary"
+	// UnknownSlot is the slot of a prediction which the traffic backend split by the canary weight without telling the serving model
+	UnknownSlot = "unknown"
+)
+
+// SlotPrediction stores the prediction of a model slot with the model which served it
+// Error is set instead of Probabilities when the model fails.
+type SlotPrediction struct {
+	Probabilities []float32 `json:"probabilities,omitempty"`
+	Argmax        int       `json:"argmax"`
+	Confidence    float32   `json:"confidence"`
+	Slot          string    `json:"slot"`
+	// ModelBasePath and Revision are the model deployed to the slot
+	ModelBasePath string  `json:"model-base-path,omitempty"`
+	Revision      int     `json:"revision,omitempty"`
+	LatencyMs     float64 `json:"latency-ms"`
+	Error         string  `json:"error,omitempty"`
+}
+
+// newSlotPrediction returns SlotPrediction of probabilities predicted by the slot
+func newSlotPrediction(probabilities []float32, slot string, latency time.Duration) SlotPrediction {
+	prediction := SlotPrediction{
+		Probabilities: probabilities,
+		Argmax:        metrics.Argmax(probabilities),
+		Slot:          slot,
+		LatencyMs:     float64(latency) / float64(time.Millisecond),
+	}
+	if prediction.Argmax >= 0 {
+		prediction.Confidence = probabilities[prediction.Argmax]
+	}
+	return prediction
+}
+
+// setSlotModel sets the model base path and revision of the deployment of the slot of prediction
+// They are left empty when the slot is unknown or the deployment can't be read.
+func (s *Server) setSlotModel(ctx context.Context, prediction *SlotPrediction, modelName string) {
+	if prediction.Slot == UnknownSlot {
+		return
+	}
+	deployment, err := s.getCachedDeployment(ctx, modelName, prediction.Slot == CanarySlot)
+	if err != nil {
+		log.Printf("Failed to get the deployment of %v: %v", modelName, err)
+		return
+	}
+	prediction.ModelBasePath = getModelBasePath(deployment)
+	if revisions := getRevisions(deployment); len(revisions) > 0 {
+		prediction.Revision = revisions[len(revisions)-1].Revision
+	}
+}
+
+// CompareResponse stores the predictions of the current and new models for the same input
+type CompareResponse struct {
+	ModelName string         `json:"model-name"`
+	Prod      SlotPrediction `json:"prod"`
+	Canary    SlotPrediction `json:"canary"`
+	// Agree reports whether both models predict the same class
+	Agree bool `json:"agree"`
+}
+
+// CompareControllerWrapper handles side-by-side prediction
+// The input is sent to the services of both models at the same time regardless of the strategy,
+// through the gRPC ports of the services when predictions are sent by gRPC.
+func (s *Server) CompareControllerWrapper(slotHost SlotHostFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pixels, err := readPixels(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requestJson, err := newPredictRequest([][]float32{pixels})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		modelName, err := s.getRequestModelName(r.URL.Query().Get("model-name"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		compareResponse := CompareResponse{ModelName: modelName}
+		var wg sync.WaitGroup
+		for _, isNewModel := range []bool{false, true} {
+			prediction := &compareResponse.Prod
+			if isNewModel {
+				prediction = &compareResponse.Canary
+			}
+			wg.Add(1)
+			go func(isNewModel bool) {
+				defer wg.Done()
+				// the latencies are measured over the protocol of predictions
+				if s.grpcClient != nil {
+					*prediction = s.predictGRPCSlot(r.Context(), modelName, isNewModel, pixels)
+				} else {
+					*prediction = predictSlot(r.Context(), slotHost(modelName, isNewModel), modelName, isNewModel, requestJson)
+				}
+				if prediction.Error == "" {
+					s.setSlotModel(r.Context(), prediction, modelName)
+				}
+			}(isNewModel)
+		}
+		wg.Wait()
+		compareResponse.Agree = compareResponse.Prod.Error == "" && compareResponse.Canary.Error == "" &&
+			compareResponse.Prod.Argmax == compareResponse.Canary.Argmax
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(compareResponse)
+	}
+}
+
+// sendSlotPrediction sends the prediction request to TF Serving at host directly
+func sendSlotPrediction(ctx context.Context, host string, modelName string, requestJson []byte) (*http.Response, []byte, error) {
+	// [FIXME] scheme as flag
+	predictUrl := url.URL{
+		Scheme: "http",
+		Host:   host,
+		Path:   fmt.Sprintf("/v1/models/%s:predict", modelName),
+	}
+	return postPrediction(ctx, predictUrl, requestJson, nil)
+}
+
+// predictSlot returns the prediction of TF Serving of the slot at host
+func predictSlot(ctx context.Context, host string, modelName string, isNewModel bool, requestJson []byte) SlotPrediction {
+	start := time.Now()
+	resp, body, err := sendSlotPrediction(ctx, host, modelName, requestJson)
+	latency := time.Since(start)
+	if err == nil && resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("Status Code: %d, %v", resp.StatusCode, string(body))
+	}
+	var predResp PredictResponse
+	if err == nil {
+		err = json.Unmarshal(body, &predResp)
+	}
+	if err == nil && len(predResp.Predictions) == 0 {
+		err = fmt.Errorf("Invalid prediction response from the model server.")
+	}
+	if err != nil {
+		return newSlotError(err, isNewModel, latency)
+	}
+	return newSlotPrediction(predResp.Predictions[0], getSlotName(isNewModel), latency)
+}
+
+// predictGRPCSlot returns the prediction of the gRPC port of TF Serving of the slot
+func (s *Server) predictGRPCSlot(ctx context.Context, modelName string, isNewModel bool, pixels []float32) SlotPrediction {
+	start := time.Now()
+	probabilities, err := s.sendGRPCSlotPrediction(ctx, modelName, isNewModel, pixels)
+	latency := time.Since(start)
+	if err != nil {
+		return newSlotError(err, isNewModel, latency)
+	}
+	return newSlotPrediction(probabilities, getSlotName(isNewModel), latency)
+}
+
+// newSlotError returns SlotPrediction of the slot which failed to predict
+func newSlotError(err error, isNewModel bool, latency time.Duration) SlotPrediction {
+	prediction := newSlotPrediction(nil, getSlotName(isNewModel), latency)
+	prediction.Error = err.Error()
+	return prediction
+}
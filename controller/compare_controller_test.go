@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+)
+
+// newSlotServer returns TF Serving stub of mnist-cnn answering with body
+func newSlotServer(t *testing.T, statusCode int, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models/mnist-cnn:predict" {
+			t.Errorf("Wrong path: %v", r.URL.Path)
+		}
+		if r.Header.Get(constants.CanaryHeader) != "" {
+			t.Errorf("Canary header is set: %v", r.Header.Get(constants.CanaryHeader))
+		}
+		w.WriteHeader(statusCode)
+		w.Write([]byte(body))
+	}))
+}
+
+func compare(t *testing.T, s *Server, prodServer *httptest.Server, canaryServer *httptest.Server, pixels []float32) (*http.Response, CompareResponse) {
+	slotHost := func(modelName string, isNewModel bool) string {
+		server := prodServer
+		if isNewModel {
+			server = canaryServer
+		}
+		serverUrl, _ := url.Parse(server.URL)
+		return serverUrl.Host
+	}
+
+	body, _ := json.Marshal(pixels)
+	w := httptest.NewRecorder()
+	s.CompareControllerWrapper(slotHost).ServeHTTP(w, httptest.NewRequest("POST", "/model:compare?model-name=mnist-cnn", bytes.NewReader(body)))
+
+	var compareResponse CompareResponse
+	json.NewDecoder(w.Result().Body).Decode(&compareResponse)
+	return w.Result(), compareResponse
+}
+
+func TestCompareController(t *testing.T) {
+	prodServer := newSlotServer(t, http.StatusOK, `{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.9, 0.1, 0.0, 0.0, 0.0]]}`)
+	defer prodServer.Close()
+	s, _ := newTestServer()
+	registerModels(t, s, "mnist-cnn")
+
+	for _, test := range []struct {
+		name         string
+		canaryBody   string
+		canaryArgmax int
+		agree        bool
+	}{
+		{"agree", `{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.6, 0.4, 0.0, 0.0, 0.0]]}`, 5, true},
+		{"disagree", `{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.2, 0.8, 0.0, 0.0, 0.0]]}`, 6, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			canaryServer := newSlotServer(t, http.StatusOK, test.canaryBody)
+			defer canaryServer.Close()
+
+			resp, compareResponse := compare(t, s, prodServer, canaryServer, make([]float32, 784))
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+			}
+			if compareResponse.ModelName != "mnist-cnn" || compareResponse.Agree != test.agree {
+				t.Errorf("Wrong comparison: %+v", compareResponse)
+			}
+			if compareResponse.Prod.Argmax != 5 || compareResponse.Prod.Confidence != 0.9 || compareResponse.Prod.Slot != ProdSlot || compareResponse.Prod.Error != "" {
+				t.Errorf("Wrong prediction of the current model: %+v", compareResponse.Prod)
+			}
+			if compareResponse.Canary.Argmax != test.canaryArgmax || compareResponse.Canary.Slot != CanarySlot || len(compareResponse.Canary.Probabilities) != 10 || compareResponse.Canary.Error != "" {
+				t.Errorf("Wrong prediction of the new model: %+v", compareResponse.Canary)
+			}
+		})
+	}
+}
+
+func TestCompareControllerSlotFailure(t *testing.T) {
+	prodServer := newSlotServer(t, http.StatusOK, `{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.9, 0.1, 0.0, 0.0, 0.0]]}`)
+	defer prodServer.Close()
+	canaryServer := newSlotServer(t, http.StatusNotFound, `{"error": "Servable not found for request: Latest(mnist-cnn)"}`)
+	defer canaryServer.Close()
+	s, _ := newTestServer()
+	registerModels(t, s, "mnist-cnn")
+
+	resp, compareResponse := compare(t, s, prodServer, canaryServer, make([]float32, 784))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+	if compareResponse.Agree || compareResponse.Prod.Argmax != 5 {
+		t.Errorf("Wrong comparison: %+v", compareResponse)
+	}
+	if compareResponse.Canary.Error == "" || compareResponse.Canary.Argmax != -1 || compareResponse.Canary.Probabilities != nil {
+		t.Errorf("Failure of the new model is not reported: %+v", compareResponse.Canary)
+	}
+}
+
+func TestCompareControllerInvalidRequest(t *testing.T) {
+	s, _ := newTestServer()
+	registerModels(t, s, "mnist-cnn")
+
+	resp, _ := compare(t, s, nil, nil, make([]float32, 10))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Wrong number of pixels: Status Code: %d", resp.StatusCode)
+	}
+}
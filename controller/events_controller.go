@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/registry"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// statusEventDelay collects the burst of watch events into one status event
+var statusEventDelay = 200 * time.Millisecond
+
+// ModelEventsController streams the status of model as Server-Sent Events
+// A "status" event is sent first and whenever the deployments, Pods or strategy of model change.
+func (s *Server) ModelEventsController(w http.ResponseWriter, r *http.Request) {
+	modelName, err := s.getRequestModelName(r.URL.Query().Get("model-name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported.", 500)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	changes, err := s.watchModel(ctx, modelName)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var lastData []byte
+	for {
+		modelStatus, err := s.getModelStatus(ctx, modelName)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			data, _ := json.Marshal(err.Error())
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+		} else if data, _ := json.Marshal(modelStatus); !bytes.Equal(data, lastData) {
+			fmt.Fprintf(w, "event: status\ndata: %s\n\n", data)
+			lastData = data
+		} else {
+			// keep the connection through proxies
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		flusher.Flush()
+
+		select {
+		case <-changes:
+			time.Sleep(statusEventDelay)
+		case <-time.After(rolloutResyncInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watchModel watches the deployments and Pods of both slots and the model registry storing the strategy
+// The objects are not watched again when the cache is enabled, and the changes pushed by the informers are used.
+// The returned channel receives a value when any of them changes until ctx is done.
+func (s *Server) watchModel(ctx context.Context, modelName string) (<-chan struct{}, error) {
+	// a pending change is enough to send the latest status
+	changes := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+
+	var watchers []watch.Interface
+	if s.cache != nil {
+		objects, unsubscribe := s.cache.Subscribe()
+		go func() {
+			defer unsubscribe()
+			for {
+				select {
+				case object := <-objects:
+					if isModelObject(object, modelName) {
+						notify()
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	} else {
+		for _, namespace := range []string{constants.ProdNamespace, constants.CanaryNamespace} {
+			deploymentWatcher, err := s.kubeClientSet.AppsV1().Deployments(namespace).Watch(ctx, metav1.ListOptions{
+				FieldSelector: fields.OneTermEqualSelector("metadata.name", registry.DeploymentName(modelName)).String(),
+			})
+			if err != nil {
+				stopWatchers(watchers)
+				return nil, err
+			}
+			watchers = append(watchers, deploymentWatcher)
+			podWatcher, err := s.kubeClientSet.CoreV1().Pods(namespace).Watch(ctx, metav1.ListOptions{
+				LabelSelector: labels.SelectorFromSet(registry.Labels(modelName)).String(),
+			})
+			if err != nil {
+				stopWatchers(watchers)
+				return nil, err
+			}
+			watchers = append(watchers, podWatcher)
+		}
+		registryWatcher, err := s.kubeClientSet.CoreV1().ConfigMaps(constants.ProdNamespace).Watch(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("metadata.name", registry.ConfigMapName).String(),
+		})
+		if err != nil {
+			stopWatchers(watchers)
+			return nil, err
+		}
+		watchers = append(watchers, registryWatcher)
+	}
+
+	for _, watcher := range watchers {
+		go func(watcher watch.Interface) {
+			defer watcher.Stop()
+			for {
+				select {
+				case _, ok := <-watcher.ResultChan():
+					if !ok {
+						// the status is still resynced periodically
+						return
+					}
+					notify()
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(watcher)
+	}
+	return changes, nil
+}
+
+// isModelObject reports whether the cached object belongs to model, or is the model registry storing its strategy
+func isModelObject(object metav1.Object, modelName string) bool {
+	if object.GetNamespace() == constants.ProdNamespace && object.GetName() == registry.ConfigMapName {
+		return true
+	}
+	switch object.GetName() {
+	case registry.DeploymentName(modelName), registry.ServiceName(modelName), registry.IngressName(modelName):
+		return true
+	}
+	return labels.SelectorFromSet(registry.Labels(modelName)).Matches(labels.Set(object.GetLabels()))
+}
+
+func stopWatchers(watchers []watch.Interface) {
+	for _, watcher := range watchers {
+		watcher.Stop()
+	}
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// readStatusEvent reads the next status event of the stream
+func readStatusEvent(t *testing.T, reader *bufio.Reader) *ModelStatus {
+	event := ""
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if event != "status" {
+				t.Fatalf("Unexpected %v event: %v", event, line)
+			}
+			var modelStatus ModelStatus
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &modelStatus); err != nil {
+				t.Fatal(err)
+			}
+			return &modelStatus
+		}
+	}
+}
+
+func TestModelEventsController(t *testing.T) {
+	for _, withCache := range []bool{false, true} {
+		testModelEventsController(t, withCache)
+	}
+}
+
+func testModelEventsController(t *testing.T, withCache bool) {
+	prodDeployment := readyDeployment(constants.ProdNamespace)
+	prodDeployment.Spec.Replicas = int32Ptr(2)
+	s, kubeClientSet := newTestServer(prodDeployment, readyDeployment(constants.CanaryNamespace), canaryIngress(map[string]string{"nginx.ingress.kubernetes.io/canary": "true"}))
+	registerModels(t, s, constants.DefaultModelName)
+	if withCache {
+		// the changes are pushed by the informers
+		enableTestCache(t, s)
+		kubeClientSet.ClearActions()
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(s.ModelEventsController))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"?model-name="+constants.DefaultModelName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Error - Status Code: %d, Content-Type: %v", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(resp.Body)
+
+	modelStatus := readStatusEvent(t, reader)
+	if withCache {
+		for _, action := range kubeClientSet.Actions() {
+			if action.GetVerb() == "watch" {
+				t.Errorf("%v is watched again", action.GetResource().Resource)
+			}
+		}
+	}
+	if modelStatus.Prod.AvailableReplicas != 1 || modelStatus.Prod.Rollout == nil || modelStatus.Prod.Rollout.State != RolloutProgressing {
+		t.Errorf("Wrong current model: %+v", modelStatus.Prod)
+	}
+
+	// the rollout progress is pushed
+	prodDeployment.Status.AvailableReplicas = 2
+	prodDeployment.Status.UpdatedReplicas = 2
+	prodDeployment.Status.ReadyReplicas = 2
+	if _, err := kubeClientSet.AppsV1().Deployments(constants.ProdNamespace).UpdateStatus(ctx, prodDeployment, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	modelStatus = readStatusEvent(t, reader)
+	if modelStatus.Prod.AvailableReplicas != 2 || modelStatus.Prod.Rollout.State != RolloutComplete {
+		t.Errorf("Wrong current model after rollout: %+v", modelStatus.Prod)
+	}
+
+	// the strategy change is pushed
+	if resp := setStrategy(t, s, map[string]interface{}{"strategy": constants.Canary, "weight": 20}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+	modelStatus = readStatusEvent(t, reader)
+	if modelStatus.Strategy.Strategy != constants.Canary || *modelStatus.Strategy.Weight != 20 {
+		t.Errorf("Wrong strategy: %+v", modelStatus.Strategy)
+	}
+}
+
+func TestModelEventsControllerUnknownModel(t *testing.T) {
+	s, _ := newTestServer()
+	w := httptest.NewRecorder()
+	s.ModelEventsController(w, httptest.NewRequest("GET", "/model/events?model-name=unknown", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Events of unknown model: %d", w.Code)
+	}
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/metrics"
+	"github.com/josh9191/mini-mnist-serving/registry"
+	"github.com/josh9191/mini-mnist-serving/tfserving"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCServiceHost returns the cluster DNS name of the gRPC port of the service of model slot
+func GRPCServiceHost(modelName string, isNewModel bool) string {
+	return fmt.Sprintf("%s.%s.svc:8500", registry.ServiceName(modelName), getNamespace(isNewModel))
+}
+
+// EnableGRPCPrediction makes the server send predictions and batch predictions to the gRPC port of TF Serving at slotHost
+// The ingress doesn't route gRPC, so the server selects the model by the strategy like direct routing.
+func (s *Server) EnableGRPCPrediction(client *tfserving.Client, slotHost SlotHostFunc) {
+	s.grpcClient = client
+	s.grpcSlotHost = slotHost
+}
+
+// sendGRPCPrediction sends images to the gRPC port of the model selected by routing
+// and returns the slot which served them with their probabilities and the latency
+func (s *Server) sendGRPCPrediction(ctx context.Context, modelName string, images [][]float32, routing routingStrategy, r *http.Request) (string, [][]float32, time.Duration, error) {
+	isNewModel := routesToNewModel(modelName, routing, r)
+	start := time.Now()
+	probabilities, err := s.grpcClient.Predict(ctx, s.grpcSlotHost(modelName, isNewModel), modelName, images, imageSize, imageSize, 1)
+	latency := time.Since(start)
+	slot := getSlotName(isNewModel)
+	s.recordPrediction(modelName, slot, metrics.Observation{
+		Time:    start,
+		Latency: latency,
+		Failed:  isServerError(err),
+	})
+	return slot, probabilities, latency, err
+}
+
+// predictGRPC sends pixels to the gRPC port of the model selected by routing
+// and returns the slot which served them with their probabilities and the latency
+func (s *Server) predictGRPC(ctx context.Context, modelName string, pixels []float32, routing routingStrategy, r *http.Request) (string, []float32, time.Duration, error) {
+	slot, probabilities, latency, err := s.sendGRPCPrediction(ctx, modelName, [][]float32{pixels}, routing, r)
+	if err != nil {
+		return slot, nil, latency, err
+	}
+	return slot, probabilities[0], latency, nil
+}
+
+// sendGRPCSlotPrediction sends pixels to the gRPC port of the model slot regardless of the strategy and returns its probabilities
+func (s *Server) sendGRPCSlotPrediction(ctx context.Context, modelName string, isNewModel bool, pixels []float32) ([]float32, error) {
+	probabilities, err := s.grpcClient.Predict(ctx, s.grpcSlotHost(modelName, isNewModel), modelName, [][]float32{pixels}, imageSize, imageSize, 1)
+	if err != nil {
+		return nil, err
+	}
+	return probabilities[0], nil
+}
+
+// isServerError reports whether the gRPC error is the failure of TF Serving, like 5xx status codes of REST
+func isServerError(err error) bool {
+	switch status.Code(err) {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.FailedPrecondition:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,306 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/tfserving"
+	"github.com/josh9191/mini-mnist-serving/tfserving/apis"
+	"github.com/josh9191/mini-mnist-serving/tfserving/framework"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// slotPredictionServer predicts class of every image like TF Serving of a slot
+type slotPredictionServer struct {
+	apis.UnimplementedPredictionServiceServer
+	class int
+	err   error
+}
+
+func (s *slotPredictionServer) Predict(ctx context.Context, req *apis.PredictRequest) (*apis.PredictResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	input := req.GetInputs()[tfserving.DefaultInputName]
+	numImages := int(input.GetTensorShape().GetDim()[0].GetSize())
+	if len(input.GetFloatVal()) != numImages*imageSize*imageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "wrong input of %d values", len(input.GetFloatVal()))
+	}
+	probabilities := make([]float32, numImages*10)
+	for i := 0; i < numImages; i++ {
+		probabilities[i*10+s.class] = 1
+	}
+	return &apis.PredictResponse{Outputs: map[string]*framework.TensorProto{
+		"dense_1": {Dtype: framework.DataType_DT_FLOAT, FloatVal: probabilities},
+	}}, nil
+}
+
+// enableTestGRPCPrediction makes s send predictions to servers in memory by the slot hosts "prod:8500" and "canary:8500"
+func enableTestGRPCPrediction(t *testing.T, s *Server, prodServer apis.PredictionServiceServer, canaryServer apis.PredictionServiceServer) {
+	listeners := make(map[string]*bufconn.Listener)
+	for host, predictionServer := range map[string]apis.PredictionServiceServer{"prod:8500": prodServer, "canary:8500": canaryServer} {
+		listener := bufconn.Listen(1 << 20)
+		server := grpc.NewServer()
+		apis.RegisterPredictionServiceServer(server, predictionServer)
+		go server.Serve(listener)
+		t.Cleanup(server.Stop)
+		listeners[host] = listener
+	}
+
+	client := tfserving.NewClient(tfserving.DefaultInputName, grpc.WithContextDialer(func(ctx context.Context, host string) (net.Conn, error) {
+		return listeners[host].Dial()
+	}))
+	t.Cleanup(func() { client.Close() })
+	slotHost, err := ParseGRPCSlotHosts("mnist-cnn/prod=prod:8500,mnist-cnn/canary=canary:8500")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.EnableGRPCPrediction(client, slotHost)
+}
+
+func TestParseGRPCSlotHosts(t *testing.T) {
+	slotHost, err := ParseGRPCSlotHosts("mnist-cnn/canary=localhost:8500")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host := slotHost("mnist-cnn", true); host != "localhost:8500" {
+		t.Errorf("Wrong host: %v", host)
+	}
+	if host := slotHost("mnist-cnn", false); host != "mnist-cnn-svc.mnist-prod.svc:8500" {
+		t.Errorf("Wrong host: %v", host)
+	}
+}
+
+func TestModelPredictControllerWrapperGRPC(t *testing.T) {
+	s, _ := newTestServer()
+	enableTestGRPCPrediction(t, s, &slotPredictionServer{class: 5}, &slotPredictionServer{class: 3})
+	// with Canary strategy and 30% weight read from the ingress
+	deployBothSlots(t, s)
+
+	// the ingress is not used
+	handler := s.ModelPredictControllerWrapper(testIngressHost)
+	canaryPredictions := 0
+	for i := 0; i < 100; i++ {
+		routingKey := fmt.Sprintf("user-%d", i)
+		body, _ := json.Marshal(make([]float32, 784))
+		r := httptest.NewRequest("POST", "/model:predict?model-name=mnist-cnn", bytes.NewReader(body))
+		r.Header.Set(constants.RoutingKeyHeader, routingKey)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Error - Status Code: %d, %v", w.Code, w.Body.String())
+		}
+		var prediction SlotPrediction
+		json.NewDecoder(w.Body).Decode(&prediction)
+
+		isNewModel := routesToNewModel("mnist-cnn", routingStrategy{strategy: constants.Canary, weight: 30}, requestWithRoutingKey(routingKey))
+		if (prediction.Slot == CanarySlot) != isNewModel || (prediction.Argmax == 3) != isNewModel || prediction.Confidence != 1 {
+			t.Errorf("%v: Wrong prediction: %+v", routingKey, prediction)
+		}
+		if isNewModel {
+			canaryPredictions++
+			if prediction.ModelBasePath != "gs://my-bucket/v2" {
+				t.Errorf("%v: Wrong model: %+v", routingKey, prediction)
+			}
+		}
+	}
+	if canaryPredictions == 0 || canaryPredictions == 100 {
+		t.Errorf("Requests are not split: %d", canaryPredictions)
+	}
+}
+
+func TestModelPredictControllerWrapperGRPCShadow(t *testing.T) {
+	s, _ := newTestServer()
+	enableTestGRPCPrediction(t, s, &slotPredictionServer{class: 5}, &slotPredictionServer{class: 3})
+	deployBothSlots(t, s)
+	resp := setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": constants.Shadow})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+
+	// the ingress is not used by the shadow request either
+	handler := s.ModelPredictControllerWrapper(testIngressHost)
+	body, _ := json.Marshal(make([]float32, 784))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/model:predict?model-name=mnist-cnn", bytes.NewReader(body)))
+	var prediction SlotPrediction
+	json.NewDecoder(w.Body).Decode(&prediction)
+	if w.Code != http.StatusOK || prediction.Argmax != 5 || prediction.Slot != ProdSlot {
+		t.Errorf("Wrong prediction: %d, %+v", w.Code, prediction)
+	}
+
+	shadowResponse := waitForComparisons(t, s, 1)
+	if shadowResponse.Comparisons != 1 || shadowResponse.CanaryErrors != 0 || shadowResponse.AgreementRate != 0 {
+		t.Errorf("Wrong agreement: %+v", shadowResponse)
+	}
+	if len(shadowResponse.Recent) != 1 || shadowResponse.Recent[0].CanaryPrediction[3] != 1 {
+		t.Errorf("Wrong recent predictions: %+v", shadowResponse.Recent)
+	}
+}
+
+func TestCompareControllerGRPC(t *testing.T) {
+	s, _ := newTestServer()
+	registerModels(t, s, "mnist-cnn")
+	enableTestGRPCPrediction(t, s, &slotPredictionServer{class: 5}, &slotPredictionServer{err: status.Error(codes.NotFound, "Servable not found")})
+
+	// the REST API of the slots is not used
+	handler := s.CompareControllerWrapper(func(modelName string, isNewModel bool) string {
+		t.Errorf("REST host of %v is used", getSlotName(isNewModel))
+		return testIngressHost
+	})
+	body, _ := json.Marshal(make([]float32, 784))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/model:compare?model-name=mnist-cnn", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Error - Status Code: %d, %v", w.Code, w.Body.String())
+	}
+
+	var compareResponse CompareResponse
+	json.NewDecoder(w.Body).Decode(&compareResponse)
+	if compareResponse.Agree || compareResponse.Prod.Argmax != 5 || compareResponse.Prod.Confidence != 1 || compareResponse.Prod.Slot != ProdSlot || compareResponse.Prod.Error != "" {
+		t.Errorf("Wrong prediction of the current model: %+v", compareResponse.Prod)
+	}
+	if compareResponse.Canary.Error == "" || compareResponse.Canary.Argmax != -1 || compareResponse.Canary.Slot != CanarySlot {
+		t.Errorf("Failure of the new model is not reported: %+v", compareResponse.Canary)
+	}
+}
+
+// predictGRPCBatch returns the batch prediction of mnist-cnn in chunks of 2 images
+func predictGRPCBatch(t *testing.T, s *Server, images []interface{}) BatchPredictResponse {
+	body, _ := json.Marshal(images)
+	w := httptest.NewRecorder()
+	s.ModelPredictBatchControllerWrapper(testIngressHost, 2).ServeHTTP(w, httptest.NewRequest("POST", "/model:predictBatch?model-name=mnist-cnn", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Error - Status Code: %d, %v", w.Code, w.Body.String())
+	}
+	var batchResponse BatchPredictResponse
+	if err := json.NewDecoder(w.Body).Decode(&batchResponse); err != nil || len(batchResponse.Predictions) != len(images) {
+		t.Fatalf("Invalid response: %+v, %v", batchResponse, err)
+	}
+	return batchResponse
+}
+
+func TestModelPredictBatchControllerGRPC(t *testing.T) {
+	s, _ := newTestServer()
+	enableTestGRPCPrediction(t, s, &slotPredictionServer{class: 5}, &slotPredictionServer{err: status.Error(codes.Unavailable, "not ready")})
+	deployBothSlots(t, s)
+	setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": constants.CurrentModelOnly})
+
+	batchResponse := predictGRPCBatch(t, s, []interface{}{batchImage(0), "pixels", batchImage(1), batchImage(2)})
+	for i, prediction := range batchResponse.Predictions {
+		if i == 1 {
+			if prediction.Error == "" {
+				t.Errorf("Invalid image is predicted: %+v", prediction)
+			}
+			continue
+		}
+		if prediction.Error != "" || prediction.Argmax != 5 || prediction.Slot != ProdSlot || prediction.ModelBasePath != "gs://my-bucket/v1" {
+			t.Errorf("%d: wrong prediction: %+v", i, prediction)
+		}
+	}
+
+	// the failure of the new model is reported
+	setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": constants.NewModelOnly})
+	start := time.Now()
+	batchResponse = predictGRPCBatch(t, s, []interface{}{batchImage(0)})
+	if prediction := batchResponse.Predictions[0]; prediction.Error == "" {
+		t.Errorf("Failed prediction is not reported: %+v", prediction)
+	}
+	if stats := s.metrics.Stats(constants.CanaryNamespace+"/mnist-cnn", start); stats.Errors != 1 {
+		t.Errorf("Wrong stats of the new model: %+v", stats)
+	}
+}
+
+// benchmarkImages returns n images of 784 pixels
+func benchmarkImages(n int) [][]float32 {
+	images := make([][]float32, n)
+	for i := range images {
+		images[i] = make([]float32, imageSize*imageSize)
+		for j := range images[i] {
+			images[i][j] = float32(j%256) / 255
+		}
+	}
+	return images
+}
+
+// BenchmarkPredictREST measures the prediction by the REST API of TF Serving over a local TCP connection,
+// which is compared with BenchmarkPredictGRPC by "go test ./controller -run ^$ -bench Predict".
+func BenchmarkPredictREST(b *testing.B) {
+	// TF Serving decodes the instances and encodes the predictions in JSON
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Instances [][][][]float32 `json:"instances"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		predictions := make([][]float32, len(req.Instances))
+		for i := range predictions {
+			predictions[i] = make([]float32, 10)
+			predictions[i][i%10] = 1
+		}
+		json.NewEncoder(w).Encode(PredictResponse{Predictions: predictions})
+	}))
+	defer server.Close()
+	serverUrl, _ := url.Parse(server.URL)
+
+	for _, numImages := range []int{1, DefaultMaxBatchSize} {
+		images := benchmarkImages(numImages)
+		b.Run(fmt.Sprintf("images-%d", numImages), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				requestJson, err := newPredictRequest(images)
+				if err != nil {
+					b.Fatal(err)
+				}
+				_, body, err := sendSlotPrediction(context.TODO(), serverUrl.Host, "mnist-cnn", requestJson)
+				if err != nil {
+					b.Fatal(err)
+				}
+				var predResp PredictResponse
+				if err := json.Unmarshal(body, &predResp); err != nil || len(predResp.Predictions) != numImages {
+					b.Fatalf("Invalid response: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPredictGRPC measures the prediction by the gRPC API of TF Serving over a local TCP connection
+func BenchmarkPredictGRPC(b *testing.B) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	server := grpc.NewServer()
+	apis.RegisterPredictionServiceServer(server, &slotPredictionServer{class: 5})
+	go server.Serve(listener)
+	defer server.Stop()
+	client := tfserving.NewClient(tfserving.DefaultInputName)
+	defer client.Close()
+
+	for _, numImages := range []int{1, DefaultMaxBatchSize} {
+		images := benchmarkImages(numImages)
+		b.Run(fmt.Sprintf("images-%d", numImages), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				probabilities, err := client.Predict(context.TODO(), listener.Addr().String(), "mnist-cnn", images, imageSize, imageSize, 1)
+				if err != nil || len(probabilities) != numImages {
+					b.Fatalf("Invalid response: %v", err)
+				}
+			}
+		})
+	}
+}
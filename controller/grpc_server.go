@@ -0,0 +1,342 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/api"
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/jobs"
+	"github.com/josh9191/mini-mnist-serving/preprocess"
+	"github.com/josh9191/mini-mnist-serving/registry"
+	"github.com/josh9191/mini-mnist-serving/traffic"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GRPCServer serves the gRPC API of the server by the same controllers as the HTTP API
+type GRPCServer struct {
+	api.UnimplementedMnistServingServer
+	server              *Server
+	googleCredsFilePath string
+	ingressHost         string
+}
+
+// NewGRPCServer returns GRPCServer of s, which deploys models with the google credentials
+// and sends predictions through the ingress like DeployControllerWrapper and ModelPredictControllerWrapper
+func NewGRPCServer(s *Server, googleCredsFilePath string, ingressHost string) *GRPCServer {
+	return &GRPCServer{
+		server:              s,
+		googleCredsFilePath: googleCredsFilePath,
+		ingressHost:         ingressHost,
+	}
+}
+
+// Deploy starts a job deploying model and returns the job
+func (g *GRPCServer) Deploy(ctx context.Context, req *api.DeployRequest) (*api.Job, error) {
+	googleCredsB64Encoded, err := readFileToBase64String(g.googleCredsFilePath)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	deployRequest := DeployRequest{
+		ModelBaseDir:   req.GetModelBaseDir(),
+		ModelName:      req.GetModelName(),
+		IsNewModel:     req.GetIsNewModel(),
+		NumReplicas:    req.GetNumReplicas(),
+		Wait:           req.GetWait(),
+		TimeoutSeconds: int(req.GetTimeoutSeconds()),
+	}
+	if err := registry.ValidateName(deployRequest.ModelName); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	job := g.server.startDeploy(deployRequest, googleCredsB64Encoded, g.ingressHost, g.server.requestUser(peerRequest(ctx)))
+	return newAPIJob(job), nil
+}
+
+// SetStrategy sets routing strategy
+func (g *GRPCServer) SetStrategy(ctx context.Context, req *api.SetStrategyRequest) (*api.SetStrategyResponse, error) {
+	modelName, err := g.server.getRequestModelName(req.GetModelName())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// the values of api.Strategy are greater than constants.Strategy by 1 so that None is the default
+	setStrategyRequest := SetStrategyRequest{
+		ModelName: modelName,
+		Strategy:  constants.Strategy(req.GetStrategy()) - 1,
+		Target:    newTargetRule(req.GetTarget()),
+	}
+	weight := int(req.GetWeight())
+	if setStrategyRequest.Strategy == constants.Canary {
+		setStrategyRequest.Weight = &weight
+	}
+	err = traffic.Validate(traffic.Strategy{Strategy: setStrategyRequest.Strategy, Weight: weight, Target: setStrategyRequest.Target})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := g.server.checkCanaryNotRunning(modelName); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	strategyStr, err := g.server.changeStrategy(ctx, modelName, setStrategyRequest)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &api.SetStrategyResponse{Strategy: strategyStr}, nil
+}
+
+// Predict handles prediction
+func (g *GRPCServer) Predict(ctx context.Context, req *api.PredictRequest) (*api.Prediction, error) {
+	return g.predict(ctx, req)
+}
+
+// PredictStream handles the predictions of the stream one by one
+// The error of an image is sent as its prediction so that the following images are still predicted.
+func (g *GRPCServer) PredictStream(stream api.MnistServing_PredictStreamServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		prediction, err := g.predict(stream.Context(), req)
+		if err != nil {
+			prediction = &api.Prediction{Error: status.Convert(err).Message()}
+		}
+		if err := stream.Send(prediction); err != nil {
+			return err
+		}
+	}
+}
+
+// GetStatus returns the status of model
+func (g *GRPCServer) GetStatus(ctx context.Context, req *api.GetStatusRequest) (*api.ModelStatus, error) {
+	modelName, err := g.server.getRequestModelName(req.GetModelName())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+	modelStatus, err := g.server.getModelStatus(ctx, modelName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return newAPIModelStatus(modelStatus), nil
+}
+
+// predict returns the prediction of the image in req by the model selected by the strategy
+func (g *GRPCServer) predict(ctx context.Context, req *api.PredictRequest) (*api.Prediction, error) {
+	pixels, err := requestPixels(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	modelName, err := g.server.getRequestModelName(req.GetModelName())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	prediction, err := g.server.predict(ctx, g.ingressHost, modelName, pixels, metadataRequest(ctx))
+	if err != nil {
+		return nil, predictionError(err)
+	}
+	return &api.Prediction{
+		Probabilities: prediction.Probabilities,
+		Argmax:        int32(prediction.Argmax),
+		Confidence:    prediction.Confidence,
+		Slot:          prediction.Slot,
+		ModelBasePath: prediction.ModelBasePath,
+		Revision:      int32(prediction.Revision),
+		LatencyMs:     prediction.LatencyMs,
+	}, nil
+}
+
+// requestPixels returns the 784 pixels of the image in req, which is preprocessed like readPixels
+func requestPixels(req *api.PredictRequest) ([]float32, error) {
+	options := preprocess.Options{Invert: req.GetInvert()}
+	if req.GetNormalize() != "" {
+		normalization, err := preprocess.ParseNormalization(req.GetNormalize())
+		if err != nil {
+			return nil, err
+		}
+		options.Normalization = normalization
+	}
+
+	switch input := req.GetInput().(type) {
+	case *api.PredictRequest_Pixels:
+		pixels := input.Pixels.GetValues()
+		if err := validatePixels(pixels); err != nil {
+			return nil, err
+		}
+		return preprocess.FloatPixels(pixels, options)
+	case *api.PredictRequest_Image:
+		return decodeImage(input.Image, options)
+	case *api.PredictRequest_Raw:
+		return preprocess.RawPixels(input.Raw, options)
+	default:
+		return nil, fmt.Errorf("The image is missing.")
+	}
+}
+
+// metadataRequest returns the request whose header and remote address are the metadata and the address of the gRPC client,
+// so that the model is selected by the routing key, header and cookie in the same way as HTTP predictions
+func metadataRequest(ctx context.Context) *http.Request {
+	r := (&http.Request{Header: http.Header{}}).WithContext(ctx)
+	md, _ := metadata.FromIncomingContext(ctx)
+	for name, values := range md {
+		for _, value := range values {
+			r.Header.Add(name, value)
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+	}
+	return r
+}
+
+// peerRequest returns the request whose remote address is the address of the gRPC client without the metadata
+// The authenticating proxy is in front of the HTTP API only, so the user headers of gRPC clients are not trusted.
+func peerRequest(ctx context.Context) *http.Request {
+	r := (&http.Request{Header: http.Header{}}).WithContext(ctx)
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+	}
+	return r
+}
+
+// predictionError returns the gRPC status of the failed prediction
+// The status of TF Serving is kept when the prediction is sent by gRPC.
+func predictionError(err error) error {
+	if serverErr, ok := err.(*modelServerError); ok {
+		return status.Error(httpStatusCode(serverErr.statusCode), serverErr.body)
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// httpStatusCode returns the gRPC code of the HTTP status code of the model server
+func httpStatusCode(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+// newTargetRule returns registry.TargetRule of the target rule of the gRPC API
+func newTargetRule(target *api.TargetRule) *registry.TargetRule {
+	if target == nil {
+		return nil
+	}
+	return &registry.TargetRule{
+		Header: target.GetHeader(),
+		Values: target.GetValues(),
+		Cookie: target.GetCookie(),
+	}
+}
+
+// newAPITargetRule returns the target rule of the gRPC API
+func newAPITargetRule(target *registry.TargetRule) *api.TargetRule {
+	if target == nil {
+		return nil
+	}
+	return &api.TargetRule{
+		Header: target.Header,
+		Values: target.Values,
+		Cookie: target.Cookie,
+	}
+}
+
+// newAPIJob returns the job of the gRPC API
+func newAPIJob(job jobs.Job) *api.Job {
+	apiJob := &api.Job{
+		Id:        job.ID,
+		Kind:      job.Kind,
+		Key:       job.Key,
+		State:     string(job.State),
+		Error:     job.Error,
+		CreatedAt: timestamppb.New(job.CreatedAt),
+	}
+	for _, step := range job.Steps {
+		apiJob.Steps = append(apiJob.Steps, &api.JobStep{
+			Name:    step.Name,
+			State:   string(step.State),
+			Message: step.Message,
+			Error:   step.Error,
+		})
+	}
+	return apiJob
+}
+
+// newAPIModelStatus returns the model status of the gRPC API
+func newAPIModelStatus(modelStatus *ModelStatus) *api.ModelStatus {
+	strategyStatus := &api.StrategyStatus{
+		Strategy:  api.Strategy(modelStatus.Strategy.Strategy + 1),
+		Name:      modelStatus.Strategy.Name,
+		Target:    newAPITargetRule(modelStatus.Strategy.Target),
+		ChangedAt: newTimestamp(modelStatus.Strategy.ChangedAt),
+	}
+	if modelStatus.Strategy.Weight != nil {
+		strategyStatus.Weight = int32(*modelStatus.Strategy.Weight)
+	}
+	return &api.ModelStatus{
+		ModelName:      modelStatus.ModelName,
+		TrafficBackend: modelStatus.TrafficBackend,
+		Prod:           newAPISlotStatus(modelStatus.Prod),
+		Canary:         newAPISlotStatus(modelStatus.Canary),
+		Strategy:       strategyStatus,
+	}
+}
+
+// newAPISlotStatus returns the slot status of the gRPC API
+func newAPISlotStatus(slotStatus SlotStatus) *api.SlotStatus {
+	apiSlotStatus := &api.SlotStatus{
+		Slot:              slotStatus.Slot,
+		Deployed:          slotStatus.Deployed,
+		Ready:             slotStatus.Ready,
+		Replicas:          slotStatus.Replicas,
+		ReadyReplicas:     slotStatus.ReadyReplicas,
+		AvailableReplicas: slotStatus.AvailableReplicas,
+		ModelBasePath:     slotStatus.ModelBasePath,
+		ServingModelName:  slotStatus.ServingModelName,
+		Image:             slotStatus.Image,
+		ChangedAt:         newTimestamp(slotStatus.ChangedAt),
+	}
+	// the revisions are sorted from the newest one
+	if len(slotStatus.Revisions) > 0 {
+		apiSlotStatus.Revision = int32(slotStatus.Revisions[0].Revision)
+	}
+	if slotStatus.Rollout != nil {
+		apiSlotStatus.RolloutState = slotStatus.Rollout.State
+	}
+	return apiSlotStatus
+}
+
+// newTimestamp returns the timestamp of t, which is nil when t is not set
+func newTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,276 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/josh9191/mini-mnist-serving/api"
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/jobs"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestGRPCClient serves the gRPC API of s in memory and returns its client
+func newTestGRPCClient(t *testing.T, s *Server) api.MnistServingClient {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	api.RegisterMnistServingServer(server, NewGRPCServer(s, writeTestCredsFile(t), testIngressHost))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufconn", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithContextDialer(func(ctx context.Context, host string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return api.NewMnistServingClient(conn)
+}
+
+// enableTestDirectRouting routes predictions of mnist-cnn to the REST servers of both slots
+func enableTestDirectRouting(t *testing.T, s *Server, prodStatusCode int) {
+	prodServer := newSlotServer(t, prodStatusCode, `{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.9, 0.1, 0.0, 0.0, 0.0]]}`)
+	t.Cleanup(prodServer.Close)
+	canaryServer := newSlotServer(t, http.StatusOK, `{"predictions": [[0.0, 0.0, 0.0, 0.8, 0.0, 0.2, 0.0, 0.0, 0.0, 0.0]]}`)
+	t.Cleanup(canaryServer.Close)
+	prodUrl, _ := url.Parse(prodServer.URL)
+	canaryUrl, _ := url.Parse(canaryServer.URL)
+	slotHost, err := ParseSlotHosts("mnist-cnn/prod=" + prodUrl.Host + ",mnist-cnn/canary=" + canaryUrl.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.EnableDirectRouting(slotHost)
+}
+
+func pixelsRequest(pixels []float32) *api.PredictRequest {
+	return &api.PredictRequest{ModelName: "mnist-cnn", Input: &api.PredictRequest_Pixels{Pixels: &api.Pixels{Values: pixels}}}
+}
+
+func TestGRPCServerDeploy(t *testing.T) {
+	s, _ := newTestServer()
+	client := newTestGRPCClient(t, s)
+
+	// the user of metadata is ignored without the authenticating proxy
+	ctx := metadata.AppendToOutgoingContext(context.TODO(), "x-forwarded-user", "mallory")
+	job, err := client.Deploy(ctx, &api.DeployRequest{ModelName: "mnist-cnn", ModelBaseDir: "gs://my-bucket/v1", NumReplicas: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.GetKind() != "deploy" || job.GetKey() != constants.ProdNamespace+"/mnist-cnn" || len(job.GetSteps()) != 5 || job.GetCreatedAt() == nil {
+		t.Errorf("Wrong job: %+v", job)
+	}
+	finished, err := s.jobs.Wait(context.TODO(), job.GetId())
+	if err != nil || finished.State != jobs.Succeeded {
+		t.Fatalf("Error - Job: %+v, %v", finished, err)
+	}
+	revisions := getRevisions(getProdDeployment(t, s, "mnist-cnn"))
+	if len(revisions) != 1 || revisions[0].ModelBaseDir != "gs://my-bucket/v1" || revisions[0].DeployedBy != "bufconn" {
+		t.Errorf("Wrong revisions: %+v", revisions)
+	}
+
+	_, err = client.Deploy(context.TODO(), &api.DeployRequest{ModelName: "Invalid_Name", ModelBaseDir: "gs://my-bucket/v1", NumReplicas: 1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Invalid name is deployed: %v", err)
+	}
+}
+
+func TestGRPCServerDeployTrustedProxyUser(t *testing.T) {
+	s, _ := newTestServer()
+	s.EnableTrustedProxyUser()
+	client := newTestGRPCClient(t, s)
+
+	// the authenticating proxy is not in front of the gRPC API
+	ctx := metadata.AppendToOutgoingContext(context.TODO(), "x-forwarded-user", "mallory", "x-remote-user", "mallory")
+	job, err := client.Deploy(ctx, &api.DeployRequest{ModelName: "mnist-cnn", ModelBaseDir: "gs://my-bucket/v1", NumReplicas: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finished, err := s.jobs.Wait(context.TODO(), job.GetId())
+	if err != nil || finished.State != jobs.Succeeded {
+		t.Fatalf("Error - Job: %+v, %v", finished, err)
+	}
+	revisions := getRevisions(getProdDeployment(t, s, "mnist-cnn"))
+	if len(revisions) != 1 || revisions[0].DeployedBy != "bufconn" {
+		t.Errorf("Wrong revisions: %+v", revisions)
+	}
+}
+
+func TestGRPCServerSetStrategy(t *testing.T) {
+	s, _ := newTestServer()
+	deployBothSlots(t, s)
+	client := newTestGRPCClient(t, s)
+
+	resp, err := client.SetStrategy(context.TODO(), &api.SetStrategyRequest{
+		ModelName: "mnist-cnn",
+		Strategy:  api.Strategy_STRATEGY_TARGETED,
+		Target:    &api.TargetRule{Header: "X-Beta", Values: []string{"yes"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetStrategy() == "" {
+		t.Errorf("Empty strategy: %+v", resp)
+	}
+	model, err := s.registry.Get(context.TODO(), "mnist-cnn")
+	if err != nil || model.Strategy != constants.Targeted || model.Target == nil || model.Target.Header != "X-Beta" {
+		t.Errorf("Wrong strategy: %+v, %v", model, err)
+	}
+
+	for _, req := range []*api.SetStrategyRequest{
+		{ModelName: "mnist-cnn"},
+		{ModelName: "mnist-cnn", Strategy: api.Strategy_STRATEGY_CANARY, Weight: 150},
+		{ModelName: "mnist-cnn", Strategy: api.Strategy_STRATEGY_TARGETED},
+		{ModelName: "unknown-model", Strategy: api.Strategy_STRATEGY_NEW_MODEL_ONLY},
+	} {
+		if _, err := client.SetStrategy(context.TODO(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%+v: wrong error: %v", req, err)
+		}
+	}
+}
+
+func TestGRPCServerPredict(t *testing.T) {
+	s, _ := newTestServer()
+	enableTestDirectRouting(t, s, http.StatusOK)
+	// with Canary strategy and 30% weight
+	deployBothSlots(t, s)
+	client := newTestGRPCClient(t, s)
+
+	canaryPredictions := 0
+	for i := 0; i < 100; i++ {
+		routingKey := fmt.Sprintf("user-%d", i)
+		ctx := metadata.AppendToOutgoingContext(context.TODO(), constants.RoutingKeyHeader, routingKey)
+		prediction, err := client.Predict(ctx, pixelsRequest(make([]float32, 784)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		isNewModel := routesToNewModel("mnist-cnn", routingStrategy{strategy: constants.Canary, weight: 30}, requestWithRoutingKey(routingKey))
+		if (prediction.GetSlot() == CanarySlot) != isNewModel || (prediction.GetArgmax() == 3) != isNewModel {
+			t.Errorf("%v: Wrong slot: %+v", routingKey, prediction)
+		}
+		if isNewModel {
+			canaryPredictions++
+			if prediction.GetModelBasePath() != "gs://my-bucket/v2" || prediction.GetConfidence() != 0.8 {
+				t.Errorf("%v: Wrong prediction: %+v", routingKey, prediction)
+			}
+		}
+	}
+	if canaryPredictions == 0 || canaryPredictions == 100 {
+		t.Errorf("Requests are not split: %d", canaryPredictions)
+	}
+
+	// the image is preprocessed like HTTP predictions
+	setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": constants.CurrentModelOnly})
+	prediction, err := client.Predict(context.TODO(), &api.PredictRequest{ModelName: "mnist-cnn", Input: &api.PredictRequest_Image{Image: digitPNG(t)}, Invert: true, Normalize: "mnist"})
+	if err != nil || prediction.GetArgmax() != 5 || prediction.GetSlot() != ProdSlot {
+		t.Errorf("Wrong prediction: %+v, %v", prediction, err)
+	}
+
+	for _, req := range []*api.PredictRequest{
+		{ModelName: "mnist-cnn"},
+		pixelsRequest(make([]float32, 10)),
+		{ModelName: "mnist-cnn", Input: &api.PredictRequest_Raw{Raw: make([]byte, 10)}},
+		{ModelName: "mnist-cnn", Input: &api.PredictRequest_Image{Image: digitPNG(t)}, Normalize: "mnist2"},
+	} {
+		if _, err := client.Predict(context.TODO(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%+v: wrong error: %v", req, err)
+		}
+	}
+}
+
+func TestGRPCServerPredictModelServerError(t *testing.T) {
+	s, _ := newTestServer()
+	enableTestDirectRouting(t, s, http.StatusNotFound)
+	deployBothSlots(t, s)
+	setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": constants.CurrentModelOnly})
+	client := newTestGRPCClient(t, s)
+
+	if _, err := client.Predict(context.TODO(), pixelsRequest(make([]float32, 784))); status.Code(err) != codes.NotFound {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
+
+func TestGRPCServerPredictStream(t *testing.T) {
+	s, _ := newTestServer()
+	enableTestDirectRouting(t, s, http.StatusOK)
+	deployBothSlots(t, s)
+	setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": constants.NewModelOnly})
+	client := newTestGRPCClient(t, s)
+
+	stream, err := client.PredictStream(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := []*api.PredictRequest{
+		pixelsRequest(make([]float32, 784)),
+		pixelsRequest(make([]float32, 10)),
+		{ModelName: "mnist-cnn", Input: &api.PredictRequest_Raw{Raw: make([]byte, 784)}},
+	}
+	for _, req := range requests {
+		if err := stream.Send(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stream.CloseSend()
+
+	var predictions []*api.Prediction
+	for {
+		prediction, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		predictions = append(predictions, prediction)
+	}
+	if len(predictions) != len(requests) {
+		t.Fatalf("Wrong number of predictions: %d", len(predictions))
+	}
+	for i, prediction := range predictions {
+		if i == 1 {
+			if prediction.GetError() == "" {
+				t.Errorf("Invalid image is predicted: %+v", prediction)
+			}
+			continue
+		}
+		if prediction.GetError() != "" || prediction.GetArgmax() != 3 || prediction.GetSlot() != CanarySlot {
+			t.Errorf("%d: wrong prediction: %+v", i, prediction)
+		}
+	}
+}
+
+func TestGRPCServerGetStatus(t *testing.T) {
+	s, _ := newTestServer()
+	deployBothSlots(t, s)
+	client := newTestGRPCClient(t, s)
+
+	modelStatus, err := client.GetStatus(context.TODO(), &api.GetStatusRequest{ModelName: "mnist-cnn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prod := modelStatus.GetProd(); !prod.GetDeployed() || prod.GetModelBasePath() != "gs://my-bucket/v1" || prod.GetRevision() != 1 || prod.GetChangedAt() == nil {
+		t.Errorf("Wrong prod slot: %+v", prod)
+	}
+	if canary := modelStatus.GetCanary(); !canary.GetDeployed() || canary.GetReplicas() != 2 || canary.GetModelBasePath() != "gs://my-bucket/v2" {
+		t.Errorf("Wrong canary slot: %+v", canary)
+	}
+	if strategy := modelStatus.GetStrategy(); strategy.GetStrategy() != api.Strategy_STRATEGY_CANARY || strategy.GetWeight() != 30 || strategy.GetChangedAt() == nil {
+		t.Errorf("Wrong strategy: %+v", strategy)
+	}
+
+	if _, err := client.GetStatus(context.TODO(), &api.GetStatusRequest{ModelName: "unknown-model"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
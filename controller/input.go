@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/josh9191/mini-mnist-serving/preprocess"
+)
+
+// maxImageBytes limits the size of uploaded images
+const maxImageBytes = 10 << 20
+
+// ImageInput stores an encoded image in the JSON prediction request
+type ImageInput struct {
+	// Image is base64-encoded PNG or JPEG, which can be a data URL
+	Image string `json:"image"`
+	// Invert turns a dark digit on light background into a light digit on dark background like MNIST
+	Invert bool `json:"invert,omitempty"`
+	// Normalize is "resize" (default) or "mnist"
+	Normalize string `json:"normalize,omitempty"`
+}
+
+// readPixels returns the 784 pixels of the image in the prediction request
+// The image is read by Content-Type:
+//   - multipart/form-data: PNG or JPEG file of "image" field
+//   - image/png, image/jpeg: PNG or JPEG body
+//   - application/octet-stream: 784 bytes of 28x28 grayscale image
+//   - otherwise: JSON array of 784 pixels from 0 to 1, or ImageInput
+//
+// The "invert" and "normalize" query parameters or form fields select the preprocessing of every input.
+func readPixels(r *http.Request) ([]float32, error) {
+	options, err := imageOptions(r.URL.Query(), preprocess.Options{})
+	if err != nil {
+		return nil, err
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	body := io.LimitReader(r.Body, maxImageBytes)
+
+	switch {
+	case mediaType == "multipart/form-data":
+		if err := r.ParseMultipartForm(maxImageBytes); err != nil {
+			return nil, err
+		}
+		file, _, err := r.FormFile("image")
+		if err != nil {
+			return nil, fmt.Errorf("The image file is missing: %v", err)
+		}
+		defer file.Close()
+		data, err := ioutil.ReadAll(io.LimitReader(file, maxImageBytes))
+		if err != nil {
+			return nil, err
+		}
+		options, err = imageOptions(r.MultipartForm.Value, options)
+		if err != nil {
+			return nil, err
+		}
+		return decodeImage(data, options)
+	case strings.HasPrefix(mediaType, "image/"):
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		return decodeImage(data, options)
+	case mediaType == "application/octet-stream":
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		return preprocess.RawPixels(data, options)
+	default:
+		var input json.RawMessage
+		if err := json.NewDecoder(body).Decode(&input); err != nil {
+			return nil, err
+		}
+		return decodeJSONImage(input, options)
+	}
+}
+
+// decodeJSONImage returns the pixels of the JSON array of 784 pixels or ImageInput
+func decodeJSONImage(input json.RawMessage, options preprocess.Options) ([]float32, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(input), []byte("{")) {
+		var imageInput ImageInput
+		if err := json.Unmarshal(input, &imageInput); err != nil {
+			return nil, err
+		}
+		img, err := preprocess.DecodeBase64Image(imageInput.Image)
+		if err != nil {
+			return nil, err
+		}
+		options.Invert = options.Invert || imageInput.Invert
+		if imageInput.Normalize != "" {
+			options.Normalization, err = preprocess.ParseNormalization(imageInput.Normalize)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return preprocess.Pixels(img, options), nil
+	}
+
+	var pixels []float32
+	if err := json.Unmarshal(input, &pixels); err != nil {
+		return nil, err
+	}
+	if err := validatePixels(pixels); err != nil {
+		return nil, err
+	}
+	return preprocess.FloatPixels(pixels, options)
+}
+
+// imageOptions returns options updated by "invert" and "normalize" of values
+func imageOptions(values url.Values, options preprocess.Options) (preprocess.Options, error) {
+	options.Invert = options.Invert || values.Get("invert") == "true"
+	if normalize := values.Get("normalize"); normalize != "" {
+		normalization, err := preprocess.ParseNormalization(normalize)
+		if err != nil {
+			return options, err
+		}
+		options.Normalization = normalization
+	}
+	return options, nil
+}
+
+// decodeImage returns the pixels of PNG or JPEG image
+func decodeImage(data []byte, options preprocess.Options) ([]float32, error) {
+	img, err := preprocess.DecodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+	return preprocess.Pixels(img, options), nil
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// digitPNG returns the PNG image of 56x56 white background whose top-left quarter is black
+func digitPNG(t *testing.T) []byte {
+	img := image.NewGray(image.Rect(0, 0, 56, 56))
+	for y := 0; y < 56; y++ {
+		for x := 0; x < 56; x++ {
+			if x >= 28 || y >= 28 {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// checkDigitPixels checks the pixels of digitPNG inverted like MNIST
+func checkDigitPixels(t *testing.T, name string, pixels []float32) {
+	if len(pixels) != imageSize*imageSize {
+		t.Errorf("%v: wrong number of pixels: %d", name, len(pixels))
+		return
+	}
+	for i, pixel := range pixels {
+		want := float32(0)
+		if i%imageSize < imageSize/2 && i/imageSize < imageSize/2 {
+			want = 1
+		}
+		if pixel != want {
+			t.Errorf("%v: pixel %d is %v, want %v", name, i, pixel, want)
+			return
+		}
+	}
+}
+
+func multipartBody(t *testing.T, data []byte, fields map[string]string) (io.Reader, string) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("image", "digit.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+	writer.Close()
+	return &body, writer.FormDataContentType()
+}
+
+func TestReadPixels(t *testing.T) {
+	data := digitPNG(t)
+	encoded := base64.StdEncoding.EncodeToString(data)
+	jsonInput, _ := json.Marshal(ImageInput{Image: "data:image/png;base64," + encoded, Invert: true})
+	multipartInput, multipartType := multipartBody(t, data, map[string]string{"invert": "true"})
+	raw := make([]byte, imageSize*imageSize)
+	for i := range raw {
+		if i%imageSize >= imageSize/2 || i/imageSize >= imageSize/2 {
+			raw[i] = 255
+		}
+	}
+
+	for _, test := range []struct {
+		name        string
+		target      string
+		contentType string
+		body        io.Reader
+	}{
+		{"multipart", "/model:predict", multipartType, multipartInput},
+		{"PNG body", "/model:predict?invert=true", "image/png", bytes.NewReader(data)},
+		{"raw bytes", "/model:predict?invert=true", "application/octet-stream", bytes.NewReader(raw)},
+		{"base64 JSON", "/model:predict", "application/json", bytes.NewReader(jsonInput)},
+		{"base64 JSON with invert query", "/model:predict?invert=true", "", strings.NewReader(`{"image": "` + encoded + `"}`)},
+	} {
+		r := httptest.NewRequest("POST", test.target, test.body)
+		if test.contentType != "" {
+			r.Header.Set("Content-Type", test.contentType)
+		}
+		pixels, err := readPixels(r)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		checkDigitPixels(t, test.name, pixels)
+	}
+
+	// pixel arrays are inverted as well
+	pixels, err := readPixels(httptest.NewRequest("POST", "/model:predict?invert=true", bytes.NewReader(mustMarshal(t, batchImage(1)))))
+	if err != nil || pixels[0] != 0 || pixels[1] != 1 {
+		t.Errorf("Wrong pixel array: %v, %v", pixels[:2], err)
+	}
+}
+
+func TestReadPixelsNormalize(t *testing.T) {
+	data := digitPNG(t)
+	encoded := base64.StdEncoding.EncodeToString(data)
+	multipartInput, multipartType := multipartBody(t, data, map[string]string{"invert": "true", "normalize": "mnist"})
+	// the light 10x10 square of pixel array
+	pixelArray := make([]float32, imageSize*imageSize)
+	for y := 2; y < 12; y++ {
+		for x := 2; x < 12; x++ {
+			pixelArray[y*imageSize+x] = 1
+		}
+	}
+
+	for _, test := range []struct {
+		name        string
+		target      string
+		contentType string
+		body        io.Reader
+	}{
+		{"multipart", "/model:predict", multipartType, multipartInput},
+		{"PNG body", "/model:predict?invert=true&normalize=mnist", "image/png", bytes.NewReader(data)},
+		{"base64 JSON", "/model:predict", "application/json", strings.NewReader(`{"image": "` + encoded + `", "invert": true, "normalize": "mnist"}`)},
+		{"pixel array", "/model:predict?normalize=mnist", "application/json", bytes.NewReader(mustMarshal(t, pixelArray))},
+	} {
+		r := httptest.NewRequest("POST", test.target, test.body)
+		r.Header.Set("Content-Type", test.contentType)
+		pixels, err := readPixels(r)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		// the black square is scaled to 20x20 at the center
+		for i, pixel := range pixels {
+			x, y := i%imageSize, i/imageSize
+			want := float32(0)
+			if x >= 4 && x < 24 && y >= 4 && y < 24 {
+				want = 1
+			}
+			if pixel != want {
+				t.Errorf("%v: pixel (%d, %d) is %v, want %v", test.name, x, y, pixel, want)
+				break
+			}
+		}
+	}
+}
+
+func TestReadPixelsInvalidInput(t *testing.T) {
+	multipartInput, multipartType := multipartBody(t, []byte("not an image"), nil)
+	for _, test := range []struct {
+		name        string
+		contentType string
+		body        io.Reader
+	}{
+		{"multipart", multipartType, multipartInput},
+		{"multipart without image", "multipart/form-data; boundary=x", strings.NewReader("--x--\r\n")},
+		{"GIF body", "image/gif", strings.NewReader("GIF89a")},
+		{"short raw bytes", "application/octet-stream", bytes.NewReader(make([]byte, 100))},
+		{"invalid base64", "application/json", strings.NewReader(`{"image": "###"}`)},
+		{"short pixel array", "application/json", strings.NewReader(`[0.1, 0.2]`)},
+	} {
+		r := httptest.NewRequest("POST", "/model:predict", test.body)
+		r.Header.Set("Content-Type", test.contentType)
+		if _, err := readPixels(r); err == nil {
+			t.Errorf("%v: invalid image is read", test.name)
+		}
+	}
+
+	if _, err := readPixels(httptest.NewRequest("POST", "/model:predict?normalize=mnist2", bytes.NewReader(mustMarshal(t, batchImage(1))))); err == nil {
+		t.Errorf("Unknown normalization is accepted")
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
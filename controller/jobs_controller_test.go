@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/josh9191/mini-mnist-serving/jobs"
+)
+
+func callJobController(handler http.HandlerFunc, method string, target string, id string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	r = mux.SetURLVars(r, map[string]string{"id": id})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestGetJobController(t *testing.T) {
+	s, _ := newTestServer()
+	deployed := deployModel(t, s, writeTestCredsFile(t), map[string]interface{}{
+		"model-base-dir": "gs://my-bucket/mnist",
+		"model-name":     "mnist-cnn",
+		"is-new-model":   false,
+		"num-replicas":   1,
+	})
+
+	w := callJobController(s.GetJobController, "GET", "/jobs/"+deployed.ID, deployed.ID)
+	var job jobs.Job
+	json.NewDecoder(w.Body).Decode(&job)
+	if w.Code != http.StatusOK || job.ID != deployed.ID || job.Kind != "deploy" || job.State != jobs.Succeeded {
+		t.Errorf("Wrong job: %d, %+v", w.Code, job)
+	}
+	if job.Key != "mnist-prod/mnist-cnn" || len(job.Steps) != 5 {
+		t.Errorf("Wrong job: %+v", job)
+	}
+
+	w = httptest.NewRecorder()
+	s.ListJobsController(w, httptest.NewRequest("GET", "/jobs", nil))
+	var jobList []jobs.Job
+	json.NewDecoder(w.Body).Decode(&jobList)
+	if len(jobList) != 1 || jobList[0].ID != deployed.ID {
+		t.Errorf("Wrong jobs: %+v", jobList)
+	}
+
+	w = callJobController(s.GetJobController, "GET", "/jobs/0123", "0123")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Unknown job: Status Code: %d", w.Code)
+	}
+}
+
+func TestCancelJobController(t *testing.T) {
+	s, _ := newTestServer()
+
+	block := make(chan struct{})
+	started := s.jobs.Start("deploy", "mnist-prod/mnist-cnn", []string{"block"}, func(ctx context.Context, progress *jobs.Progress) (interface{}, error) {
+		return nil, progress.Run("block", func() (string, error) {
+			select {
+			case <-block:
+				return "", nil
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		})
+	})
+	defer close(block)
+
+	w := callJobController(s.CancelJobController, "POST", "/jobs/"+started.ID+":cancel", started.ID)
+	var job jobs.Job
+	json.NewDecoder(w.Body).Decode(&job)
+	if w.Code != http.StatusOK || job.State != jobs.Canceled {
+		t.Errorf("Wrong canceled job: %d, %+v", w.Code, job)
+	}
+
+	w = callJobController(s.CancelJobController, "POST", "/jobs/"+started.ID+":cancel", started.ID)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Canceling finished job: Status Code: %d", w.Code)
+	}
+
+	w = callJobController(s.CancelJobController, "POST", "/jobs/0123:cancel", "0123")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Unknown job: Status Code: %d", w.Code)
+	}
+}
@@ -12,33 +12,57 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/josh9191/mini-mnist-serving/clients"
 	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/jobs"
+	"github.com/josh9191/mini-mnist-serving/metrics"
+	"github.com/josh9191/mini-mnist-serving/registry"
+	"github.com/josh9191/mini-mnist-serving/traffic"
 
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
-	exv1beta1 "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// registryTimeout bounds reading the registry for the model name of request
+const registryTimeout = 5 * time.Second
+
 // DeployRequest stores deploy request JSON data
 type DeployRequest struct {
 	ModelBaseDir string `json:"model-base-dir"`
 	ModelName    string `json:"model-name"`
 	IsNewModel   bool   `json:"is-new-model"`
 	NumReplicas  int32  `json:"num-replicas"`
+	// Wait makes the job wait until the rollout is complete or failed
+	Wait           bool `json:"wait,omitempty"`
+	TimeoutSeconds int  `json:"timeout-seconds,omitempty"`
 }
 
 // SetStrategyRequest stores strategy set request JSON data
 type SetStrategyRequest struct {
-	Strategy constants.Strategy `json:"strategy"`
-	Weight   *int               `json:"weight,omitempty"`
+	ModelName string             `json:"model-name"`
+	Strategy  constants.Strategy `json:"strategy"`
+	Weight    *int               `json:"weight,omitempty"`
+	// Target is required by Targeted strategy
+	Target *registry.TargetRule `json:"target,omitempty"`
+}
+
+// UndeployRequest stores undeploy request JSON data
+type UndeployRequest struct {
+	ModelName  string `json:"model-name"`
+	IsNewModel bool   `json:"is-new-model"`
+}
+
+// UndeployResponse stores the objects deleted by undeploy request
+type UndeployResponse struct {
+	ModelName  string   `json:"model-name"`
+	IsNewModel bool     `json:"is-new-model"`
+	Deleted    []string `json:"deleted"`
 }
 
 // PredictResponse stores prediction data
@@ -46,10 +70,14 @@ type PredictResponse struct {
 	Predictions [][]float32 `json:"predictions"`
 }
 
-// DeployControllerWrapper deploys model
-func DeployControllerWrapper(googleCredsFilePath string, ingressHost string) http.HandlerFunc {
+// DeployControllerWrapper starts a job deploying model and returns the job
+func (s *Server) DeployControllerWrapper(googleCredsFilePath string, ingressHost string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		googleCredsB64Encoded, err := readFileToBase64String(googleCredsFilePath)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
 
 		decoder := json.NewDecoder(r.Body)
 
@@ -61,49 +89,70 @@ func DeployControllerWrapper(googleCredsFilePath string, ingressHost string) htt
 			return
 		}
 
-		// [FIXME] model name should be fixed to "model"
-		if deployRequest.ModelName != "model" {
-			http.Error(w, "The model name should be set to \"model\".", 500)
+		err = registry.ValidateName(deployRequest.ModelName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		kubeClientSet := clients.GetKubernetesClientSet()
+		job := s.startDeploy(deployRequest, googleCredsB64Encoded, ingressHost, s.requestUser(r))
 
-		// First of all, we create namespaces for production / canary deployment
-		// named mnist-prod, mnist-canary respectively.
-		namespacesClient := kubeClientSet.CoreV1().Namespaces()
-		prodNamespace := &apiv1.Namespace{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: constants.ProdNamespace,
-			},
-		}
-		canaryNamespace := &apiv1.Namespace{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: constants.CanaryNamespace,
-			},
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/jobs/"+job.ID)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job)
+	}
+}
+
+// startDeploy starts a job deploying the model slot of deployRequest
+func (s *Server) startDeploy(deployRequest DeployRequest, googleCredsB64Encoded string, ingressHost string, deployedBy string) jobs.Job {
+	stepNames := []string{"namespaces", "secret", "deployment", "service", "traffic"}
+	if deployRequest.Wait {
+		stepNames = append(stepNames, "rollout")
+	}
+	// deploys of the same slot are run one by one
+	key := getNamespace(deployRequest.IsNewModel) + "/" + deployRequest.ModelName
+	return s.jobs.Start("deploy", key, stepNames, func(ctx context.Context, progress *jobs.Progress) (interface{}, error) {
+		rolloutStatus, err := s.deploy(ctx, progress, deployRequest, googleCredsB64Encoded, ingressHost, deployedBy)
+		if rolloutStatus == nil {
+			return nil, err
 		}
+		return rolloutStatus, err
+	})
+}
+
+// deploy creates or updates the objects of model slot
+// The rollout status is returned when the request waits for the rollout.
+func (s *Server) deploy(ctx context.Context, progress *jobs.Progress, deployRequest DeployRequest, googleCredsB64Encoded string, ingressHost string, deployedBy string) (*RolloutStatus, error) {
+	kubeClientSet := s.kubeClientSet
+	namespace := getNamespace(deployRequest.IsNewModel)
 
-		namespaceResult, err := namespacesClient.Create(context.TODO(), prodNamespace, metav1.CreateOptions{})
+	// First of all, we create namespaces for production / canary deployment
+	// named mnist-prod, mnist-canary respectively.
+	err := progress.Run("namespaces", func() (string, error) {
+		err := s.createNamespaces(ctx)
 		if err != nil {
-			if errors.IsAlreadyExists(err) {
-				log.Printf("The namespace %v already exists.", namespaceResult.GetObjectMeta().GetName())
-			} else {
-				http.Error(w, err.Error(), 500)
-				return
-			}
+			return "", err
+		}
+
+		// Register the model when it is deployed for the first time
+		err = s.registry.Create(ctx, &registry.Model{Name: deployRequest.ModelName})
+		if err == registry.ErrAlreadyExists {
+			log.Printf("The model %v is registered already.", deployRequest.ModelName)
+			return "", nil
 		}
-		namespaceResult, err = namespacesClient.Create(context.TODO(), canaryNamespace, metav1.CreateOptions{})
 		if err != nil {
-			if errors.IsAlreadyExists(err) {
-				log.Printf("The namespace %v already exists.", namespaceResult.GetObjectMeta().GetName())
-			} else {
-				http.Error(w, err.Error(), 500)
-				return
-			}
+			return "", err
 		}
+		return "Registered the model " + deployRequest.ModelName, nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		// Create secret if it doesn't exist
-		secretsClient := kubeClientSet.CoreV1().Secrets(getNamespace(deployRequest.IsNewModel))
+	// Create secret if it doesn't exist
+	err = progress.Run("secret", func() (string, error) {
+		secretsClient := kubeClientSet.CoreV1().Secrets(namespace)
 		secret := &apiv1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
 				Name: constants.ModelSecretName,
@@ -112,18 +161,20 @@ func DeployControllerWrapper(googleCredsFilePath string, ingressHost string) htt
 				"sa_json": googleCredsB64Encoded,
 			},
 		}
-		secretResult, err := secretsClient.Create(context.TODO(), secret, metav1.CreateOptions{})
-		if err != nil {
-			if errors.IsAlreadyExists(err) {
-				log.Printf("The secret %v already exists.", secretResult.GetObjectMeta().GetName())
-			} else {
-				http.Error(w, err.Error(), 500)
-				return
-			}
+		_, err := secretsClient.Create(ctx, secret, metav1.CreateOptions{})
+		if errors.IsAlreadyExists(err) {
+			log.Printf("The secret %v already exists.", secret.GetObjectMeta().GetName())
+			return "Already exists", nil
 		}
+		return "Created", err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		// Create or update deployment
-		deploymentsClient := kubeClientSet.AppsV1().Deployments(getNamespace(deployRequest.IsNewModel))
+	// Create or update deployment
+	err = progress.Run("deployment", func() (string, error) {
+		deploymentsClient := kubeClientSet.AppsV1().Deployments(namespace)
 		// container env
 		envVar := []apiv1.EnvVar{
 			{
@@ -141,20 +192,17 @@ func DeployControllerWrapper(googleCredsFilePath string, ingressHost string) htt
 		}
 		deployment := &appsv1.Deployment{
 			ObjectMeta: metav1.ObjectMeta{
-				Name: constants.DeploymentName,
+				Name:   registry.DeploymentName(deployRequest.ModelName),
+				Labels: registry.Labels(deployRequest.ModelName),
 			},
 			Spec: appsv1.DeploymentSpec{
 				Replicas: int32Ptr(deployRequest.NumReplicas),
 				Selector: &metav1.LabelSelector{
-					MatchLabels: map[string]string{
-						"app": constants.LabelAppSelector,
-					},
+					MatchLabels: registry.Labels(deployRequest.ModelName),
 				},
 				Template: apiv1.PodTemplateSpec{
 					ObjectMeta: metav1.ObjectMeta{
-						Labels: map[string]string{
-							"app": constants.LabelAppSelector,
-						},
+						Labels: registry.Labels(deployRequest.ModelName),
 					},
 					Spec: apiv1.PodSpec{
 						Containers: []apiv1.Container{
@@ -167,6 +215,11 @@ func DeployControllerWrapper(googleCredsFilePath string, ingressHost string) htt
 										Protocol:      apiv1.ProtocolTCP,
 										ContainerPort: 8501,
 									},
+									{
+										Name:          "grpc",
+										Protocol:      apiv1.ProtocolTCP,
+										ContainerPort: 8500,
+									},
 								},
 								VolumeMounts: []apiv1.VolumeMount{
 									{
@@ -208,137 +261,110 @@ func DeployControllerWrapper(googleCredsFilePath string, ingressHost string) htt
 			},
 		}
 
-		deploymentResult, err := deploymentsClient.Create(context.TODO(), deployment, metav1.CreateOptions{})
-		if err != nil {
-			if errors.IsAlreadyExists(err) {
-				log.Printf("The deployment %v already exists. Force rolling update...", deploymentResult.GetObjectMeta().GetName())
-
-				result, err := deploymentsClient.Get(context.TODO(), constants.DeploymentName, metav1.GetOptions{})
-				if err != nil {
-					http.Error(w, err.Error(), 500)
-					return
-				}
-
-				// update model base directory / name
-				result.Spec.Template.Spec.Containers[0].Env = envVar
-				result.Spec.Replicas = int32Ptr(deployRequest.NumReplicas)
-				// Force rolling update using date label
-				if result.Spec.Template.ObjectMeta.Annotations == nil {
-					result.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
-				}
-				result.Spec.Template.ObjectMeta.Annotations["date"] = strconv.FormatInt(time.Now().Unix(), 10)
-				_, err = deploymentsClient.Update(context.TODO(), result, metav1.UpdateOptions{})
-				if err != nil {
-					http.Error(w, err.Error(), 500)
-					return
-				}
+		revision := Revision{
+			ModelBaseDir: deployRequest.ModelBaseDir,
+			ModelName:    deployRequest.ModelName,
+			NumReplicas:  deployRequest.NumReplicas,
+			DeployedBy:   deployedBy,
+			ChangeCause:  "deploy",
+		}
+		recordRevision(deployment, revision)
 
-			} else {
-				http.Error(w, err.Error(), 500)
-				return
-			}
+		_, err := deploymentsClient.Create(ctx, deployment, metav1.CreateOptions{})
+		if !errors.IsAlreadyExists(err) {
+			return "Created", err
 		}
+		log.Printf("The deployment %v already exists. Force rolling update...", deployment.GetObjectMeta().GetName())
 
-		// Create Service object
-		servicesClient := kubeClientSet.CoreV1().Services(getNamespace(deployRequest.IsNewModel))
+		result, err := deploymentsClient.Get(ctx, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+
+		// update model base directory / name
+		result.Spec.Template.Spec.Containers[0].Env = envVar
+		result.Spec.Replicas = int32Ptr(deployRequest.NumReplicas)
+		forceRollingUpdate(result)
+		recordRevision(result, revision)
+		_, err = deploymentsClient.Update(ctx, result, metav1.UpdateOptions{})
+		return "Rolling update", err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Create Service object
+	err = progress.Run("service", func() (string, error) {
+		servicesClient := kubeClientSet.CoreV1().Services(namespace)
 		service := &apiv1.Service{
 			ObjectMeta: metav1.ObjectMeta{
-				Name: constants.ServiceName,
+				Name:   registry.ServiceName(deployRequest.ModelName),
+				Labels: registry.Labels(deployRequest.ModelName),
 			},
 			Spec: apiv1.ServiceSpec{
-				Type: apiv1.ServiceTypeClusterIP,
-				Selector: map[string]string{
-					"app": constants.LabelAppSelector,
-				},
+				Type:     apiv1.ServiceTypeClusterIP,
+				Selector: registry.Labels(deployRequest.ModelName),
+				// the REST API is routed by the traffic backend, and the gRPC API is called by the server directly
 				Ports: []apiv1.ServicePort{
 					{
+						Name:       "http",
 						Protocol:   apiv1.ProtocolTCP,
 						Port:       8501,
 						TargetPort: intstr.FromInt(8501),
 					},
-				},
-			},
-		}
-		serviceResult, err := servicesClient.Create(context.TODO(), service, metav1.CreateOptions{})
-		if err != nil {
-			if errors.IsAlreadyExists(err) {
-				log.Printf("The service %v already exists.", serviceResult.GetObjectMeta().GetName())
-			} else {
-				http.Error(w, err.Error(), 500)
-				return
-			}
-		}
-
-		// Ingress - 80 port
-		ingressClient := kubeClientSet.ExtensionsV1beta1().Ingresses(getNamespace(deployRequest.IsNewModel))
-		var nginxAnnotations = make(map[string]string)
-		nginxAnnotations["kubernetes.io/ingress.class"] = "nginx"
-		if deployRequest.IsNewModel {
-			nginxAnnotations["nginx.ingress.kubernetes.io/canary"] = "true"
-		} else {
-			// If canary option is specified to true, other annotations are ignored
-			// We set rewrite option only to prod model
-			nginxAnnotations["nginx.ingress.kubernetes.io/rewrite-target"] = fmt.Sprintf("/v1/models/%s:predict", deployRequest.ModelName)
-		}
-
-		ingress := &exv1beta1.Ingress{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:        constants.IngressName,
-				Annotations: nginxAnnotations,
-			},
-			Spec: exv1beta1.IngressSpec{
-				Rules: []exv1beta1.IngressRule{
 					{
-						Host: ingressHost,
-						IngressRuleValue: exv1beta1.IngressRuleValue{
-							HTTP: &exv1beta1.HTTPIngressRuleValue{
-								Paths: []exv1beta1.HTTPIngressPath{
-									{
-										Backend: exv1beta1.IngressBackend{
-											ServiceName: constants.ServiceName,
-											ServicePort: intstr.FromInt(8501),
-										},
-										Path: "/predict",
-									},
-								},
-							},
-						},
+						Name:       "grpc",
+						Protocol:   apiv1.ProtocolTCP,
+						Port:       8500,
+						TargetPort: intstr.FromInt(8500),
 					},
 				},
 			},
 		}
-
-		ingressResult, err := ingressClient.Create(context.TODO(), ingress, metav1.CreateOptions{})
-		if err != nil {
-			if errors.IsAlreadyExists(err) {
-				log.Printf("The ingress %v already exists.", ingressResult.GetObjectMeta().GetName())
-				result, err := ingressClient.Get(context.TODO(), constants.IngressName, metav1.GetOptions{})
-				if err != nil {
-					http.Error(w, err.Error(), 500)
-					return
-				}
-
-				// Force rolling update using date label
-				result.ObjectMeta.Annotations = nginxAnnotations
-				_, err = ingressClient.Update(context.TODO(), result, metav1.UpdateOptions{})
-				if err != nil {
-					http.Error(w, err.Error(), 500)
-					return
-				}
-			} else {
-				http.Error(w, err.Error(), 500)
-				return
-			}
+		_, err := servicesClient.Create(ctx, service, metav1.CreateOptions{})
+		if errors.IsAlreadyExists(err) {
+			log.Printf("The service %v already exists.", service.GetObjectMeta().GetName())
+			return "Already exists", nil
 		}
+		return "Created", err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Routes of the traffic backend - 80 port
+	err = progress.Run("traffic", func() (string, error) {
+		return s.traffic.Deploy(ctx, deployRequest.ModelName, deployRequest.IsNewModel, ingressHost)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, "Deployed: %v\n", deployRequest.ModelName)
+	if !deployRequest.Wait {
+		return nil, nil
 	}
 
+	var rolloutStatus *RolloutStatus
+	err = progress.Run("rollout", func() (string, error) {
+		timeout := defaultRolloutTimeout
+		if deployRequest.TimeoutSeconds > 0 {
+			timeout = time.Duration(deployRequest.TimeoutSeconds) * time.Second
+		}
+		rolloutStatus, err = s.waitForRollout(ctx, deployRequest.ModelName, deployRequest.IsNewModel, timeout)
+		if err != nil {
+			return "", err
+		}
+		message := fmt.Sprintf("%d/%d replicas are available", rolloutStatus.AvailableReplicas, rolloutStatus.Replicas)
+		if rolloutStatus.State != RolloutComplete {
+			return message, fmt.Errorf("%v: %v", rolloutStatus.State, rolloutStatus.Message)
+		}
+		return message, nil
+	})
+	return rolloutStatus, err
 }
 
 // ModelStrategyController sets routing strategy
-func ModelStrategyController(w http.ResponseWriter, r *http.Request) {
+func (s *Server) ModelStrategyController(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
 
 	var setStrategyRequest SetStrategyRequest
@@ -349,39 +375,18 @@ func ModelStrategyController(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	kubeClientSet := clients.GetKubernetesClientSet()
-	// canary namespace
-	ingressClient := kubeClientSet.ExtensionsV1beta1().Ingresses(getNamespace(true))
-	result, err := ingressClient.Get(context.TODO(), constants.IngressName, metav1.GetOptions{})
-
+	modelName, err := s.getRequestModelName(setStrategyRequest.ModelName)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	strategyStr := ""
-	if setStrategyRequest.Strategy == constants.CurrentModelOnly {
-		result.ObjectMeta.Annotations["nginx.ingress.kubernetes.io/canary"] = "false"
-		deleteMapKeyIfExists(result.ObjectMeta.Annotations, "nginx.ingress.kubernetes.io/canary-by-header")
-		deleteMapKeyIfExists(result.ObjectMeta.Annotations, "nginx.ingress.kubernetes.io/canary-weight")
-		strategyStr = "Current Model Only"
-	} else if setStrategyRequest.Strategy == constants.NewModelOnly {
-		result.ObjectMeta.Annotations["nginx.ingress.kubernetes.io/canary"] = "true"
-		result.ObjectMeta.Annotations["nginx.ingress.kubernetes.io/canary-by-header"] = constants.CanaryHeader
-		deleteMapKeyIfExists(result.ObjectMeta.Annotations, "nginx.ingress.kubernetes.io/canary-weight")
-		strategyStr = "New Model Only"
-	} else { // else if setStrategyRequest.Strategy == constants.Canary
-		if setStrategyRequest.Weight == nil {
-			http.Error(w, "Weight missing.", 500)
-			return
-		}
-		result.ObjectMeta.Annotations["nginx.ingress.kubernetes.io/canary"] = "true"
-		deleteMapKeyIfExists(result.ObjectMeta.Annotations, "nginx.ingress.kubernetes.io/canary-by-header")
-		result.ObjectMeta.Annotations["nginx.ingress.kubernetes.io/canary-weight"] = strconv.Itoa(*setStrategyRequest.Weight)
-		strategyStr = "Canary"
+	if err := s.checkCanaryNotRunning(modelName); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
 	}
 
-	_, err = ingressClient.Update(context.TODO(), result, metav1.UpdateOptions{})
+	strategyStr, err := s.changeStrategy(context.TODO(), modelName, setStrategyRequest)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
@@ -389,75 +394,459 @@ func ModelStrategyController(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "Changed to strategy: %v\n", strategyStr)
 }
 
+// checkCanaryNotRunning returns an error while the pending or running canary changes the strategy of model
+// and analyzes both of its slots
+func (s *Server) checkCanaryNotRunning(modelName string) error {
+	s.canariesMu.Lock()
+	defer s.canariesMu.Unlock()
+
+	return s.checkCanaryNotRunningLocked(modelName)
+}
+
+// checkCanaryNotRunningLocked is checkCanaryNotRunning called with canariesMu held
+func (s *Server) checkCanaryNotRunningLocked(modelName string) error {
+	if analysis, ok := s.canaries[modelName]; ok && !analysis.Finished() {
+		return fmt.Errorf("The canary job %v is running. Cancel it first.", analysis.JobID)
+	}
+	return nil
+}
+
+// changeStrategy sets the strategy of setStrategyRequest to model and returns the strategy set to the traffic backend
+func (s *Server) changeStrategy(ctx context.Context, modelName string, setStrategyRequest SetStrategyRequest) (string, error) {
+	strategyStr, err := s.setStrategy(ctx, modelName, setStrategyRequest.Strategy, setStrategyRequest.Weight, setStrategyRequest.Target)
+	if err != nil {
+		return "", err
+	}
+	// the comparisons are collected from the start of Shadow strategy
+	if setStrategyRequest.Strategy == constants.Shadow {
+		s.comparisons.Reset(modelName)
+	}
+	return strategyStr, nil
+}
+
+// UndeployController starts a job removing the objects of model slot and returns the job
+func (s *Server) UndeployController(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+
+	var undeployRequest UndeployRequest
+	err := decoder.Decode(&undeployRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	modelName, err := s.getRequestModelName(undeployRequest.ModelName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the running canary routes predictions to both slots
+	if err := s.checkCanaryNotRunning(modelName); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	job := s.startUndeploy(modelName, undeployRequest.IsNewModel)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// startUndeploy starts a job removing the objects of model slot
+func (s *Server) startUndeploy(modelName string, isNewModel bool) jobs.Job {
+	stepNames := []string{"traffic", "deployment", "service", "secret"}
+	if isNewModel {
+		stepNames = append([]string{"strategy"}, stepNames...)
+	}
+	// the undeploy is run one by one with deploys, promotions and rollbacks of the slot
+	key := getNamespace(isNewModel) + "/" + modelName
+	return s.jobs.Start("undeploy", key, stepNames, func(ctx context.Context, progress *jobs.Progress) (interface{}, error) {
+		deleted, err := s.undeploySlot(ctx, progress, modelName, isNewModel)
+		if err != nil {
+			return nil, err
+		}
+		return &UndeployResponse{
+			ModelName:  modelName,
+			IsNewModel: isNewModel,
+			Deleted:    deleted,
+		}, nil
+	})
+}
+
 // ModelPredictControllerWrapper handles prediction
-func ModelPredictControllerWrapper(ingressHost string) http.HandlerFunc {
+func (s *Server) ModelPredictControllerWrapper(ingressHost string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		decoder := json.NewDecoder(r.Body)
-		var pixels []float32
-		err := decoder.Decode(&pixels)
+		pixels, err := readPixels(r)
 		if err != nil {
-			http.Error(w, err.Error(), 500)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		if len(pixels) != 784 {
-			http.Error(w, "Pixel should have 768 elements.", 500)
+		modelName, err := s.getRequestModelName(r.URL.Query().Get("model-name"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		// [FIXME] better way to reshape array
-		var reshapedPixels [][][][]float32 = make([][][][]float32, 1)
-		for i := 0; i < 1; i++ {
-			reshapedPixels[i] = make([][][]float32, 28)
-			for j := 0; j < 28; j++ {
-				reshapedPixels[i][j] = make([][]float32, 28)
-				for k := 0; k < 28; k++ {
-					reshapedPixels[i][j][k] = make([]float32, 1)
-					for l := 0; l < 1; l++ {
-						reshapedPixels[i][j][k][l] = pixels[j*28+k]
-					}
-				}
-			}
+		prediction, err := s.predict(r.Context(), ingressHost, modelName, pixels, r)
+		if serverErr, ok := err.(*modelServerError); ok {
+			http.Error(w, serverErr.body, serverErr.statusCode)
+			return
 		}
-
-		pixelJson, err := json.Marshal(reshapedPixels)
 		if err != nil {
 			http.Error(w, err.Error(), 500)
 			return
 		}
-		requestJson := []byte(fmt.Sprintf(`{"signature_name": "serving_default", "instances": %v}`, string(pixelJson)))
-		predictUrl, err := url.Parse(ingressHost)
 
-		// [FIXME] scheme as flag
-		predictUrl.Scheme = "http"
-		predictUrl.Path = path.Join(predictUrl.Path, "predict")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(prediction)
+	}
+}
+
+// modelServerError is the error response of the REST API of the model server, which is passed to the client
+type modelServerError struct {
+	statusCode int
+	body       string
+}
+
+func (e *modelServerError) Error() string {
+	return e.body
+}
+
+// predict sends pixels to the model selected by the strategy and returns its prediction
+// The request selects the model by its routing key, header and cookie.
+func (s *Server) predict(ctx context.Context, ingressHost string, modelName string, pixels []float32, r *http.Request) (*SlotPrediction, error) {
+	requestJson, err := newPredictRequest([][]float32{pixels})
+	if err != nil {
+		return nil, err
+	}
+
+	routing := s.getRoutingStrategy(ctx, modelName)
+	start := time.Now()
+	var slot string
+	var probabilities []float32
+	var latency time.Duration
+	if s.grpcClient != nil {
+		slot, probabilities, latency, err = s.predictGRPC(ctx, modelName, pixels, routing, r)
+	} else {
+		slot, probabilities, latency, err = s.predictREST(ctx, ingressHost, modelName, requestJson, routing, r)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the copy of the request is sent to the new model in Shadow strategy
+	if routing.strategy == constants.Shadow {
+		s.shadowPredict(ingressHost, modelName, pixels, requestJson, metrics.Comparison{
+			Time:           start,
+			ProdPrediction: probabilities,
+			ProdLatencyMs:  float64(latency) / float64(time.Millisecond),
+		})
+	}
+
+	prediction := newSlotPrediction(probabilities, slot, latency)
+	s.setSlotModel(ctx, &prediction, modelName)
+	return &prediction, nil
+}
+
+// predictREST sends the prediction request to the REST API of the model selected by routing
+// and returns the slot which served it with its probabilities and the latency
+func (s *Server) predictREST(ctx context.Context, ingressHost string, modelName string, requestJson []byte, routing routingStrategy, r *http.Request) (string, []float32, time.Duration, error) {
+	start := time.Now()
+	slot, resp, body, err := s.routePrediction(ctx, ingressHost, modelName, requestJson, routing, r)
+	if err != nil {
+		s.recordUnreachable(ctx, modelName, slot, start)
+		return slot, nil, 0, err
+	}
+	latency := time.Since(start)
+	// record the traffic of the serving model for canary analysis
+	s.recordPrediction(modelName, slot, metrics.Observation{
+		Time:    start,
+		Latency: latency,
+		Failed:  resp.StatusCode >= 500,
+	})
+	if resp.StatusCode != http.StatusOK {
+		return slot, nil, latency, &modelServerError{statusCode: resp.StatusCode, body: string(body)}
+	}
+	var predResp PredictResponse
+	err = json.Unmarshal(body, &predResp)
+	if err != nil || len(predResp.Predictions) == 0 {
+		return slot, nil, latency, fmt.Errorf("Invalid prediction response from the model server.")
+	}
+	return slot, predResp.Predictions[0], latency, nil
+}
+
+// recordPrediction records the observation of the prediction served by slot for canary analysis
+// The predictions of UnknownSlot are not recorded.
+func (s *Server) recordPrediction(modelName string, slot string, observation metrics.Observation) {
+	if slot == UnknownSlot {
+		return
+	}
+	s.metrics.Record(getNamespace(slot == CanarySlot)+"/"+modelName, observation)
+}
+
+// recordUnreachable records the failure of the prediction sent at start which got no response from slot,
+// e.g. the connection is refused or timed out, unless the request is canceled by the client
+func (s *Server) recordUnreachable(ctx context.Context, modelName string, slot string, start time.Time) {
+	if ctx.Err() != nil {
+		return
+	}
+	s.recordPrediction(modelName, slot, metrics.Observation{
+		Time:    start,
+		Latency: time.Since(start),
+		Failed:  true,
+	})
+}
+
+// imageSize is the width and height of MNIST images
+const imageSize = 28
+
+// validatePixels checks that pixels are a 28x28 image
+func validatePixels(pixels []float32) error {
+	if len(pixels) != imageSize*imageSize {
+		return fmt.Errorf("Pixel should have %d elements.", imageSize*imageSize)
+	}
+	return nil
+}
+
+// newPredictRequest returns the prediction request body of TF Serving which predicts images at once
+// Each image of 784 pixels is reshaped to [28][28][1].
+func newPredictRequest(images [][]float32) ([]byte, error) {
+	instances := make([][][][]float32, len(images))
+	for i, pixels := range images {
+		instances[i] = make([][][]float32, imageSize)
+		for row := range instances[i] {
+			instances[i][row] = make([][]float32, imageSize)
+			for col := range instances[i][row] {
+				instances[i][row][col] = []float32{pixels[row*imageSize+col]}
+			}
+		}
+	}
+
+	instancesJson, err := json.Marshal(instances)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf(`{"signature_name": "serving_default", "instances": %v}`, string(instancesJson))), nil
+}
+
+// sendPrediction sends the prediction request to the model behind the ingress and returns the response with its body
+func sendPrediction(ctx context.Context, ingressHost string, modelName string, requestJson []byte, header http.Header) (*http.Response, []byte, error) {
+	// [FIXME] scheme as flag
+	predictUrl := url.URL{
+		Scheme: "http",
+		Host:   ingressHost,
+		Path:   registry.IngressPath(modelName),
+	}
+	return postPrediction(ctx, predictUrl, requestJson, header)
+}
+
+// postPrediction sends the prediction request with header to predictUrl and returns the response with its body
+func postPrediction(ctx context.Context, predictUrl url.URL, requestJson []byte, header http.Header) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", predictUrl.String(), bytes.NewBuffer(requestJson))
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp, body, err
+}
+
+// undeploySlot deletes the objects of model slot and returns the deleted objects
+// The objects are deleted in the order of routes, deployment and service, so requests are never routed to
+// removed backends. The secret is deleted with the last model of the namespace.
+func (s *Server) undeploySlot(ctx context.Context, progress *jobs.Progress, modelName string, isNewModel bool) ([]string, error) {
+	namespace := getNamespace(isNewModel)
+	deleted := []string{}
+
+	if isNewModel {
+		err := progress.Run("strategy", func() (string, error) {
+			err := s.storeStrategy(ctx, modelName, constants.CurrentModelOnly, nil, nil)
+			if err != nil {
+				return "", err
+			}
+			return "Reset to " + traffic.StrategyName(constants.CurrentModelOnly), nil
+		})
+		if err != nil {
+			return deleted, err
+		}
+	}
+
+	// the traffic backend sends every request to the current model before removing the routes of the new model
+	err := progress.Run("traffic", func() (string, error) {
+		routes, err := s.traffic.Undeploy(ctx, modelName, isNewModel)
+		deleted = append(deleted, routes...)
+		return deletedMessage(routes), err
+	})
+	if err != nil {
+		return deleted, err
+	}
+
+	deploymentsClient := s.kubeClientSet.AppsV1().Deployments(namespace)
+	deploymentName := registry.DeploymentName(modelName)
+	err = progress.Run("deployment", func() (string, error) {
+		propagationPolicy := metav1.DeletePropagationBackground
+		err := deploymentsClient.Delete(ctx, deploymentName, metav1.DeleteOptions{
+			PropagationPolicy: &propagationPolicy,
+		})
+		if errors.IsNotFound(err) {
+			return deletedMessage(nil), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		deleted = append(deleted, "deployment/"+deploymentName)
+		return deletedMessage([]string{"deployment/" + deploymentName}), nil
+	})
+	if err != nil {
+		return deleted, err
+	}
 
-		req, err := http.NewRequest("POST", predictUrl.String(), bytes.NewBuffer(requestJson))
-		// the header will be ignored when non-canary model prediction
-		req.Header.Set(constants.CanaryHeader, "always")
-		req.Header.Set("Content-Type", "application/json")
+	serviceName := registry.ServiceName(modelName)
+	err = progress.Run("service", func() (string, error) {
+		err := s.kubeClientSet.CoreV1().Services(namespace).Delete(ctx, serviceName, metav1.DeleteOptions{})
+		if errors.IsNotFound(err) {
+			return deletedMessage(nil), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		deleted = append(deleted, "service/"+serviceName)
+		return deletedMessage([]string{"service/" + serviceName}), nil
+	})
+	if err != nil {
+		return deleted, err
+	}
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+	// the secret is shared by the models in the namespace
+	err = progress.Run("secret", func() (string, error) {
+		deployments, err := deploymentsClient.List(ctx, metav1.ListOptions{
+			LabelSelector: "app=" + constants.LabelAppSelector,
+		})
 		if err != nil {
-			http.Error(w, err.Error(), 500)
-			return
+			return "", err
+		}
+		if len(deployments.Items) > 0 {
+			return "Kept for the other models", nil
 		}
-		defer resp.Body.Close()
-		body, _ := ioutil.ReadAll(resp.Body)
-		var predResp PredictResponse
-		json.Unmarshal(body, &predResp)
+		err = s.kubeClientSet.CoreV1().Secrets(namespace).Delete(ctx, constants.ModelSecretName, metav1.DeleteOptions{})
+		if errors.IsNotFound(err) {
+			return deletedMessage(nil), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		deleted = append(deleted, "secret/"+constants.ModelSecretName)
+		return deletedMessage([]string{"secret/" + constants.ModelSecretName}), nil
+	})
+	return deleted, err
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(predResp.Predictions[0])
+// deletedMessage returns the message of the step deleting the objects
+func deletedMessage(deleted []string) string {
+	if len(deleted) == 0 {
+		return "Already deleted"
+	}
+	return "Deleted " + strings.Join(deleted, ", ")
+}
+
+// getRequestModelName returns the registered model name of request
+// If the name is not specified, the only registered model is used.
+// The registry is read from the cache when it is enabled, because every prediction checks the model name.
+func (s *Server) getRequestModelName(modelName string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+	defer cancel()
+
+	if modelName != "" {
+		err := registry.ValidateName(modelName)
+		if err != nil {
+			return "", err
+		}
+		_, err = s.cachedRegistry().Get(ctx, modelName)
+		if err == registry.ErrNotFound && s.cache != nil {
+			// the model registered just now may not be cached yet
+			_, err = s.registry.Get(ctx, modelName)
+		}
+		if err != nil {
+			return "", fmt.Errorf("%v: %v", modelName, err)
+		}
+		return modelName, nil
+	}
+
+	models, err := s.cachedRegistry().List(ctx)
+	if err == nil && len(models) != 1 && s.cache != nil {
+		models, err = s.registry.List(ctx)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(models) != 1 {
+		return "", fmt.Errorf("The model name should be specified among %d models.", len(models))
+	}
+	return models[0].Name, nil
+}
+
+// createNamespaces creates namespaces of current (production) and new (canary) models
+func (s *Server) createNamespaces(ctx context.Context) error {
+	namespacesClient := s.kubeClientSet.CoreV1().Namespaces()
+	for _, namespace := range []string{constants.ProdNamespace, constants.CanaryNamespace} {
+		_, err := namespacesClient.Create(ctx, &apiv1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: namespace,
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			if errors.IsAlreadyExists(err) {
+				log.Printf("The namespace %v already exists.", namespace)
+			} else {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getModelName returns MODEL_NAME environment variable of Tensorflow Serving container
+func getModelName(deployment *appsv1.Deployment) string {
+	return getEnvValue(deployment, "MODEL_NAME")
+}
+
+// getModelBasePath returns MODEL_BASE_PATH environment variable of Tensorflow Serving container
+func getModelBasePath(deployment *appsv1.Deployment) string {
+	return getEnvValue(deployment, "MODEL_BASE_PATH")
+}
+
+func getEnvValue(deployment *appsv1.Deployment, name string) string {
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		for _, env := range container.Env {
+			if env.Name == name {
+				return env.Value
+			}
+		}
 	}
+	return ""
 }
 
-func deleteMapKeyIfExists(m map[string]string, key string) {
-	_, ok := m[key]
-	if ok {
-		delete(m, key)
+// forceRollingUpdate changes the date annotation of Pod template so that Pods are replaced
+// even when the model base directory is not changed
+func forceRollingUpdate(deployment *appsv1.Deployment) {
+	if deployment.Spec.Template.ObjectMeta.Annotations == nil {
+		deployment.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
 	}
+	deployment.Spec.Template.ObjectMeta.Annotations["date"] = strconv.FormatInt(time.Now().Unix(), 10)
 }
 
 func readFileToBase64String(googleCredsFilePath string) (string, error) {
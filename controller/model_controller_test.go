@@ -2,58 +2,609 @@ package controller
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"path/filepath"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/josh9191/mini-mnist-serving/clients"
-	"k8s.io/client-go/util/homedir"
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/jobs"
+	"github.com/josh9191/mini-mnist-serving/registry"
+	"github.com/josh9191/mini-mnist-serving/traffic"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
+const testIngressHost string = "mini-serving.example.com"
+
+func startDeploy(t *testing.T, s *Server, credsFilePath string, deployReqBody map[string]interface{}) *http.Response {
+	body, _ := json.Marshal(deployReqBody)
+
+	r, err := http.NewRequest("POST", "/model:deploy", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	handler := s.DeployControllerWrapper(credsFilePath, testIngressHost)
+	handler.ServeHTTP(w, r)
+
+	return w.Result()
+}
+
+// deployModel starts a deploy job and returns the finished job
+func deployModel(t *testing.T, s *Server, credsFilePath string, deployReqBody map[string]interface{}) jobs.Job {
+	resp := startDeploy(t, s, credsFilePath, deployReqBody)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+	var job jobs.Job
+	json.NewDecoder(resp.Body).Decode(&job)
+
+	job, err := s.jobs.Wait(context.TODO(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func setStrategy(t *testing.T, s *Server, setStrategyReqBody map[string]interface{}) *http.Response {
+	body, _ := json.Marshal(setStrategyReqBody)
+
+	r, err := http.NewRequest("PUT", "/model/strategy", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	handler := http.HandlerFunc(s.ModelStrategyController)
+	handler.ServeHTTP(w, r)
+
+	return w.Result()
+}
+
+func startUndeploy(t *testing.T, s *Server, undeployReqBody map[string]interface{}) *http.Response {
+	body, _ := json.Marshal(undeployReqBody)
+
+	r, err := http.NewRequest("DELETE", "/model:undeploy", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	handler := http.HandlerFunc(s.UndeployController)
+	handler.ServeHTTP(w, r)
+
+	return w.Result()
+}
+
+// undeployModel runs the undeploy job and returns its result
+func undeployModel(t *testing.T, s *Server, undeployReqBody map[string]interface{}) *UndeployResponse {
+	resp := startUndeploy(t, s, undeployReqBody)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+	var job jobs.Job
+	json.NewDecoder(resp.Body).Decode(&job)
+
+	job, err := s.jobs.Wait(context.TODO(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	undeployResponse, ok := job.Result.(*UndeployResponse)
+	if job.State != jobs.Succeeded || !ok {
+		t.Fatalf("Error - Job: %+v", job)
+	}
+	return undeployResponse
+}
+
 func TestDeployControllerWrapper(t *testing.T) {
+	t.Run(clients.NetworkingV1, func(t *testing.T) {
+		testDeployControllerWrapper(t, clients.NewNetworkingV1IngressClient)
+	})
+	t.Run(clients.ExtensionsV1beta1, func(t *testing.T) {
+		testDeployControllerWrapper(t, clients.NewExtensionsV1beta1IngressClient)
+	})
+}
+
+func testDeployControllerWrapper(t *testing.T, newIngressClient func(kubernetes.Interface) clients.IngressClient) {
 	const modelBaseDir string = "gs://nice-soldev-tf-models/mnist-new/model/1"
 	const modelName string = "model"
-	const isNewModel bool = true
 	const numReplicas int = 2
 
+	s, kubeClientSet := newTestServerWithIngressClient(newIngressClient)
+	credsFilePath := writeTestCredsFile(t)
+
+	for _, isNewModel := range []bool{false, true} {
+		job := deployModel(t, s, credsFilePath, map[string]interface{}{
+			"model-base-dir": modelBaseDir,
+			"model-name":     modelName,
+			"is-new-model":   isNewModel,
+			"num-replicas":   numReplicas,
+		})
+		if job.State != jobs.Succeeded {
+			t.Fatalf("Error - Job: %+v", job)
+		}
+		for i, name := range []string{"namespaces", "secret", "deployment", "service", "traffic"} {
+			step := job.Steps[i]
+			if step.Name != name || step.State != jobs.Succeeded || step.FinishedAt == nil {
+				t.Errorf("Wrong step: %+v", step)
+			}
+		}
+	}
+
+	for _, namespace := range []string{constants.ProdNamespace, constants.CanaryNamespace} {
+		_, err := kubeClientSet.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("Namespace %v is not created: %v", namespace, err)
+		}
+
+		_, err = kubeClientSet.CoreV1().Secrets(namespace).Get(context.TODO(), constants.ModelSecretName, metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("Secret is not created in %v: %v", namespace, err)
+		}
+
+		_, err = kubeClientSet.CoreV1().Services(namespace).Get(context.TODO(), registry.ServiceName(constants.DefaultModelName), metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("Service is not created in %v: %v", namespace, err)
+		}
+
+		deployment, err := kubeClientSet.AppsV1().Deployments(namespace).Get(context.TODO(), registry.DeploymentName(constants.DefaultModelName), metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Deployment is not created in %v: %v", namespace, err)
+		}
+		if *deployment.Spec.Replicas != int32(numReplicas) {
+			t.Errorf("Wrong number of replicas in %v: %d", namespace, *deployment.Spec.Replicas)
+		}
+		env := deployment.Spec.Template.Spec.Containers[0].Env
+		if env[0].Name != "MODEL_BASE_PATH" || env[0].Value != modelBaseDir {
+			t.Errorf("Wrong model base path in %v: %v", namespace, env[0])
+		}
+	}
+
+	prodIngress, err := s.ingressClient.Get(context.TODO(), constants.ProdNamespace, registry.IngressName(constants.DefaultModelName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prodIngress.Annotations["nginx.ingress.kubernetes.io/rewrite-target"] != "/v1/models/model:predict" {
+		t.Errorf("Wrong rewrite target: %v", prodIngress.Annotations)
+	}
+	// ingress-nginx rejects snippets by default
+	if _, ok := prodIngress.Annotations["nginx.ingress.kubernetes.io/configuration-snippet"]; ok {
+		t.Errorf("Configuration snippet is added: %v", prodIngress.Annotations)
+	}
+	if prodIngress.Host != testIngressHost {
+		t.Errorf("Wrong ingress host: %v", prodIngress.Host)
+	}
+	if prodIngress.ClassName != constants.IngressClassName {
+		t.Errorf("Wrong ingress class: %v", prodIngress.ClassName)
+	}
+
+	canaryIngress, err := s.ingressClient.Get(context.TODO(), constants.CanaryNamespace, registry.IngressName(constants.DefaultModelName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canaryIngress.Annotations["nginx.ingress.kubernetes.io/canary"] != "true" {
+		t.Errorf("Canary ingress is not marked as canary: %v", canaryIngress.Annotations)
+	}
+}
+
+func TestDeployControllerWrapperRedeploy(t *testing.T) {
+	s, kubeClientSet := newTestServer()
+	credsFilePath := writeTestCredsFile(t)
+
 	deployReqBody := map[string]interface{}{
-		"model-base-dir": modelBaseDir,
-		"model-name":     modelName,
-		"is-new-model":   isNewModel,
-		"num-replicas":   numReplicas,
+		"model-base-dir": "gs://my-bucket/mnist/model/1",
+		"model-name":     "model",
+		"is-new-model":   false,
+		"num-replicas":   1,
+	}
+	job := deployModel(t, s, credsFilePath, deployReqBody)
+	if job.State != jobs.Succeeded {
+		t.Fatalf("Error - Job: %+v", job)
 	}
-	body, _ := json.Marshal(deployReqBody)
 
-	r, err := http.NewRequest("POST", "/model:deploy", bytes.NewReader(body))
+	deployReqBody["model-base-dir"] = "gs://my-bucket/mnist/model/2"
+	deployReqBody["num-replicas"] = 3
+	job = deployModel(t, s, credsFilePath, deployReqBody)
+	if job.State != jobs.Succeeded {
+		t.Fatalf("Error - Job: %+v", job)
+	}
+
+	deployment, err := kubeClientSet.AppsV1().Deployments(constants.ProdNamespace).Get(context.TODO(), registry.DeploymentName(constants.DefaultModelName), metav1.GetOptions{})
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	if *deployment.Spec.Replicas != 3 {
+		t.Errorf("Wrong number of replicas: %d", *deployment.Spec.Replicas)
+	}
+	if deployment.Spec.Template.Spec.Containers[0].Env[0].Value != "gs://my-bucket/mnist/model/2" {
+		t.Errorf("Model base path is not updated: %v", deployment.Spec.Template.Spec.Containers[0].Env[0])
+	}
+	if _, ok := deployment.Spec.Template.Annotations["date"]; !ok {
+		t.Errorf("Rolling update is not forced: %v", deployment.Spec.Template.Annotations)
+	}
+}
+
+func TestDeployControllerWrapperInvalidRequest(t *testing.T) {
+	s, _ := newTestServer()
+	credsFilePath := writeTestCredsFile(t)
+
+	for _, modelName := range []string{"", "My_Model", "model:predict", "-model", strings.Repeat("m", 64)} {
+		resp := startDeploy(t, s, credsFilePath, map[string]interface{}{
+			"model-base-dir": "gs://my-bucket/mnist",
+			"model-name":     modelName,
+			"is-new-model":   false,
+			"num-replicas":   1,
+		})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Invalid model name %q: Status Code: %d", modelName, resp.StatusCode)
+		}
 	}
 
-	// initliaze kubernetes configuration - you should use config file in hoe directory ($HOME/.kube/config)
-	home := homedir.HomeDir()
-	kubeconfig := filepath.Join(home, ".kube", "config")
-	clients.InitKubernetesClient(kubeconfig)
+	resp := startDeploy(t, s, "/nonexistent/key.json", map[string]interface{}{
+		"model-base-dir": "gs://my-bucket/mnist/model/1",
+		"model-name":     "model",
+		"is-new-model":   false,
+		"num-replicas":   1,
+	})
+	if resp.StatusCode == http.StatusAccepted {
+		t.Errorf("Deploy started without Google application credentials")
+	}
+}
 
-	// [FIXME] change the paths below
-	googleCredsFilePath := "/home/josh9191/gcp/key.json"
-	ingressHost := "mini-serving.duckdns.org"
+func TestDeployControllerWrapperMultipleModels(t *testing.T) {
+	s, kubeClientSet := newTestServer()
+	credsFilePath := writeTestCredsFile(t)
 
-	w := httptest.NewRecorder()
-	handlerFunc := DeployControllerWrapper(googleCredsFilePath, ingressHost)
-	handler := http.HandlerFunc(handlerFunc)
+	modelNames := []string{"mnist-cnn", "mnist-mlp"}
+	for _, modelName := range modelNames {
+		job := deployModel(t, s, credsFilePath, map[string]interface{}{
+			"model-base-dir": "gs://my-bucket/classifiers",
+			"model-name":     modelName,
+			"is-new-model":   false,
+			"num-replicas":   1,
+		})
+		if job.State != jobs.Succeeded {
+			t.Fatalf("Error - Job: %+v", job)
+		}
+	}
 
-	handler.ServeHTTP(w, r)
+	models, err := s.registry.List(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != len(modelNames) {
+		t.Fatalf("Deployed models are not registered: %v", models)
+	}
 
-	resp := w.Result()
+	for _, modelName := range modelNames {
+		deployment, err := kubeClientSet.AppsV1().Deployments(constants.ProdNamespace).Get(context.TODO(), registry.DeploymentName(modelName), metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if getModelName(deployment) != modelName {
+			t.Errorf("Wrong MODEL_NAME: %v", deployment.Spec.Template.Spec.Containers[0].Env)
+		}
+		if deployment.Spec.Selector.MatchLabels["model"] != modelName {
+			t.Errorf("Deployment selects Pods of other models: %v", deployment.Spec.Selector.MatchLabels)
+		}
+
+		service, err := kubeClientSet.CoreV1().Services(constants.ProdNamespace).Get(context.TODO(), registry.ServiceName(modelName), metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if service.Spec.Selector["model"] != modelName {
+			t.Errorf("Service selects Pods of other models: %v", service.Spec.Selector)
+		}
+
+		ingress, err := s.ingressClient.Get(context.TODO(), constants.ProdNamespace, registry.IngressName(modelName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ingress.Annotations["nginx.ingress.kubernetes.io/rewrite-target"] != "/v1/models/"+modelName+":predict" {
+			t.Errorf("Wrong rewrite target: %v", ingress.Annotations)
+		}
+		if ingress.Path != "/predict/"+modelName || ingress.ServiceName != registry.ServiceName(modelName) {
+			t.Errorf("Wrong ingress path: %v -> %v", ingress.Path, ingress.ServiceName)
+		}
+	}
+}
+
+func TestModelStrategyController(t *testing.T) {
+	s, _ := newTestServer()
+	credsFilePath := writeTestCredsFile(t)
 
+	for _, isNewModel := range []bool{false, true} {
+		job := deployModel(t, s, credsFilePath, map[string]interface{}{
+			"model-base-dir": "gs://my-bucket/mnist/model/1",
+			"model-name":     "model",
+			"is-new-model":   isNewModel,
+			"num-replicas":   1,
+		})
+		if job.State != jobs.Succeeded {
+			t.Fatalf("Error - Job: %+v", job)
+		}
+	}
+
+	const canaryAnnotation = "nginx.ingress.kubernetes.io/canary"
+	const canaryByHeaderAnnotation = "nginx.ingress.kubernetes.io/canary-by-header"
+	const canaryWeightAnnotation = "nginx.ingress.kubernetes.io/canary-weight"
+	const canaryByHeaderValueAnnotation = "nginx.ingress.kubernetes.io/canary-by-header-value"
+	const canaryByHeaderPatternAnnotation = "nginx.ingress.kubernetes.io/canary-by-header-pattern"
+	const canaryByCookieAnnotation = "nginx.ingress.kubernetes.io/canary-by-cookie"
+
+	weight := 30
+	tests := []struct {
+		name        string
+		request     map[string]interface{}
+		annotations map[string]string
+		absent      []string
+	}{
+		{
+			name:        "new model only",
+			request:     map[string]interface{}{"strategy": constants.NewModelOnly},
+			annotations: map[string]string{canaryAnnotation: "true", canaryByHeaderAnnotation: constants.CanaryHeader},
+			absent:      []string{canaryWeightAnnotation},
+		},
+		{
+			name: "targeted by header value",
+			request: map[string]interface{}{"strategy": constants.Targeted, "target": map[string]interface{}{
+				"header": "X-Client-Id", "values": []string{"alice"},
+			}},
+			annotations: map[string]string{canaryAnnotation: "true", canaryByHeaderAnnotation: "X-Client-Id", canaryByHeaderValueAnnotation: "alice",
+				traffic.TargetAnnotation: `{"header":"X-Client-Id","values":["alice"]}`},
+			absent: []string{canaryWeightAnnotation, canaryByHeaderPatternAnnotation, canaryByCookieAnnotation},
+		},
+		{
+			name: "targeted by header values and cookie",
+			request: map[string]interface{}{"strategy": constants.Targeted, "target": map[string]interface{}{
+				"header": "X-Client-Id", "values": []string{"alice", "bob.smith"}, "cookie": "mnist_canary",
+			}},
+			annotations: map[string]string{canaryAnnotation: "true", canaryByHeaderAnnotation: "X-Client-Id",
+				canaryByHeaderPatternAnnotation: `^(alice|bob\.smith)$`, canaryByCookieAnnotation: "mnist_canary"},
+			absent: []string{canaryWeightAnnotation, canaryByHeaderValueAnnotation},
+		},
+		{
+			name:        "targeted by cookie",
+			request:     map[string]interface{}{"strategy": constants.Targeted, "target": map[string]interface{}{"cookie": "mnist_canary"}},
+			annotations: map[string]string{canaryAnnotation: "true", canaryByCookieAnnotation: "mnist_canary"},
+			absent:      []string{canaryWeightAnnotation, canaryByHeaderAnnotation, canaryByHeaderValueAnnotation, canaryByHeaderPatternAnnotation},
+		},
+		{
+			name:        "canary",
+			request:     map[string]interface{}{"strategy": constants.Canary, "weight": weight},
+			annotations: map[string]string{canaryAnnotation: "true", canaryWeightAnnotation: strconv.Itoa(weight)},
+			absent:      []string{canaryByHeaderAnnotation, canaryByCookieAnnotation, traffic.TargetAnnotation},
+		},
+		{
+			name:        "current model only",
+			request:     map[string]interface{}{"strategy": constants.CurrentModelOnly},
+			annotations: map[string]string{canaryAnnotation: "false"},
+			absent:      []string{canaryByHeaderAnnotation, canaryWeightAnnotation},
+		},
+	}
+
+	for _, test := range tests {
+		resp := setStrategy(t, s, test.request)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%v: Error - Status Code: %d", test.name, resp.StatusCode)
+		}
+
+		ingress, err := s.ingressClient.Get(context.TODO(), constants.CanaryNamespace, registry.IngressName(constants.DefaultModelName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, value := range test.annotations {
+			if ingress.Annotations[key] != value {
+				t.Errorf("%v: annotation %v = %q, want %q", test.name, key, ingress.Annotations[key], value)
+			}
+		}
+		for _, key := range test.absent {
+			if _, ok := ingress.Annotations[key]; ok {
+				t.Errorf("%v: annotation %v should be removed", test.name, key)
+			}
+		}
+	}
+}
+
+func TestModelStrategyControllerInvalidRequest(t *testing.T) {
+	// canary ingress doesn't exist
+	s, _ := newTestServer()
+	resp := setStrategy(t, s, map[string]interface{}{"strategy": constants.NewModelOnly})
+	if resp.StatusCode == http.StatusOK {
+		t.Errorf("Strategy is set without canary ingress")
+	}
+
+	// weight missing
+	s, _ = newTestServer(&networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        registry.IngressName(constants.DefaultModelName),
+			Namespace:   constants.CanaryNamespace,
+			Annotations: map[string]string{},
+		},
+	})
+	registerModels(t, s, constants.DefaultModelName)
+	resp = setStrategy(t, s, map[string]interface{}{"strategy": constants.Canary})
+	if resp.StatusCode == http.StatusOK {
+		t.Errorf("Canary strategy is set without weight")
+	}
+
+	// invalid target rules
+	for _, target := range []map[string]interface{}{
+		nil,
+		{"header": "X-Client-Id"},
+		{"header": "X-Client-Id", "values": []string{""}},
+		{"header": "X Client", "values": []string{"alice"}},
+		{"cookie": "mnist;canary"},
+	} {
+		resp = setStrategy(t, s, map[string]interface{}{"strategy": constants.Targeted, "target": target})
+		if resp.StatusCode == http.StatusOK {
+			t.Errorf("Targeted strategy is set with target %v", target)
+		}
+	}
+}
+
+func TestUndeployController(t *testing.T) {
+	s, kubeClientSet := newTestServer()
+	credsFilePath := writeTestCredsFile(t)
+
+	for _, modelName := range []string{"mnist-cnn", "mnist-mlp"} {
+		for _, isNewModel := range []bool{false, true} {
+			job := deployModel(t, s, credsFilePath, map[string]interface{}{
+				"model-base-dir": "gs://my-bucket/classifiers",
+				"model-name":     modelName,
+				"is-new-model":   isNewModel,
+				"num-replicas":   1,
+			})
+			if job.State != jobs.Succeeded {
+				t.Fatalf("Error - Job: %+v", job)
+			}
+		}
+	}
+	resp := setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": constants.NewModelOnly})
 	if resp.StatusCode != http.StatusOK {
-		t.Errorf("Error - Status Code: %d", resp.StatusCode)
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+	kubeClientSet.ClearActions()
+
+	undeployResponse := undeployModel(t, s, map[string]interface{}{"model-name": "mnist-cnn", "is-new-model": true})
+	wantDeleted := []string{"ingress/mnist-cnn-ingress", "deployment/mnist-cnn-deploy", "service/mnist-cnn-svc"}
+	if strings.Join(undeployResponse.Deleted, ",") != strings.Join(wantDeleted, ",") {
+		t.Errorf("Wrong deleted objects: %v", undeployResponse.Deleted)
+	}
+
+	// the canary ingress routes every request to the current model before it is deleted
+	verbs := []string{}
+	for _, action := range kubeClientSet.Actions() {
+		if action.GetResource().Resource == "ingresses" && action.GetVerb() != "get" {
+			verbs = append(verbs, action.GetVerb())
+		}
+	}
+	if strings.Join(verbs, ",") != "update,delete" {
+		t.Errorf("Wrong order of ingress actions: %v", verbs)
+	}
+
+	// the secret is kept for the other model
+	_, err := kubeClientSet.CoreV1().Secrets(constants.CanaryNamespace).Get(context.TODO(), constants.ModelSecretName, metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("Secret is deleted while other model is deployed: %v", err)
+	}
+	_, err = kubeClientSet.AppsV1().Deployments(constants.ProdNamespace).Get(context.TODO(), registry.DeploymentName("mnist-cnn"), metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("Current model is deleted: %v", err)
+	}
+
+	undeployResponse = undeployModel(t, s, map[string]interface{}{"model-name": "mnist-mlp", "is-new-model": true})
+	if undeployResponse.Deleted[len(undeployResponse.Deleted)-1] != "secret/"+constants.ModelSecretName {
+		t.Errorf("Secret is not deleted with the last model: %v", undeployResponse.Deleted)
+	}
+	_, err = kubeClientSet.CoreV1().Secrets(constants.CanaryNamespace).Get(context.TODO(), constants.ModelSecretName, metav1.GetOptions{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("Secret is not deleted: %v", err)
+	}
+
+	// nothing left to delete
+	undeployResponse = undeployModel(t, s, map[string]interface{}{"model-name": "mnist-mlp", "is-new-model": true})
+	if len(undeployResponse.Deleted) != 0 {
+		t.Errorf("Wrong deleted objects: %v", undeployResponse.Deleted)
+	}
+
+	resp = startUndeploy(t, s, map[string]interface{}{"model-name": "mnist-rnn", "is-new-model": true})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Unknown model: Status Code: %d", resp.StatusCode)
+	}
+}
+
+func TestUndeployControllerAfterDeploy(t *testing.T) {
+	s, kubeClientSet := newTestServer()
+	deployBothSlots(t, s)
+
+	// the job of the new model slot is running
+	release := make(chan struct{})
+	deployJob := s.jobs.Start("deploy", constants.CanaryNamespace+"/mnist-cnn", nil, func(ctx context.Context, progress *jobs.Progress) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	resp := startUndeploy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "is-new-model": true})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+	var job jobs.Job
+	json.NewDecoder(resp.Body).Decode(&job)
+	if job.Kind != "undeploy" || job.Key != deployJob.Key || len(job.Steps) != 5 {
+		t.Errorf("Wrong job: %+v", job)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if pending, _ := s.jobs.Get(job.ID); pending.State != jobs.Pending {
+		t.Errorf("Undeploy runs with the deploy: %+v", pending)
+	}
+	if _, err := kubeClientSet.AppsV1().Deployments(constants.CanaryNamespace).Get(context.TODO(), registry.DeploymentName("mnist-cnn"), metav1.GetOptions{}); err != nil {
+		t.Errorf("New model is deleted before the deploy: %v", err)
+	}
+
+	close(release)
+	job, err := s.jobs.Wait(context.TODO(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != jobs.Succeeded {
+		t.Fatalf("Error - Job: %+v", job)
+	}
+	_, err = kubeClientSet.AppsV1().Deployments(constants.CanaryNamespace).Get(context.TODO(), registry.DeploymentName("mnist-cnn"), metav1.GetOptions{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("New model is not deleted: %v", err)
+	}
+}
+
+func TestUndeployControllerCanaryRunning(t *testing.T) {
+	s, _ := newTestServer()
+	deployBothSlots(t, s)
+	s.canaries["mnist-cnn"] = &CanaryAnalysis{JobID: "0123456789abcdef", ModelName: "mnist-cnn", State: CanaryRunning}
+
+	for _, isNewModel := range []bool{true, false} {
+		resp := startUndeploy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "is-new-model": isNewModel})
+		if resp.StatusCode != http.StatusConflict {
+			t.Errorf("Undeploy while canary is running: Status Code: %d", resp.StatusCode)
+		}
 	}
 }
 
 func TestModelPredictControllerWrapper(t *testing.T) {
+	// stub of Tensorflow Serving behind the ingress
+	modelServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/predict/mnist-cnn" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get(constants.CanaryHeader) != "always" {
+			t.Errorf("Canary header is missing")
+		}
+
+		var predictRequest struct {
+			Instances [][][][]float32 `json:"instances"`
+		}
+		json.NewDecoder(r.Body).Decode(&predictRequest)
+		if len(predictRequest.Instances) != 1 || len(predictRequest.Instances[0]) != 28 || len(predictRequest.Instances[0][0]) != 28 {
+			t.Errorf("Wrong instances shape")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.9, 0.1, 0.0, 0.0, 0.0]]}`))
+	}))
+	defer modelServer.Close()
+	modelServerUrl, _ := url.Parse(modelServer.URL)
+
 	deployReqBody := make([]float32, 784)
 	body, _ := json.Marshal(deployReqBody)
 
@@ -62,40 +613,146 @@ func TestModelPredictControllerWrapper(t *testing.T) {
 		t.Error(err)
 	}
 
-	// initliaze kubernetes configuration - you should use config file in hoe directory ($HOME/.kube/config)
-	home := homedir.HomeDir()
-	kubeconfig := filepath.Join(home, ".kube", "config")
-	clients.InitKubernetesClient(kubeconfig)
-
-	// [FIXME] change the path below
-	ingressHost := "mini-serving.duckdns.org"
-
+	// the only registered model is used without model-name parameter
+	s, _ := newTestServer()
+	enableTestUpstreamHeader(s)
+	registerModels(t, s, "mnist-cnn")
 	w := httptest.NewRecorder()
-	handlerFunc := ModelPredictControllerWrapper(ingressHost)
+	handlerFunc := s.ModelPredictControllerWrapper(modelServerUrl.Host)
 	handler := http.HandlerFunc(handlerFunc)
 
 	handler.ServeHTTP(w, r)
 
 	resp := w.Result()
 	decoder := json.NewDecoder(resp.Body)
-	var predictions []float32
-	err = decoder.Decode(&predictions)
+	var prediction SlotPrediction
+	err = decoder.Decode(&prediction)
 
 	if resp.StatusCode != http.StatusOK {
 		t.Errorf("Error - Status Code: %d", resp.StatusCode)
 	}
 
-	var max float32 = -1.0
-	argmax := 0
-	for i := 0; i < len(predictions); i++ {
-		if max < predictions[i] {
-			max = predictions[i]
-			argmax = i
+	if prediction.Argmax != 5 || prediction.Confidence != 0.9 || len(prediction.Probabilities) != 10 {
+		t.Errorf("Unit test failed - Wrong prediction value: %+v", prediction)
+		t.Errorf("Input: %v", deployReqBody)
+	}
+	// the model is not deployed
+	if prediction.Slot != ProdSlot || prediction.ModelBasePath != "" || prediction.Revision != 0 {
+		t.Errorf("Wrong serving model: %+v", prediction)
+	}
+}
+
+func TestModelPredictControllerWrapperServingSlot(t *testing.T) {
+	// the ingress routes the second request to the new model
+	var requests int32
+	modelServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 2 {
+			w.Header().Set(constants.CanaryUpstreamHeader, "mnist-canary-mnist-cnn-svc-8501")
+			w.Write([]byte(`{"predictions": [[0.0, 0.0, 0.0, 0.7, 0.0, 0.3, 0.0, 0.0, 0.0, 0.0]]}`))
+		} else {
+			w.Write([]byte(`{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.9, 0.1, 0.0, 0.0, 0.0]]}`))
+		}
+	}))
+	defer modelServer.Close()
+	modelServerUrl, _ := url.Parse(modelServer.URL)
+
+	s, _ := newTestServer()
+	enableTestUpstreamHeader(s)
+	deployBothSlots(t, s)
+
+	handler := s.ModelPredictControllerWrapper(modelServerUrl.Host)
+	body, _ := json.Marshal(make([]float32, 784))
+	for _, expected := range []SlotPrediction{
+		{Argmax: 5, Confidence: 0.9, Slot: ProdSlot, ModelBasePath: "gs://my-bucket/v1", Revision: 1},
+		{Argmax: 3, Confidence: 0.7, Slot: CanarySlot, ModelBasePath: "gs://my-bucket/v2", Revision: 1},
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/model:predict?model-name=mnist-cnn", bytes.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Error - Status Code: %d", w.Code)
+		}
+
+		var prediction SlotPrediction
+		json.NewDecoder(w.Body).Decode(&prediction)
+		if prediction.Argmax != expected.Argmax || prediction.Confidence != expected.Confidence || prediction.Slot != expected.Slot ||
+			prediction.ModelBasePath != expected.ModelBasePath || prediction.Revision != expected.Revision {
+			t.Errorf("Wrong prediction: %+v, expected: %+v", prediction, expected)
 		}
 	}
+}
 
-	if argmax != 5 {
-		t.Errorf("Unit test failed - Wrong prediction value")
-		t.Errorf("Input: %v", deployReqBody)
+func TestModelPredictControllerWrapperCache(t *testing.T) {
+	modelServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.9, 0.1, 0.0, 0.0, 0.0]]}`))
+	}))
+	defer modelServer.Close()
+	modelServerUrl, _ := url.Parse(modelServer.URL)
+
+	s, kubeClientSet := newTestServer()
+	enableTestUpstreamHeader(s)
+	deployBothSlots(t, s)
+	enableTestCache(t, s)
+	kubeClientSet.ClearActions()
+
+	w := httptest.NewRecorder()
+	body, _ := json.Marshal(make([]float32, 784))
+	s.ModelPredictControllerWrapper(modelServerUrl.Host).ServeHTTP(w, httptest.NewRequest("POST", "/model:predict?model-name=mnist-cnn", bytes.NewReader(body)))
+	var prediction SlotPrediction
+	json.NewDecoder(w.Body).Decode(&prediction)
+	if w.Code != http.StatusOK || prediction.ModelBasePath != "gs://my-bucket/v1" {
+		t.Fatalf("Wrong prediction: %d, %+v", w.Code, prediction)
+	}
+
+	// the model name, the strategy and the model of slot are read from the cache
+	for _, action := range kubeClientSet.Actions() {
+		if action.GetVerb() == "get" {
+			t.Errorf("%v %v is not cached", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+}
+
+func TestModelPredictControllerWrapperInvalidInput(t *testing.T) {
+	body, _ := json.Marshal(make([]float32, 10))
+
+	r, err := http.NewRequest("POST", "/model:predict", bytes.NewReader(body))
+	if err != nil {
+		t.Error(err)
+	}
+
+	s, _ := newTestServer()
+	w := httptest.NewRecorder()
+	handler := s.ModelPredictControllerWrapper(testIngressHost)
+	handler.ServeHTTP(w, r)
+
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("Wrong number of pixels: Status Code: %d", w.Result().StatusCode)
+	}
+
+	// no model is registered
+	body, _ = json.Marshal(make([]float32, 784))
+	r, err = http.NewRequest("POST", "/model:predict", bytes.NewReader(body))
+	if err != nil {
+		t.Error(err)
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Result().StatusCode == http.StatusOK {
+		t.Errorf("Prediction succeeded without registered model")
+	}
+
+	// model name is ambiguous or unknown
+	registerModels(t, s, "mnist-cnn", "mnist-mlp")
+	for _, target := range []string{"/model:predict", "/model:predict?model-name=mnist-rnn"} {
+		r, err = http.NewRequest("POST", target, bytes.NewReader(body))
+		if err != nil {
+			t.Error(err)
+		}
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("%v: Status Code: %d", target, w.Result().StatusCode)
+		}
 	}
 }
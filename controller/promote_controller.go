@@ -0,0 +1,284 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/jobs"
+	"github.com/josh9191/mini-mnist-serving/registry"
+	"github.com/josh9191/mini-mnist-serving/traffic"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PromoteRequest stores promote request JSON data
+type PromoteRequest struct {
+	ModelName       string `json:"model-name"`
+	ScaleDownCanary bool   `json:"scale-down-canary"`
+	TimeoutSeconds  int    `json:"timeout-seconds,omitempty"`
+}
+
+// PromoteStep stores the result of a promotion step
+type PromoteStep struct {
+	Name    string `json:"name"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PromoteResponse stores the steps of promotion
+type PromoteResponse struct {
+	ModelName    string         `json:"model-name"`
+	ModelBaseDir string         `json:"model-base-dir"`
+	NumReplicas  int32          `json:"num-replicas"`
+	Promoted     bool           `json:"promoted"`
+	RolledBack   bool           `json:"rolled-back"`
+	Steps        []PromoteStep  `json:"steps"`
+	Rollout      *RolloutStatus `json:"rollout,omitempty"`
+}
+
+// PromoteController starts a job rolling the current model to the new model and sending every request to it, and returns the job
+func (s *Server) PromoteController(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+
+	var promoteRequest PromoteRequest
+	err := decoder.Decode(&promoteRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	modelName, err := s.getRequestModelName(promoteRequest.ModelName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the running canary promotes the new model by itself, or resets the strategy over the promoted model when it is aborted
+	if err := s.checkCanaryNotRunning(modelName); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	// both of slots should be deployed
+	for _, isNewModel := range []bool{true, false} {
+		_, err = s.kubeClientSet.AppsV1().Deployments(getNamespace(isNewModel)).Get(r.Context(), registry.DeploymentName(modelName), metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) && isNewModel {
+				http.Error(w, "The new model is not deployed.", http.StatusBadRequest)
+			} else if errors.IsNotFound(err) {
+				http.Error(w, "The current model is not deployed.", http.StatusBadRequest)
+			} else {
+				http.Error(w, err.Error(), 500)
+			}
+			return
+		}
+	}
+
+	timeout := defaultRolloutTimeout
+	if promoteRequest.TimeoutSeconds > 0 {
+		timeout = time.Duration(promoteRequest.TimeoutSeconds) * time.Second
+	}
+	stepNames := []string{"read-new-model", "update-current-model", "wait-current-model", "set-strategy"}
+	if promoteRequest.ScaleDownCanary {
+		stepNames = append(stepNames, "scale-down-new-model")
+	}
+	deployedBy := s.requestUser(r)
+	// the promotion is run one by one with deploys and rollbacks of the current model
+	key := getNamespace(false) + "/" + modelName
+	job := s.jobs.Start("promote", key, stepNames, func(ctx context.Context, progress *jobs.Progress) (interface{}, error) {
+		promoteResponse := s.promote(ctx, progress, modelName, promoteRequest.ScaleDownCanary, timeout, deployedBy)
+		return promoteResponse, promoteResponse.err()
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// err returns the error of the first failed step, or nil when the new model is promoted
+func (p *PromoteResponse) err() error {
+	if p.Promoted {
+		return nil
+	}
+	for _, step := range p.Steps {
+		if step.Error != "" {
+			return fmt.Errorf("Promotion failed at %v: %v", step.Name, step.Error)
+		}
+	}
+	return fmt.Errorf("Promotion is canceled.")
+}
+
+// promote runs the promotion steps and records the result of each step
+// The steps are also recorded to progress of the job unless it is nil.
+// The current model is rolled back when it doesn't become ready in time.
+func (s *Server) promote(ctx context.Context, progress *jobs.Progress, modelName string, scaleDownCanary bool, timeout time.Duration, deployedBy string) *PromoteResponse {
+	promoteResponse := &PromoteResponse{
+		ModelName: modelName,
+		Steps:     []PromoteStep{},
+	}
+	record := func(name string, step func() (string, error)) (string, error) {
+		message, err := step()
+		if err != nil {
+			log.Printf("Promotion step %v failed: %v", name, err)
+			promoteResponse.Steps = append(promoteResponse.Steps, PromoteStep{Name: name, Error: err.Error()})
+			return "", err
+		}
+		promoteResponse.Steps = append(promoteResponse.Steps, PromoteStep{Name: name, Message: message})
+		return message, nil
+	}
+	run := func(name string, step func() (string, error)) error {
+		if progress == nil {
+			_, err := record(name, step)
+			return err
+		}
+		return progress.Run(name, func() (string, error) {
+			return record(name, step)
+		})
+	}
+
+	deploymentName := registry.DeploymentName(modelName)
+	var canaryDeployment, prodDeployment *appsv1.Deployment
+	var numReplicas int32
+	err := run("read-new-model", func() (string, error) {
+		var err error
+		canaryDeployment, err = s.kubeClientSet.AppsV1().Deployments(getNamespace(true)).Get(ctx, deploymentName, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		prodDeployment, err = s.kubeClientSet.AppsV1().Deployments(getNamespace(false)).Get(ctx, deploymentName, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+
+		numReplicas = *prodDeployment.Spec.Replicas
+		// keep the replicas of the current model when the new model is scaled down already
+		if canaryDeployment.Spec.Replicas != nil && *canaryDeployment.Spec.Replicas > 0 {
+			numReplicas = *canaryDeployment.Spec.Replicas
+		}
+		promoteResponse.ModelBaseDir = getModelBasePath(canaryDeployment)
+		promoteResponse.NumReplicas = numReplicas
+		return fmt.Sprintf("MODEL_BASE_PATH=%v, MODEL_NAME=%v, replicas=%d", promoteResponse.ModelBaseDir, getModelName(canaryDeployment), numReplicas), nil
+	})
+	if err != nil {
+		return promoteResponse
+	}
+	modelBaseDir := promoteResponse.ModelBaseDir
+
+	prevModelBaseDir := getModelBasePath(prodDeployment)
+	prevModelName := getModelName(prodDeployment)
+	prevNumReplicas := *prodDeployment.Spec.Replicas
+
+	err = run("update-current-model", func() (string, error) {
+		_, err := s.rollDeployment(ctx, getNamespace(false), deploymentName, Revision{
+			ModelBaseDir: modelBaseDir,
+			ModelName:    getModelName(canaryDeployment),
+			NumReplicas:  numReplicas,
+			DeployedBy:   deployedBy,
+			ChangeCause:  "promote",
+		})
+		return fmt.Sprintf("Rolling update of %v to %v", deploymentName, modelBaseDir), err
+	})
+	if err != nil {
+		return promoteResponse
+	}
+
+	err = run("wait-current-model", func() (string, error) {
+		rolloutStatus, err := s.waitForRollout(ctx, modelName, false, timeout)
+		if err == nil && rolloutStatus.State != RolloutComplete {
+			err = fmt.Errorf("%v: %v", rolloutStatus.State, rolloutStatus.Message)
+		}
+		promoteResponse.Rollout = rolloutStatus
+		return fmt.Sprintf("%d replicas are available", numReplicas), err
+	})
+	if err != nil {
+		rollback := func() (string, error) {
+			// the current model is rolled back even when the job is canceled
+			_, err := s.rollDeployment(context.Background(), getNamespace(false), deploymentName, Revision{
+				ModelBaseDir: prevModelBaseDir,
+				ModelName:    prevModelName,
+				NumReplicas:  prevNumReplicas,
+				DeployedBy:   deployedBy,
+				ChangeCause:  "rollback of failed promotion",
+			})
+			return fmt.Sprintf("Rolling update of %v to %v", deploymentName, prevModelBaseDir), err
+		}
+		if ctx.Err() == nil {
+			err = run("rollback-current-model", rollback)
+		} else {
+			_, err = record("rollback-current-model", rollback)
+		}
+		if err != nil {
+			return promoteResponse
+		}
+		promoteResponse.RolledBack = true
+		return promoteResponse
+	}
+
+	err = run("set-strategy", func() (string, error) {
+		// the routes of the new model exist only when it is deployed through the server
+		strategyStr, err := s.traffic.SetStrategy(ctx, modelName, traffic.Strategy{Strategy: constants.CurrentModelOnly})
+		if err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+		message := "Changed to strategy: " + strategyStr
+		if err != nil {
+			message = "Skipped: the new model is not routed"
+		}
+		return message, s.storeStrategy(ctx, modelName, constants.CurrentModelOnly, nil, nil)
+	})
+	if err != nil {
+		return promoteResponse
+	}
+
+	if scaleDownCanary {
+		err = run("scale-down-new-model", func() (string, error) {
+			deploymentsClient := s.kubeClientSet.AppsV1().Deployments(getNamespace(true))
+			result, err := deploymentsClient.Get(ctx, deploymentName, metav1.GetOptions{})
+			if err != nil {
+				return "", err
+			}
+			result.Spec.Replicas = int32Ptr(0)
+			_, err = deploymentsClient.Update(ctx, result, metav1.UpdateOptions{})
+			return fmt.Sprintf("Scaled %v down to 0 replicas", deploymentName), err
+		})
+		if err != nil {
+			return promoteResponse
+		}
+	}
+
+	promoteResponse.Promoted = true
+	return promoteResponse
+}
+
+// rollDeployment changes the model and replicas of Deployment to the revision and forces rolling update
+// The revision is appended to the history of Deployment.
+func (s *Server) rollDeployment(ctx context.Context, namespace string, name string, revision Revision) (Revision, error) {
+	deploymentsClient := s.kubeClientSet.AppsV1().Deployments(namespace)
+	result, err := deploymentsClient.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return revision, err
+	}
+
+	env := result.Spec.Template.Spec.Containers[0].Env
+	for i := range env {
+		if env[i].Name == "MODEL_BASE_PATH" {
+			env[i].Value = revision.ModelBaseDir
+		} else if env[i].Name == "MODEL_NAME" {
+			env[i].Value = revision.ModelName
+		}
+	}
+	result.Spec.Replicas = int32Ptr(revision.NumReplicas)
+	forceRollingUpdate(result)
+	revision = recordRevision(result, revision)
+
+	_, err = deploymentsClient.Update(ctx, result, metav1.UpdateOptions{})
+	return revision, err
+}
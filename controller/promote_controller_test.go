@@ -0,0 +1,261 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/jobs"
+	"github.com/josh9191/mini-mnist-serving/registry"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func startPromote(t *testing.T, s *Server, promoteReqBody map[string]interface{}) (*http.Response, jobs.Job) {
+	body, _ := json.Marshal(promoteReqBody)
+
+	r, err := http.NewRequest("POST", "/model:promote", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	handler := http.HandlerFunc(s.PromoteController)
+	handler.ServeHTTP(w, r)
+
+	var job jobs.Job
+	json.NewDecoder(w.Result().Body).Decode(&job)
+	return w.Result(), job
+}
+
+// promoteModel runs the promote job and returns the finished job with its result
+func promoteModel(t *testing.T, s *Server, promoteReqBody map[string]interface{}) (jobs.Job, *PromoteResponse) {
+	resp, job := startPromote(t, s, promoteReqBody)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+	job, err := s.jobs.Wait(context.TODO(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	promoteResponse, ok := job.Result.(*PromoteResponse)
+	if !ok {
+		t.Fatalf("Wrong result: %+v", job)
+	}
+	return job, promoteResponse
+}
+
+// deployBothSlots deploys the current and new models of mnist-cnn with canary strategy
+func deployBothSlots(t *testing.T, s *Server) {
+	credsFilePath := writeTestCredsFile(t)
+	for _, deployReqBody := range []map[string]interface{}{
+		{"model-base-dir": "gs://my-bucket/v1", "model-name": "mnist-cnn", "is-new-model": false, "num-replicas": 1},
+		{"model-base-dir": "gs://my-bucket/v2", "model-name": "mnist-cnn", "is-new-model": true, "num-replicas": 2},
+	} {
+		job := deployModel(t, s, credsFilePath, deployReqBody)
+		if job.State != jobs.Succeeded {
+			t.Fatalf("Error - Job: %+v", job)
+		}
+	}
+	resp := setStrategy(t, s, map[string]interface{}{"strategy": constants.Canary, "weight": 30})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+}
+
+// rollOutDeployments makes created or updated Deployments of the namespace available at once
+func rollOutDeployments(kubeClientSet *fake.Clientset, namespace string) {
+	rollOut := func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() == namespace {
+			deployment := action.(k8stesting.CreateAction).GetObject().(*appsv1.Deployment)
+			deployment.Status.Replicas = *deployment.Spec.Replicas
+			deployment.Status.UpdatedReplicas = *deployment.Spec.Replicas
+			deployment.Status.ReadyReplicas = *deployment.Spec.Replicas
+			deployment.Status.AvailableReplicas = *deployment.Spec.Replicas
+		}
+		return false, nil, nil
+	}
+	kubeClientSet.PrependReactor("create", "deployments", rollOut)
+	kubeClientSet.PrependReactor("update", "deployments", rollOut)
+}
+
+func TestPromoteController(t *testing.T) {
+	s, kubeClientSet := newTestServer()
+	deployBothSlots(t, s)
+	rollOutDeployments(kubeClientSet, constants.ProdNamespace)
+
+	job, promoteResponse := promoteModel(t, s, map[string]interface{}{"model-name": "mnist-cnn", "scale-down-canary": true})
+	if job.State != jobs.Succeeded || job.Key != "mnist-prod/mnist-cnn" {
+		t.Fatalf("Error - Job: %+v", job)
+	}
+	if !promoteResponse.Promoted || promoteResponse.RolledBack {
+		t.Errorf("Wrong result: %+v", promoteResponse)
+	}
+	// the job runs the steps of promotion
+	wantSteps := []string{"read-new-model", "update-current-model", "wait-current-model", "set-strategy", "scale-down-new-model"}
+	if len(promoteResponse.Steps) != len(wantSteps) || len(job.Steps) != len(wantSteps) {
+		t.Fatalf("Wrong steps: %+v, %+v", promoteResponse.Steps, job.Steps)
+	}
+	for i := range wantSteps {
+		if promoteResponse.Steps[i].Name != wantSteps[i] || job.Steps[i].Name != wantSteps[i] || job.Steps[i].State != jobs.Succeeded {
+			t.Errorf("Wrong steps: %+v, %+v", promoteResponse.Steps, job.Steps)
+		}
+	}
+
+	prodDeployment, err := kubeClientSet.AppsV1().Deployments(constants.ProdNamespace).Get(context.TODO(), registry.DeploymentName("mnist-cnn"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if getModelBasePath(prodDeployment) != "gs://my-bucket/v2" || *prodDeployment.Spec.Replicas != 2 {
+		t.Errorf("Current model is not promoted: %v, %d replicas", getModelBasePath(prodDeployment), *prodDeployment.Spec.Replicas)
+	}
+
+	canaryDeployment, err := kubeClientSet.AppsV1().Deployments(constants.CanaryNamespace).Get(context.TODO(), registry.DeploymentName("mnist-cnn"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *canaryDeployment.Spec.Replicas != 0 {
+		t.Errorf("New model is not scaled down: %d replicas", *canaryDeployment.Spec.Replicas)
+	}
+
+	ingress, err := s.ingressClient.Get(context.TODO(), constants.CanaryNamespace, registry.IngressName("mnist-cnn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingress.Annotations["nginx.ingress.kubernetes.io/canary"] != "false" {
+		t.Errorf("Strategy is not changed: %v", ingress.Annotations)
+	}
+}
+
+func TestPromoteControllerRollback(t *testing.T) {
+	// the current model never becomes ready
+	s, kubeClientSet := newTestServer()
+	deployBothSlots(t, s)
+
+	job, promoteResponse := promoteModel(t, s, map[string]interface{}{"model-name": "mnist-cnn", "scale-down-canary": true, "timeout-seconds": 1})
+	if job.State != jobs.Failed || !strings.Contains(job.Error, "wait-current-model") {
+		t.Fatalf("Wrong job: %+v", job)
+	}
+	if promoteResponse.Promoted || !promoteResponse.RolledBack {
+		t.Errorf("Wrong result: %+v", promoteResponse)
+	}
+	lastStep := promoteResponse.Steps[len(promoteResponse.Steps)-1]
+	if lastStep.Name != "rollback-current-model" || lastStep.Error != "" {
+		t.Errorf("Wrong last step: %+v", lastStep)
+	}
+	lastJobStep := job.Steps[len(job.Steps)-1]
+	if lastJobStep.Name != "rollback-current-model" || lastJobStep.State != jobs.Succeeded {
+		t.Errorf("Wrong last step of job: %+v", job.Steps)
+	}
+
+	prodDeployment, err := kubeClientSet.AppsV1().Deployments(constants.ProdNamespace).Get(context.TODO(), registry.DeploymentName("mnist-cnn"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if getModelBasePath(prodDeployment) != "gs://my-bucket/v1" || *prodDeployment.Spec.Replicas != 1 {
+		t.Errorf("Current model is not rolled back: %v, %d replicas", getModelBasePath(prodDeployment), *prodDeployment.Spec.Replicas)
+	}
+
+	// the strategy and the new model are kept
+	ingress, err := s.ingressClient.Get(context.TODO(), constants.CanaryNamespace, registry.IngressName("mnist-cnn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingress.Annotations["nginx.ingress.kubernetes.io/canary-weight"] != "30" {
+		t.Errorf("Strategy is changed: %v", ingress.Annotations)
+	}
+	canaryDeployment, err := kubeClientSet.AppsV1().Deployments(constants.CanaryNamespace).Get(context.TODO(), registry.DeploymentName("mnist-cnn"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *canaryDeployment.Spec.Replicas != 2 {
+		t.Errorf("New model is scaled down: %d replicas", *canaryDeployment.Spec.Replicas)
+	}
+}
+
+func TestPromoteControllerInvalidRequest(t *testing.T) {
+	s, _ := newTestServer()
+	credsFilePath := writeTestCredsFile(t)
+	job := deployModel(t, s, credsFilePath, map[string]interface{}{
+		"model-base-dir": "gs://my-bucket/v1",
+		"model-name":     "mnist-cnn",
+		"is-new-model":   false,
+		"num-replicas":   1,
+	})
+	if job.State != jobs.Succeeded {
+		t.Fatalf("Error - Job: %+v", job)
+	}
+
+	// new model is not deployed
+	resp, _ := startPromote(t, s, map[string]interface{}{"model-name": "mnist-cnn"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Status Code: %d", resp.StatusCode)
+	}
+
+	resp, _ = startPromote(t, s, map[string]interface{}{"model-name": "mnist-rnn"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Unknown model: Status Code: %d", resp.StatusCode)
+	}
+}
+
+func TestPromoteControllerCanaryRunning(t *testing.T) {
+	s, _ := newTestServer()
+	deployBothSlots(t, s)
+	s.canaries["mnist-cnn"] = &CanaryAnalysis{JobID: "0123456789abcdef", ModelName: "mnist-cnn", State: CanaryRunning}
+
+	resp, _ := startPromote(t, s, map[string]interface{}{"model-name": "mnist-cnn"})
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Promote while canary is running: Status Code: %d", resp.StatusCode)
+	}
+}
+
+func TestPromoteControllerCancel(t *testing.T) {
+	// the current model never becomes ready
+	s, kubeClientSet := newTestServer()
+	deployBothSlots(t, s)
+
+	resp, job := startPromote(t, s, map[string]interface{}{"model-name": "mnist-cnn", "timeout-seconds": 60})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+	// cancel the job waiting for the rollout
+	for {
+		job, _ = s.jobs.Get(job.ID)
+		if job.Steps[2].State == jobs.Running {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := s.jobs.Cancel(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	job, err := s.jobs.Wait(context.TODO(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != jobs.Canceled {
+		t.Errorf("Wrong job: %+v", job)
+	}
+
+	// the current model is rolled back even when the job is canceled
+	prodDeployment, err := kubeClientSet.AppsV1().Deployments(constants.ProdNamespace).Get(context.TODO(), registry.DeploymentName("mnist-cnn"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if getModelBasePath(prodDeployment) != "gs://my-bucket/v1" {
+		t.Errorf("Current model is not rolled back: %v", getModelBasePath(prodDeployment))
+	}
+	if !job.Result.(*PromoteResponse).RolledBack {
+		t.Errorf("Wrong result: %+v", job.Result)
+	}
+}
@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/josh9191/mini-mnist-serving/clients"
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/jobs"
+	"github.com/josh9191/mini-mnist-serving/rbac"
+	"github.com/josh9191/mini-mnist-serving/traffic"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// TestPolicyRules checks that the generated RBAC rules grant exactly the API calls made by controllers
+func TestPolicyRules(t *testing.T) {
+	used := make(map[string]bool)
+	// the failing Pod makes controllers report its logs
+	objects := []runtime.Object{crashLoopingPod(constants.CanaryNamespace, "model")}
+	callBackends := []func() []k8stesting.Action{}
+	// both of Ingress API versions are used depending on the cluster
+	for _, newIngressClient := range []func(kubernetes.Interface) clients.IngressClient{
+		clients.NewNetworkingV1IngressClient,
+		clients.NewExtensionsV1beta1IngressClient,
+	} {
+		newIngressClient := newIngressClient
+		callBackends = append(callBackends, func() []k8stesting.Action {
+			s, kubeClientSet := newTestServerWithIngressClient(newIngressClient, objects...)
+			callControllers(t, s)
+			return kubeClientSet.Actions()
+		})
+	}
+	// the other traffic backends manage their objects through the dynamic client
+	for _, newBackend := range []func(dynamic.Interface) (traffic.Backend, error){
+		func(client dynamic.Interface) (traffic.Backend, error) {
+			return traffic.NewGatewayBackend(client, "gateway-ns/mnist-gateway")
+		},
+		func(client dynamic.Interface) (traffic.Backend, error) {
+			return traffic.NewIstioBackend(client, "istio-system/mnist-gateway")
+		},
+	} {
+		newBackend := newBackend
+		callBackends = append(callBackends, func() []k8stesting.Action {
+			s, kubeClientSet := newTestServer(objects...)
+			dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+			backend, err := newBackend(dynamicClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.SetTrafficBackend(backend)
+			callControllers(t, s)
+			return append(kubeClientSet.Actions(), dynamicClient.Actions()...)
+		})
+	}
+
+	for _, callBackend := range callBackends {
+		for _, action := range callBackend() {
+			group := action.GetResource().Group
+			resource := action.GetResource().Resource
+			if action.GetSubresource() != "" {
+				resource += "/" + action.GetSubresource()
+			}
+			rules := rbac.NamespaceRules
+			if action.GetNamespace() == "" {
+				rules = rbac.ClusterRules
+			}
+			if !rbac.Allows(rules, group, resource, action.GetVerb()) {
+				t.Errorf("%v %v.%v is not allowed by RBAC rules", action.GetVerb(), resource, group)
+			}
+			used[group+"/"+resource+"/"+action.GetVerb()] = true
+		}
+	}
+
+	// every granted verb should be used by controllers
+	for _, rules := range [][]rbacv1.PolicyRule{rbac.ClusterRules, rbac.NamespaceRules} {
+		for _, rule := range rules {
+			for _, group := range rule.APIGroups {
+				for _, resource := range rule.Resources {
+					for _, verb := range rule.Verbs {
+						if !used[group+"/"+resource+"/"+verb] {
+							t.Errorf("%v %v.%v is granted but never used", verb, resource, group)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// callControllers calls every controller accessing the cluster
+func callControllers(t *testing.T, s *Server) {
+	credsFilePath := writeTestCredsFile(t)
+	// the informers list and watch the objects read by the status
+	enableTestCache(t, s)
+
+	// deploy twice to exercise both of create and update paths
+	for i := 0; i < 2; i++ {
+		for _, isNewModel := range []bool{false, true} {
+			job := deployModel(t, s, credsFilePath, map[string]interface{}{
+				"model-base-dir": "gs://my-bucket/mnist/model/1",
+				"model-name":     "model",
+				"is-new-model":   isNewModel,
+				"num-replicas":   1,
+			})
+			if job.State != jobs.Succeeded {
+				t.Fatalf("Error - Job: %+v", job)
+			}
+		}
+	}
+	job := deployModel(t, s, credsFilePath, map[string]interface{}{
+		"model-base-dir": "gs://my-bucket/mnist/model/1",
+		"model-name":     "model",
+		"is-new-model":   true,
+		"num-replicas":   1,
+		"wait":           true,
+	})
+	if job.State != jobs.Failed {
+		t.Fatalf("Rollout of crash-looping model: Job: %+v", job)
+	}
+	resp := setStrategy(t, s, map[string]interface{}{"strategy": constants.Canary, "weight": 10})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+	renderRoot(t, s)
+	// the stream is closed right after opening the watches
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.ModelEventsController(httptest.NewRecorder(), httptest.NewRequest("GET", "/model/events?model-name=model", nil).WithContext(ctx))
+
+	// register, deploy and delete another model
+	for _, handler := range []struct {
+		method  string
+		target  string
+		body    string
+		handler http.HandlerFunc
+	}{
+		{"POST", "/models", `{"name": "other"}`, s.CreateModelController},
+		{"GET", "/models", "", s.ListModelsController},
+		{"POST", "/model:deploy", `{"model-base-dir": "gs://my-bucket/mnist", "model-name": "other", "num-replicas": 1}`, s.DeployControllerWrapper(credsFilePath, testIngressHost)},
+		{"DELETE", "/models/other", "", s.DeleteModelController},
+	} {
+		r := httptest.NewRequest(handler.method, handler.target, strings.NewReader(handler.body))
+		r = mux.SetURLVars(r, map[string]string{"name": "other"})
+		w := httptest.NewRecorder()
+		handler.handler.ServeHTTP(w, r)
+		if w.Code >= 300 {
+			t.Fatalf("%v %v: Status Code: %d", handler.method, handler.target, w.Code)
+		}
+	}
+
+	// undeploy the last model to delete the secrets
+	for _, isNewModel := range []bool{true, false} {
+		undeployModel(t, s, map[string]interface{}{"model-name": "model", "is-new-model": isNewModel})
+	}
+}
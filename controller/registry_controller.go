@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/josh9191/mini-mnist-serving/jobs"
+	"github.com/josh9191/mini-mnist-serving/registry"
+)
+
+// CreateModelRequest stores model registration request JSON data
+type CreateModelRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ListModelsController returns registered models
+func (s *Server) ListModelsController(w http.ResponseWriter, r *http.Request) {
+	models, err := s.registry.List(context.TODO())
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models)
+}
+
+// CreateModelController registers model
+func (s *Server) CreateModelController(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+
+	var createModelRequest CreateModelRequest
+	err := decoder.Decode(&createModelRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = registry.ValidateName(createModelRequest.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the registry is stored in the production namespace
+	err = s.createNamespaces(context.TODO())
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	model := &registry.Model{
+		Name:        createModelRequest.Name,
+		Description: createModelRequest.Description,
+	}
+	err = s.registry.Create(context.TODO(), model)
+	if err != nil {
+		if err == registry.ErrAlreadyExists {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else {
+			http.Error(w, err.Error(), 500)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(model)
+}
+
+// DeleteModelController removes the objects of both slots and unregisters model
+func (s *Server) DeleteModelController(w http.ResponseWriter, r *http.Request) {
+	modelName := mux.Vars(r)["name"]
+
+	_, err := s.registry.Get(context.TODO(), modelName)
+	if err != nil {
+		if err == registry.ErrNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), 500)
+		}
+		return
+	}
+
+	// the running canary routes predictions to both slots
+	if err := s.checkCanaryNotRunning(modelName); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	// remove the new model first so that canary requests never reach the deleted current model
+	// The undeploy jobs run after the running jobs of each slot.
+	for _, isNewModel := range []bool{true, false} {
+		job := s.startUndeploy(modelName, isNewModel)
+		job, err = s.jobs.Wait(r.Context(), job.ID)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if job.State != jobs.Succeeded {
+			http.Error(w, fmt.Sprintf("Undeploy job %v is %v: %v", job.ID, job.State, job.Error), 500)
+			return
+		}
+	}
+
+	err = s.registry.Delete(context.TODO(), modelName)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	fmt.Fprintf(w, "Deleted: %v\n", modelName)
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/jobs"
+	"github.com/josh9191/mini-mnist-serving/registry"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func callRegistryController(s *Server, method string, target string, body string, vars map[string]string) *http.Response {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if vars != nil {
+		r = mux.SetURLVars(r, vars)
+	}
+
+	var handler http.HandlerFunc
+	switch method {
+	case "GET":
+		handler = s.ListModelsController
+	case "POST":
+		handler = s.CreateModelController
+	case "DELETE":
+		handler = s.DeleteModelController
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Result()
+}
+
+func TestCreateModelController(t *testing.T) {
+	s, _ := newTestServer()
+
+	resp := callRegistryController(s, "POST", "/models", `{"name": "mnist-cnn", "description": "CNN classifier"}`, nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+
+	resp = callRegistryController(s, "POST", "/models", `{"name": "mnist-cnn"}`, nil)
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Duplicated model: Status Code: %d", resp.StatusCode)
+	}
+
+	resp = callRegistryController(s, "POST", "/models", `{"name": "MNIST"}`, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Invalid model name: Status Code: %d", resp.StatusCode)
+	}
+
+	resp = callRegistryController(s, "GET", "/models", "", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+	var models []registry.Model
+	json.NewDecoder(resp.Body).Decode(&models)
+	if len(models) != 1 || models[0].Name != "mnist-cnn" || models[0].Description != "CNN classifier" {
+		t.Errorf("Wrong models: %v", models)
+	}
+}
+
+func TestDeleteModelController(t *testing.T) {
+	s, kubeClientSet := newTestServer()
+	credsFilePath := writeTestCredsFile(t)
+
+	for _, modelName := range []string{"mnist-cnn", "mnist-mlp"} {
+		for _, isNewModel := range []bool{false, true} {
+			job := deployModel(t, s, credsFilePath, map[string]interface{}{
+				"model-base-dir": "gs://my-bucket/classifiers",
+				"model-name":     modelName,
+				"is-new-model":   isNewModel,
+				"num-replicas":   1,
+			})
+			if job.State != jobs.Succeeded {
+				t.Fatalf("Error - Job: %+v", job)
+			}
+		}
+	}
+
+	resp := callRegistryController(s, "DELETE", "/models/mnist-cnn", "", map[string]string{"name": "mnist-cnn"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+
+	for _, namespace := range []string{constants.ProdNamespace, constants.CanaryNamespace} {
+		_, err := kubeClientSet.AppsV1().Deployments(namespace).Get(context.TODO(), registry.DeploymentName("mnist-cnn"), metav1.GetOptions{})
+		if !errors.IsNotFound(err) {
+			t.Errorf("Deployment is not deleted in %v: %v", namespace, err)
+		}
+		_, err = kubeClientSet.CoreV1().Services(namespace).Get(context.TODO(), registry.ServiceName("mnist-cnn"), metav1.GetOptions{})
+		if !errors.IsNotFound(err) {
+			t.Errorf("Service is not deleted in %v: %v", namespace, err)
+		}
+		_, err = s.ingressClient.Get(context.TODO(), namespace, registry.IngressName("mnist-cnn"))
+		if !errors.IsNotFound(err) {
+			t.Errorf("Ingress is not deleted in %v: %v", namespace, err)
+		}
+
+		// the other model is kept
+		_, err = kubeClientSet.AppsV1().Deployments(namespace).Get(context.TODO(), registry.DeploymentName("mnist-mlp"), metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("Deployment of other model is deleted in %v: %v", namespace, err)
+		}
+	}
+
+	if _, err := s.registry.Get(context.TODO(), "mnist-cnn"); err != registry.ErrNotFound {
+		t.Errorf("Model is not unregistered: %v", err)
+	}
+
+	resp = callRegistryController(s, "DELETE", "/models/mnist-cnn", "", map[string]string{"name": "mnist-cnn"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Unknown model: Status Code: %d", resp.StatusCode)
+	}
+}
+
+func TestDeleteModelControllerCanaryRunning(t *testing.T) {
+	s, _ := newTestServer()
+	deployBothSlots(t, s)
+	s.canaries["mnist-cnn"] = &CanaryAnalysis{JobID: "0123456789abcdef", ModelName: "mnist-cnn", State: CanaryPending}
+
+	resp := callRegistryController(s, "DELETE", "/models/mnist-cnn", "", map[string]string{"name": "mnist-cnn"})
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Delete while canary is running: Status Code: %d", resp.StatusCode)
+	}
+	if _, err := s.registry.Get(context.TODO(), "mnist-cnn"); err != nil {
+		t.Errorf("Model is unregistered: %v", err)
+	}
+}
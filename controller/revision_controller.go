@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/jobs"
+	"github.com/josh9191/mini-mnist-serving/registry"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// revisionHistoryAnnotation is the Deployment annotation storing the revision history of slot
+const revisionHistoryAnnotation = "mini-mnist-serving/revision-history"
+
+// maxRevisionHistory is the number of revisions kept for each slot
+const maxRevisionHistory = 10
+
+// Revision stores the model deployed to a slot at some point
+type Revision struct {
+	Revision     int       `json:"revision"`
+	ModelBaseDir string    `json:"model-base-dir"`
+	ModelName    string    `json:"model-name"`
+	NumReplicas  int32     `json:"num-replicas"`
+	DeployedAt   time.Time `json:"deployed-at"`
+	DeployedBy   string    `json:"deployed-by"`
+	ChangeCause  string    `json:"change-cause"`
+}
+
+// RollbackRequest stores rollback request JSON data
+// The previous revision is restored when the revision is not specified.
+type RollbackRequest struct {
+	ModelName  string `json:"model-name"`
+	IsNewModel bool   `json:"is-new-model"`
+	Revision   int    `json:"revision,omitempty"`
+}
+
+// RollbackController starts a job restoring a revision of model slot and returns the job
+func (s *Server) RollbackController(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+
+	var rollbackRequest RollbackRequest
+	err := decoder.Decode(&rollbackRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	modelName, err := s.getRequestModelName(rollbackRequest.ModelName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	namespace := getNamespace(rollbackRequest.IsNewModel)
+	deploymentName := registry.DeploymentName(modelName)
+	deployment, err := s.kubeClientSet.AppsV1().Deployments(namespace).Get(r.Context(), deploymentName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			http.Error(w, "The model is not deployed.", http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), 500)
+		}
+		return
+	}
+	if _, err := findRollbackTarget(deployment, rollbackRequest.Revision); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deployedBy := s.requestUser(r)
+	// the rollback is run one by one with deploys and promotions of the slot
+	key := namespace + "/" + modelName
+	job := s.jobs.Start("rollback", key, []string{"read-revision", "deployment"}, func(ctx context.Context, progress *jobs.Progress) (interface{}, error) {
+		revision, err := s.rollback(ctx, progress, namespace, deploymentName, rollbackRequest.Revision, deployedBy)
+		if err != nil {
+			return nil, err
+		}
+		return revision, nil
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// rollback restores the revision of Deployment and returns the recorded revision
+// The target is read again in the job because the jobs run before it may have changed the history.
+func (s *Server) rollback(ctx context.Context, progress *jobs.Progress, namespace string, deploymentName string, targetRevision int, deployedBy string) (*Revision, error) {
+	var target *Revision
+	err := progress.Run("read-revision", func() (string, error) {
+		deployment, err := s.kubeClientSet.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		target, err = findRollbackTarget(deployment, targetRevision)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Revision %d: MODEL_BASE_PATH=%v, MODEL_NAME=%v, replicas=%d", target.Revision, target.ModelBaseDir, target.ModelName, target.NumReplicas), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var revision Revision
+	err = progress.Run("deployment", func() (string, error) {
+		revision, err = s.rollDeployment(ctx, namespace, deploymentName, Revision{
+			ModelBaseDir: target.ModelBaseDir,
+			ModelName:    target.ModelName,
+			NumReplicas:  target.NumReplicas,
+			DeployedBy:   deployedBy,
+			ChangeCause:  fmt.Sprintf("rollback to revision %d", target.Revision),
+		})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Recorded revision %d", revision.Revision), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// findRollbackTarget returns the revision in the history of Deployment, or the previous revision when it is 0
+func findRollbackTarget(deployment *appsv1.Deployment, targetRevision int) (*Revision, error) {
+	revisions := getRevisions(deployment)
+	if targetRevision == 0 {
+		if len(revisions) >= 2 {
+			return &revisions[len(revisions)-2], nil
+		}
+	} else {
+		for i := range revisions {
+			if revisions[i].Revision == targetRevision {
+				return &revisions[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("The revision is not found in the history.")
+}
+
+// getRevisions returns the revision history of Deployment from the oldest one
+func getRevisions(deployment *appsv1.Deployment) []Revision {
+	revisions := []Revision{}
+	value, ok := deployment.Annotations[revisionHistoryAnnotation]
+	if !ok {
+		return revisions
+	}
+	if err := json.Unmarshal([]byte(value), &revisions); err != nil {
+		log.Printf("Invalid revision history of %v: %v", deployment.Name, err)
+		return []Revision{}
+	}
+	return revisions
+}
+
+// recordRevision appends the revision to the history of Deployment and returns the recorded revision
+func recordRevision(deployment *appsv1.Deployment, revision Revision) Revision {
+	revisions := getRevisions(deployment)
+	revision.Revision = 1
+	if len(revisions) > 0 {
+		revision.Revision = revisions[len(revisions)-1].Revision + 1
+	}
+	revision.DeployedAt = time.Now().UTC()
+
+	revisions = append(revisions, revision)
+	if len(revisions) > maxRevisionHistory {
+		revisions = revisions[len(revisions)-maxRevisionHistory:]
+	}
+
+	value, _ := json.Marshal(revisions)
+	if deployment.Annotations == nil {
+		deployment.Annotations = make(map[string]string)
+	}
+	deployment.Annotations[revisionHistoryAnnotation] = string(value)
+	return revision
+}
+
+// EnableTrustedProxyUser makes the server record the user set by the authenticating proxy in front of it
+// Any client can set the headers, so they are ignored unless every request passes the proxy.
+func (s *Server) EnableTrustedProxyUser() {
+	s.trustedProxyUser = true
+}
+
+// requestUser returns the user set by authenticating proxy or the client address
+func (s *Server) requestUser(r *http.Request) string {
+	if s.trustedProxyUser {
+		for _, header := range []string{"X-Forwarded-User", "X-Remote-User"} {
+			if user := r.Header.Get(header); user != "" {
+				return user
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
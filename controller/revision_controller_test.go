@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/jobs"
+	"github.com/josh9191/mini-mnist-serving/registry"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func startRollback(t *testing.T, s *Server, rollbackReqBody map[string]interface{}) (*http.Response, jobs.Job) {
+	body, _ := json.Marshal(rollbackReqBody)
+
+	r, err := http.NewRequest("POST", "/model:rollback", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("X-Forwarded-User", "alice")
+
+	w := httptest.NewRecorder()
+	handler := http.HandlerFunc(s.RollbackController)
+	handler.ServeHTTP(w, r)
+
+	var job jobs.Job
+	json.NewDecoder(w.Result().Body).Decode(&job)
+	return w.Result(), job
+}
+
+// rollbackModel runs the rollback job and returns the recorded revision
+func rollbackModel(t *testing.T, s *Server, rollbackReqBody map[string]interface{}) *Revision {
+	resp, job := startRollback(t, s, rollbackReqBody)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+	job, err := s.jobs.Wait(context.TODO(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revision, ok := job.Result.(*Revision)
+	if job.State != jobs.Succeeded || !ok {
+		t.Fatalf("Error - Job: %+v", job)
+	}
+	return revision
+}
+
+func getProdDeployment(t *testing.T, s *Server, modelName string) *appsv1.Deployment {
+	deployment, err := s.kubeClientSet.AppsV1().Deployments(constants.ProdNamespace).Get(context.TODO(), registry.DeploymentName(modelName), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deployment
+}
+
+func TestRollbackController(t *testing.T) {
+	s, _ := newTestServer()
+	s.EnableTrustedProxyUser()
+	credsFilePath := writeTestCredsFile(t)
+
+	for i, modelBaseDir := range []string{"gs://my-bucket/v1", "gs://my-bucket/v2", "gs://my-bucket/v3"} {
+		job := deployModel(t, s, credsFilePath, map[string]interface{}{
+			"model-base-dir": modelBaseDir,
+			"model-name":     "mnist-cnn",
+			"is-new-model":   false,
+			"num-replicas":   i + 1,
+		})
+		if job.State != jobs.Succeeded {
+			t.Fatalf("Error - Job: %+v", job)
+		}
+	}
+	revisions := getRevisions(getProdDeployment(t, s, "mnist-cnn"))
+	if len(revisions) != 3 || revisions[2].Revision != 3 || revisions[2].ModelBaseDir != "gs://my-bucket/v3" || revisions[2].ChangeCause != "deploy" {
+		t.Fatalf("Wrong revisions: %+v", revisions)
+	}
+
+	// the previous revision
+	revision := rollbackModel(t, s, map[string]interface{}{"model-name": "mnist-cnn"})
+	if revision.Revision != 4 || revision.ModelBaseDir != "gs://my-bucket/v2" || revision.DeployedBy != "alice" {
+		t.Errorf("Wrong revision: %+v", revision)
+	}
+
+	revision = rollbackModel(t, s, map[string]interface{}{"model-name": "mnist-cnn", "revision": 1})
+	if revision.ChangeCause != "rollback to revision 1" {
+		t.Errorf("Wrong change cause: %v", revision.ChangeCause)
+	}
+	deployment := getProdDeployment(t, s, "mnist-cnn")
+	if getModelBasePath(deployment) != "gs://my-bucket/v1" || *deployment.Spec.Replicas != 1 {
+		t.Errorf("Revision is not restored: %v, %d replicas", getModelBasePath(deployment), *deployment.Spec.Replicas)
+	}
+
+	// unknown revision
+	resp, _ := startRollback(t, s, map[string]interface{}{"model-name": "mnist-cnn", "revision": 42})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Unknown revision: Status Code: %d", resp.StatusCode)
+	}
+	// new model is not deployed
+	resp, _ = startRollback(t, s, map[string]interface{}{"model-name": "mnist-cnn", "is-new-model": true})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Undeployed slot: Status Code: %d", resp.StatusCode)
+	}
+}
+
+func TestRollbackControllerAfterDeploy(t *testing.T) {
+	s, _ := newTestServer()
+	credsFilePath := writeTestCredsFile(t)
+	for _, modelBaseDir := range []string{"gs://my-bucket/v1", "gs://my-bucket/v2"} {
+		job := deployModel(t, s, credsFilePath, map[string]interface{}{
+			"model-base-dir": modelBaseDir,
+			"model-name":     "mnist-cnn",
+			"is-new-model":   false,
+			"num-replicas":   1,
+		})
+		if job.State != jobs.Succeeded {
+			t.Fatalf("Error - Job: %+v", job)
+		}
+	}
+
+	// the job of the current model slot is running
+	release := make(chan struct{})
+	deployJob := s.jobs.Start("deploy", constants.ProdNamespace+"/mnist-cnn", nil, func(ctx context.Context, progress *jobs.Progress) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	resp, job := startRollback(t, s, map[string]interface{}{"model-name": "mnist-cnn"})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+	if job.Kind != "rollback" || job.Key != deployJob.Key {
+		t.Errorf("Wrong job: %+v", job)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if pending, _ := s.jobs.Get(job.ID); pending.State != jobs.Pending {
+		t.Errorf("Rollback runs with the deploy: %+v", pending)
+	}
+	if revisions := getRevisions(getProdDeployment(t, s, "mnist-cnn")); len(revisions) != 2 {
+		t.Errorf("Rollback runs before the deploy: %+v", revisions)
+	}
+
+	close(release)
+	job, err := s.jobs.Wait(context.TODO(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != jobs.Succeeded {
+		t.Fatalf("Error - Job: %+v", job)
+	}
+	if deployment := getProdDeployment(t, s, "mnist-cnn"); getModelBasePath(deployment) != "gs://my-bucket/v1" {
+		t.Errorf("Revision is not restored: %v", getModelBasePath(deployment))
+	}
+}
+
+func TestRecordRevision(t *testing.T) {
+	deployment := &appsv1.Deployment{}
+	for i := 0; i < maxRevisionHistory+5; i++ {
+		recordRevision(deployment, Revision{ModelBaseDir: "gs://my-bucket/mnist"})
+	}
+
+	revisions := getRevisions(deployment)
+	if len(revisions) != maxRevisionHistory {
+		t.Fatalf("%d revisions are kept, want %d", len(revisions), maxRevisionHistory)
+	}
+	if revisions[0].Revision != 6 || revisions[maxRevisionHistory-1].Revision != maxRevisionHistory+5 {
+		t.Errorf("Wrong revisions: %d .. %d", revisions[0].Revision, revisions[maxRevisionHistory-1].Revision)
+	}
+}
+
+func TestRequestUser(t *testing.T) {
+	s, _ := newTestServer()
+	r := httptest.NewRequest("POST", "/model:deploy", strings.NewReader(""))
+	if user := s.requestUser(r); user != "192.0.2.1" {
+		t.Errorf("Wrong user: %v", user)
+	}
+	// the header is ignored without the authenticating proxy
+	r.Header.Set("X-Remote-User", "bob")
+	if user := s.requestUser(r); user != "192.0.2.1" {
+		t.Errorf("Wrong user without trusted proxy: %v", user)
+	}
+
+	s.EnableTrustedProxyUser()
+	if user := s.requestUser(r); user != "bob" {
+		t.Errorf("Wrong user: %v", user)
+	}
+}
@@ -0,0 +1,322 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/registry"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// RolloutProgressing means Pods of the latest template are not available yet
+	RolloutProgressing = "Progressing"
+	// RolloutComplete means every replica of the latest template is available
+	RolloutComplete = "Complete"
+	// RolloutFailed means Pods can't become available without fixing the deployment
+	RolloutFailed = "Failed"
+	// RolloutTimedOut means the rollout is not complete in time
+	RolloutTimedOut = "TimedOut"
+)
+
+// defaultRolloutTimeout is the time to wait for the model to become ready
+const defaultRolloutTimeout = 5 * time.Minute
+
+// rolloutResyncInterval is the interval of checking the rollout when no watch event is received
+var rolloutResyncInterval = 10 * time.Second
+
+// podLogTailLines is the number of Tensorflow Serving log lines reported for failing Pods
+const podLogTailLines int64 = 20
+
+// podFailureReasons are the container waiting reasons which are not resolved without fixing the deployment
+var podFailureReasons = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+}
+
+// RolloutStatus stores the rollout progress of model slot
+type RolloutStatus struct {
+	ModelName         string      `json:"model-name"`
+	IsNewModel        bool        `json:"is-new-model"`
+	State             string      `json:"state"`
+	Message           string      `json:"message,omitempty"`
+	Replicas          int32       `json:"replicas"`
+	UpdatedReplicas   int32       `json:"updated-replicas"`
+	ReadyReplicas     int32       `json:"ready-replicas"`
+	AvailableReplicas int32       `json:"available-replicas"`
+	Pods              []PodStatus `json:"pods"`
+}
+
+// PodStatus stores the status of Pod serving the model
+type PodStatus struct {
+	Name         string `json:"name"`
+	Phase        string `json:"phase"`
+	Ready        bool   `json:"ready"`
+	RestartCount int32  `json:"restart-count"`
+	Reason       string `json:"reason,omitempty"`
+	Message      string `json:"message,omitempty"`
+	Logs         string `json:"logs,omitempty"`
+}
+
+// RolloutStatusController returns the rollout progress of model slot
+func (s *Server) RolloutStatusController(w http.ResponseWriter, r *http.Request) {
+	modelName, err := s.getRequestModelName(r.URL.Query().Get("model-name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	isNewModel, _ := strconv.ParseBool(r.URL.Query().Get("is-new-model"))
+
+	rolloutStatus, err := s.getRolloutStatus(r.Context(), modelName, isNewModel, true)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			http.Error(w, "The model is not deployed.", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), 500)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rolloutStatus)
+}
+
+// waitForRollout watches the Deployment and Pods of model slot until the rollout is complete or failed
+// The logs of failing Pods are reported when the rollout is not complete.
+func (s *Server) waitForRollout(ctx context.Context, modelName string, isNewModel bool, timeout time.Duration) (*RolloutStatus, error) {
+	namespace := getNamespace(isNewModel)
+	watchCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// watch before checking the status so that no change is missed
+	deploymentWatcher, err := s.kubeClientSet.AppsV1().Deployments(namespace).Watch(watchCtx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", registry.DeploymentName(modelName)).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer deploymentWatcher.Stop()
+	podWatcher, err := s.kubeClientSet.CoreV1().Pods(namespace).Watch(watchCtx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(registry.Labels(modelName)).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer podWatcher.Stop()
+
+	deploymentEvents := deploymentWatcher.ResultChan()
+	podEvents := podWatcher.ResultChan()
+	for {
+		rolloutStatus, err := s.getRolloutStatus(watchCtx, modelName, isNewModel, false)
+		if err != nil && watchCtx.Err() == nil {
+			return nil, err
+		}
+		if err == nil && rolloutStatus.State != RolloutProgressing {
+			break
+		}
+
+		select {
+		case _, ok := <-deploymentEvents:
+			if !ok {
+				deploymentEvents = nil
+			}
+		case _, ok := <-podEvents:
+			if !ok {
+				podEvents = nil
+			}
+		case <-time.After(rolloutResyncInterval):
+		case <-watchCtx.Done():
+		}
+		if watchCtx.Err() != nil {
+			break
+		}
+	}
+
+	// report with the logs of failing Pods
+	rolloutStatus, err := s.getRolloutStatus(ctx, modelName, isNewModel, true)
+	if err != nil {
+		return nil, err
+	}
+	if rolloutStatus.State == RolloutProgressing {
+		rolloutStatus.State = RolloutTimedOut
+		rolloutStatus.Message = fmt.Sprintf("The deployment %v is not ready in %v.", registry.DeploymentName(modelName), timeout)
+	}
+	return rolloutStatus, nil
+}
+
+// getRolloutStatus returns the rollout progress of model slot
+func (s *Server) getRolloutStatus(ctx context.Context, modelName string, isNewModel bool, withLogs bool) (*RolloutStatus, error) {
+	namespace := getNamespace(isNewModel)
+	deployment, err := s.kubeClientSet.AppsV1().Deployments(namespace).Get(ctx, registry.DeploymentName(modelName), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	listOptions := metav1.ListOptions{LabelSelector: labels.SelectorFromSet(registry.Labels(modelName)).String()}
+	replicaSets, err := s.kubeClientSet.AppsV1().ReplicaSets(namespace).List(ctx, listOptions)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := s.kubeClientSet.CoreV1().Pods(namespace).List(ctx, listOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	rolloutStatus := newRolloutStatus(modelName, isNewModel, deployment, replicaSetPointers(replicaSets.Items), podPointers(pods.Items))
+	if withLogs && rolloutStatus.State != RolloutComplete {
+		for i := range rolloutStatus.Pods {
+			podStatus := &rolloutStatus.Pods[i]
+			if podStatus.Reason == "" || (podStatus.Phase == string(apiv1.PodPending) && podStatus.RestartCount == 0) {
+				continue
+			}
+			// the logs of crash-looping Pod are left by the previous container
+			logs, err := s.kubeClientSet.CoreV1().Pods(namespace).GetLogs(podStatus.Name, &apiv1.PodLogOptions{
+				Container: "tensorflow-serving",
+				TailLines: int64Ptr(podLogTailLines),
+				Previous:  podStatus.Reason == "CrashLoopBackOff",
+			}).DoRaw(ctx)
+			if err != nil {
+				podStatus.Logs = fmt.Sprintf("Error getting logs: %v", err)
+			} else {
+				podStatus.Logs = string(logs)
+			}
+		}
+	}
+	return rolloutStatus, nil
+}
+
+// newRolloutStatus returns the rollout progress of the deployment and Pods of model slot
+// Only Pods of the current ReplicaSet are reported, because Pods of the previous templates are being replaced.
+func newRolloutStatus(modelName string, isNewModel bool, deployment *appsv1.Deployment, replicaSets []*appsv1.ReplicaSet, pods []*apiv1.Pod) *RolloutStatus {
+	rolloutStatus := &RolloutStatus{
+		ModelName:         modelName,
+		IsNewModel:        isNewModel,
+		State:             RolloutProgressing,
+		UpdatedReplicas:   deployment.Status.UpdatedReplicas,
+		ReadyReplicas:     deployment.Status.ReadyReplicas,
+		AvailableReplicas: deployment.Status.AvailableReplicas,
+		Pods:              []PodStatus{},
+	}
+	if deployment.Spec.Replicas != nil {
+		rolloutStatus.Replicas = *deployment.Spec.Replicas
+	}
+	podTemplateHash := currentPodTemplateHash(deployment, replicaSets)
+	for _, pod := range pods {
+		if podTemplateHash == "" || pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey] != podTemplateHash {
+			continue
+		}
+		podStatus := getPodStatus(pod)
+		if podFailureReasons[podStatus.Reason] {
+			rolloutStatus.State = RolloutFailed
+			rolloutStatus.Message = fmt.Sprintf("The pod %v is failing: %v", pod.Name, podStatus.Reason)
+		}
+		rolloutStatus.Pods = append(rolloutStatus.Pods, podStatus)
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			rolloutStatus.State = RolloutFailed
+			rolloutStatus.Message = condition.Message
+		}
+	}
+	if isRolledOut(deployment) {
+		rolloutStatus.State = RolloutComplete
+		rolloutStatus.Message = ""
+	}
+	return rolloutStatus
+}
+
+// currentPodTemplateHash returns the pod-template-hash of the ReplicaSet of the current template of deployment,
+// which is empty until the deployment controller creates the ReplicaSet
+func currentPodTemplateHash(deployment *appsv1.Deployment, replicaSets []*appsv1.ReplicaSet) string {
+	for _, replicaSet := range replicaSets {
+		owner := metav1.GetControllerOf(replicaSet)
+		if owner == nil || owner.UID != deployment.UID || owner.Name != deployment.Name {
+			continue
+		}
+		// the deployment controller finds the ReplicaSet in the same way, by the template without the hash label
+		template := replicaSet.Spec.Template.DeepCopy()
+		podTemplateHash := template.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+		delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+		if podTemplateHash != "" && apiequality.Semantic.DeepEqual(*template, deployment.Spec.Template) {
+			return podTemplateHash
+		}
+	}
+	return ""
+}
+
+// getPodStatus returns the status of Pod with the reason it is not ready
+func getPodStatus(pod *apiv1.Pod) PodStatus {
+	podStatus := PodStatus{
+		Name:  pod.Name,
+		Phase: string(pod.Status.Phase),
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == apiv1.PodReady {
+			podStatus.Ready = condition.Status == apiv1.ConditionTrue
+			if !podStatus.Ready && pod.Status.Phase == apiv1.PodRunning {
+				// Tensorflow Serving doesn't listen until the model is loaded
+				podStatus.Reason = "NotReady"
+				podStatus.Message = condition.Message
+			}
+		}
+		if condition.Type == apiv1.PodScheduled && condition.Status == apiv1.ConditionFalse {
+			podStatus.Reason = condition.Reason
+			podStatus.Message = condition.Message
+		}
+	}
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		podStatus.RestartCount += containerStatus.RestartCount
+		if waiting := containerStatus.State.Waiting; waiting != nil && waiting.Reason != "ContainerCreating" {
+			podStatus.Reason = waiting.Reason
+			podStatus.Message = waiting.Message
+		} else if terminated := containerStatus.State.Terminated; terminated != nil {
+			podStatus.Reason = terminated.Reason
+			podStatus.Message = terminated.Message
+		}
+	}
+	return podStatus
+}
+
+// isRolledOut reports whether the Deployment has only available Pods of the latest template
+func isRolledOut(deployment *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	return status.ObservedGeneration >= deployment.Generation &&
+		status.UpdatedReplicas >= replicas &&
+		status.Replicas <= status.UpdatedReplicas &&
+		status.AvailableReplicas >= replicas
+}
+
+func podPointers(pods []apiv1.Pod) []*apiv1.Pod {
+	result := make([]*apiv1.Pod, len(pods))
+	for i := range pods {
+		result[i] = &pods[i]
+	}
+	return result
+}
+
+func replicaSetPointers(replicaSets []appsv1.ReplicaSet) []*appsv1.ReplicaSet {
+	result := make([]*appsv1.ReplicaSet, len(replicaSets))
+	for i := range replicaSets {
+		result[i] = &replicaSets[i]
+	}
+	return result
+}
+
+func int64Ptr(i int64) *int64 { return &i }
@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/jobs"
+	"github.com/josh9191/mini-mnist-serving/registry"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// crashLoopingPod returns Pod of the current template of the model whose Tensorflow Serving container keeps failing
+func crashLoopingPod(namespace string, modelName string) *apiv1.Pod {
+	return crashLoopingPodOfTemplate(namespace, modelName, testPodTemplateHash)
+}
+
+// crashLoopingPodOfTemplate returns crash-looping Pod of the model template whose hash is podTemplateHash
+func crashLoopingPodOfTemplate(namespace string, modelName string, podTemplateHash string) *apiv1.Pod {
+	podLabels := registry.Labels(modelName)
+	podLabels[appsv1.DefaultDeploymentUniqueLabelKey] = podTemplateHash
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      registry.DeploymentName(modelName) + "-" + podTemplateHash + "-x7k2p",
+			Namespace: namespace,
+			Labels:    podLabels,
+		},
+		Status: apiv1.PodStatus{
+			Phase: apiv1.PodRunning,
+			ContainerStatuses: []apiv1.ContainerStatus{
+				{
+					Name:         "tensorflow-serving",
+					RestartCount: 3,
+					State: apiv1.ContainerState{
+						Waiting: &apiv1.ContainerStateWaiting{
+							Reason:  "CrashLoopBackOff",
+							Message: "back-off 40s restarting failed container",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// deployAndWait deploys the new model waiting for the rollout and returns the finished job
+func deployAndWait(t *testing.T, s *Server, timeoutSeconds int) (jobs.Job, RolloutStatus) {
+	job := deployModel(t, s, writeTestCredsFile(t), map[string]interface{}{
+		"model-base-dir":  "gs://my-bucket/classifiers",
+		"model-name":      "mnist-cnn",
+		"is-new-model":    true,
+		"num-replicas":    2,
+		"wait":            true,
+		"timeout-seconds": timeoutSeconds,
+	})
+
+	var rolloutStatus RolloutStatus
+	if status, ok := job.Result.(*RolloutStatus); ok && status != nil {
+		rolloutStatus = *status
+	}
+	return job, rolloutStatus
+}
+
+func TestDeployControllerWrapperWait(t *testing.T) {
+	s, kubeClientSet := newTestServer()
+	rollOutDeployments(kubeClientSet, constants.CanaryNamespace)
+
+	job, rolloutStatus := deployAndWait(t, s, 10)
+	if job.State != jobs.Succeeded {
+		t.Fatalf("Error - Job: %+v", job)
+	}
+	if rolloutStatus.State != RolloutComplete || rolloutStatus.AvailableReplicas != 2 {
+		t.Errorf("Wrong rollout status: %+v", rolloutStatus)
+	}
+	lastStep := job.Steps[len(job.Steps)-1]
+	if lastStep.Name != "rollout" || lastStep.State != jobs.Succeeded {
+		t.Errorf("Wrong last step: %+v", lastStep)
+	}
+}
+
+func TestDeployControllerWrapperWaitFailure(t *testing.T) {
+	s, _ := newTestServer(crashLoopingPod(constants.CanaryNamespace, "mnist-cnn"))
+
+	job, rolloutStatus := deployAndWait(t, s, 10)
+	if job.State != jobs.Failed || job.Error == "" {
+		t.Fatalf("Wrong job: %+v", job)
+	}
+	if rolloutStatus.State != RolloutFailed || len(rolloutStatus.Pods) != 1 {
+		t.Fatalf("Wrong rollout status: %+v", rolloutStatus)
+	}
+	podStatus := rolloutStatus.Pods[0]
+	if podStatus.Reason != "CrashLoopBackOff" || podStatus.RestartCount != 3 {
+		t.Errorf("Wrong pod status: %+v", podStatus)
+	}
+	if podStatus.Logs != "fake logs" {
+		t.Errorf("Logs are not reported: %q", podStatus.Logs)
+	}
+
+	// Pods of other models and of the previous template are ignored
+	for _, pod := range []*apiv1.Pod{
+		crashLoopingPod(constants.CanaryNamespace, "mnist-mlp"),
+		crashLoopingPodOfTemplate(constants.CanaryNamespace, "mnist-cnn", "5d8f9c7b6a"),
+	} {
+		s, _ = newTestServer(pod)
+		job, rolloutStatus = deployAndWait(t, s, 1)
+		if job.State != jobs.Failed || rolloutStatus.State != RolloutTimedOut || len(rolloutStatus.Pods) != 0 {
+			t.Errorf("%v: wrong rollout status: %+v", pod.Name, rolloutStatus)
+		}
+	}
+}
+
+func TestRolloutStatusController(t *testing.T) {
+	s, _ := newTestServer()
+	registerModels(t, s, "mnist-cnn")
+
+	for _, test := range []struct {
+		target     string
+		statusCode int
+	}{
+		{"/model/rollout?model-name=mnist-cnn", http.StatusNotFound},
+		{"/model/rollout?model-name=mnist-rnn", http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		s.RolloutStatusController(w, httptest.NewRequest("GET", test.target, nil))
+		if w.Code != test.statusCode {
+			t.Errorf("%v: Status Code: %d", test.target, w.Code)
+		}
+	}
+
+	job := deployModel(t, s, writeTestCredsFile(t), map[string]interface{}{
+		"model-base-dir": "gs://my-bucket/classifiers",
+		"model-name":     "mnist-cnn",
+		"is-new-model":   true,
+		"num-replicas":   1,
+	})
+	if job.State != jobs.Succeeded {
+		t.Fatalf("Error - Job: %+v", job)
+	}
+	w := httptest.NewRecorder()
+	s.RolloutStatusController(w, httptest.NewRequest("GET", "/model/rollout?model-name=mnist-cnn&is-new-model=true", nil))
+	var rolloutStatus RolloutStatus
+	json.NewDecoder(w.Body).Decode(&rolloutStatus)
+	if w.Code != http.StatusOK || rolloutStatus.State != RolloutProgressing || !rolloutStatus.IsNewModel {
+		t.Errorf("Wrong rollout status: %d, %+v", w.Code, rolloutStatus)
+	}
+}
+
+func TestGetPodStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status apiv1.PodStatus
+		reason string
+		ready  bool
+	}{
+		{
+			name: "image pull failure",
+			status: apiv1.PodStatus{
+				Phase: apiv1.PodPending,
+				ContainerStatuses: []apiv1.ContainerStatus{
+					{State: apiv1.ContainerState{Waiting: &apiv1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}},
+				},
+			},
+			reason: "ImagePullBackOff",
+		},
+		{
+			name: "container creating",
+			status: apiv1.PodStatus{
+				Phase: apiv1.PodPending,
+				ContainerStatuses: []apiv1.ContainerStatus{
+					{State: apiv1.ContainerState{Waiting: &apiv1.ContainerStateWaiting{Reason: "ContainerCreating"}}},
+				},
+			},
+			reason: "",
+		},
+		{
+			name: "readiness failure",
+			status: apiv1.PodStatus{
+				Phase: apiv1.PodRunning,
+				Conditions: []apiv1.PodCondition{
+					{Type: apiv1.PodReady, Status: apiv1.ConditionFalse, Message: "containers with unready status: [tensorflow-serving]"},
+				},
+			},
+			reason: "NotReady",
+		},
+		{
+			name: "unschedulable",
+			status: apiv1.PodStatus{
+				Phase: apiv1.PodPending,
+				Conditions: []apiv1.PodCondition{
+					{Type: apiv1.PodScheduled, Status: apiv1.ConditionFalse, Reason: "Unschedulable"},
+				},
+			},
+			reason: "Unschedulable",
+		},
+		{
+			name: "ready",
+			status: apiv1.PodStatus{
+				Phase: apiv1.PodRunning,
+				Conditions: []apiv1.PodCondition{
+					{Type: apiv1.PodReady, Status: apiv1.ConditionTrue},
+				},
+			},
+			reason: "",
+			ready:  true,
+		},
+	}
+
+	for _, test := range tests {
+		podStatus := getPodStatus(&apiv1.Pod{Status: test.status})
+		if podStatus.Reason != test.reason || podStatus.Ready != test.ready {
+			t.Errorf("%v: got %+v", test.name, podStatus)
+		}
+	}
+}
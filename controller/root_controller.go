@@ -7,80 +7,85 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-
-	"github.com/josh9191/mini-mnist-serving/clients"
 	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/metrics"
+	"github.com/josh9191/mini-mnist-serving/registry"
+	"github.com/josh9191/mini-mnist-serving/traffic"
 )
 
 type TemplateVar struct {
-	ProdModelReady   bool
-	CanaryModelReady bool
-	CurrentStrategy  constants.Strategy
+	Status         *ModelStatus
+	CanaryAnalysis *CanaryAnalysis
+	Shadow         *metrics.ComparisonSummary
+	ModelName      string
+	Models         []registry.Model
+}
+
+// templateFuncs are the functions used by the root page
+var templateFuncs = template.FuncMap{
+	"percent": func(rate float64) float64 { return rate * 100 },
+	"join":    strings.Join,
 }
 
 // RootController renders root page
-func RootController(w http.ResponseWriter, r *http.Request) {
+func (s *Server) RootController(w http.ResponseWriter, r *http.Request) {
 	wd, err := os.Getwd()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	tmpl := template.Must(template.ParseFiles(filepath.Join(wd, "..", "templates", "index.html")))
-
-	prodModelReady := false
-	canaryModelReady := false
-	curStrategy := constants.None
+	tmpl := template.Must(template.New("index.html").Funcs(templateFuncs).ParseFiles(filepath.Join(wd, "..", "templates", "index.html")))
 
-	kubeClientSet := clients.GetKubernetesClientSet()
-	// check the model is deployed - deployment
-	deploymentsClient := kubeClientSet.AppsV1().Deployments(getNamespace(false))
-	result, err := deploymentsClient.Get(context.TODO(), constants.DeploymentName, metav1.GetOptions{})
+	ctx, cancel := context.WithTimeout(r.Context(), statusTimeout)
+	defer cancel()
+	models, err := s.cachedRegistry().List(ctx)
 	if err != nil {
-		// to confirm
-		prodModelReady = false
+		log.Printf("Error listing models: %v", err)
 	}
-	// deployment ready
-	if result.Status.AvailableReplicas > 0 {
-		prodModelReady = true
-		log.Println("Current model is ready.")
+	// show the selected model or the first registered model
+	modelName := r.URL.Query().Get("model")
+	if modelName == "" {
+		if len(models) > 0 {
+			modelName = models[0].Name
+		} else {
+			modelName = constants.DefaultModelName
+		}
 	}
 
-	deploymentsClient = kubeClientSet.AppsV1().Deployments(getNamespace(true))
-	result, err = deploymentsClient.Get(context.TODO(), constants.DeploymentName, metav1.GetOptions{})
+	// the page is rendered from the same status as the status API
+	modelStatus, err := s.getModelStatus(ctx, modelName)
 	if err != nil {
-		// to confirm
-		canaryModelReady = false
-	}
-	// deployment ready
-	if result.Status.AvailableReplicas > 0 {
-		canaryModelReady = true
-		log.Println("New model is ready.")
+		log.Printf("Error getting status of %v: %v", modelName, err)
+		modelStatus = &ModelStatus{
+			ModelName: modelName,
+			Prod:      SlotStatus{Slot: ProdSlot},
+			Canary:    SlotStatus{Slot: CanarySlot},
+			Strategy:  StrategyStatus{Strategy: constants.None, Name: traffic.StrategyName(constants.None)},
+		}
 	}
 
-	// check canary metadata
-	ingressClient := kubeClientSet.ExtensionsV1beta1().Ingresses(getNamespace(true))
-	ingressResult, err := ingressClient.Get(context.TODO(), constants.IngressName, metav1.GetOptions{})
-	if err != nil {
-		log.Println("Error getting ingress. Maybe it is not created.")
-	} else {
-		_, hasCanaryHeaderKey := ingressResult.ObjectMeta.Annotations["nginx.ingress.kubernetes.io/canary-by-header"]
-		if hasCanaryHeaderKey {
-			_, hasCanaryWeightKey := ingressResult.ObjectMeta.Annotations["nginx.ingress.kubernetes.io/canary-weight"]
-			if hasCanaryWeightKey {
-				curStrategy = constants.Canary
-			} else {
-				curStrategy = constants.NewModelOnly
-			}
-		} else {
-			curStrategy = constants.CurrentModelOnly
-		}
+	var shadow *metrics.ComparisonSummary
+	if modelStatus.Strategy.Strategy == constants.Shadow {
+		summary := s.comparisons.Summary(modelName)
+		shadow = &summary
 	}
 
 	tmpl.Execute(w, TemplateVar{
-		ProdModelReady:   prodModelReady,
-		CanaryModelReady: canaryModelReady,
-		CurrentStrategy:  curStrategy,
+		Status:         modelStatus,
+		CanaryAnalysis: s.getCanaryAnalysis(modelName),
+		Shadow:         shadow,
+		ModelName:      modelName,
+		Models:         models,
 	})
 }
+
+// reverseRevisions returns the revisions from the newest one
+func reverseRevisions(revisions []Revision) []Revision {
+	reversed := make([]Revision, len(revisions))
+	for i, revision := range revisions {
+		reversed[len(revisions)-1-i] = revision
+	}
+	return reversed
+}
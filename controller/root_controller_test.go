@@ -1,28 +1,32 @@
 package controller
 
 import (
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
-	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/josh9191/mini-mnist-serving/clients"
-	"k8s.io/client-go/util/homedir"
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/metrics"
+	"github.com/josh9191/mini-mnist-serving/registry"
+	"github.com/josh9191/mini-mnist-serving/traffic"
+
+	appsv1 "k8s.io/api/apps/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
-func TestRootController(t *testing.T) {
+func renderRoot(t *testing.T, s *Server) string {
 	r, err := http.NewRequest("GET", "/", nil)
 	if err != nil {
 		t.Error(err)
 	}
 
-	// initliaze kubernetes configuration - you should use config file in hoe directory ($HOME/.kube/config)
-	home := homedir.HomeDir()
-	kubeconfig := filepath.Join(home, ".kube", "config")
-	clients.InitKubernetesClient(kubeconfig)
-
 	w := httptest.NewRecorder()
-	handler := http.HandlerFunc(RootController)
+	handler := http.HandlerFunc(s.RootController)
 	handler.ServeHTTP(w, r)
 
 	resp := w.Result()
@@ -30,4 +34,194 @@ func TestRootController(t *testing.T) {
 	if resp.StatusCode != http.StatusOK {
 		t.Errorf("Error - Status Code: %d", resp.StatusCode)
 	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	// collapse whitespaces of the rendered template
+	return strings.Join(strings.Fields(string(body)), " ")
+}
+
+func readyDeployment(namespace string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      registry.DeploymentName(constants.DefaultModelName),
+			Namespace: namespace,
+		},
+		Status: appsv1.DeploymentStatus{
+			AvailableReplicas: 1,
+		},
+	}
+}
+
+func canaryIngress(annotations map[string]string) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        registry.IngressName(constants.DefaultModelName),
+			Namespace:   constants.CanaryNamespace,
+			Annotations: annotations,
+		},
+	}
+}
+
+func TestRootController(t *testing.T) {
+	s, _ := newTestServer()
+	body := renderRoot(t, s)
+
+	if !strings.Contains(body, "Strategy - None") {
+		t.Errorf("Strategy should be None when nothing is deployed")
+	}
+	if strings.Contains(body, "Re-Deploy") {
+		t.Errorf("Models should not be ready when nothing is deployed")
+	}
+}
+
+func TestRootControllerStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		strategy string
+		redeploy int
+		undeploy int
+	}{
+		{
+			name: "current model only",
+			objects: []runtime.Object{
+				readyDeployment(constants.ProdNamespace),
+				canaryIngress(map[string]string{"nginx.ingress.kubernetes.io/canary": "false"}),
+			},
+			strategy: "Current Model Only",
+			redeploy: 1,
+			undeploy: 1,
+		},
+		{
+			name: "new model only",
+			objects: []runtime.Object{
+				readyDeployment(constants.ProdNamespace),
+				readyDeployment(constants.CanaryNamespace),
+				canaryIngress(map[string]string{
+					"nginx.ingress.kubernetes.io/canary":           "true",
+					"nginx.ingress.kubernetes.io/canary-by-header": constants.CanaryHeader,
+				}),
+			},
+			strategy: "New Model Only",
+			redeploy: 2,
+			undeploy: 2,
+		},
+		{
+			name: "canary",
+			objects: []runtime.Object{
+				readyDeployment(constants.ProdNamespace),
+				readyDeployment(constants.CanaryNamespace),
+				canaryIngress(map[string]string{
+					"nginx.ingress.kubernetes.io/canary":           "true",
+					"nginx.ingress.kubernetes.io/canary-by-header": constants.CanaryHeader,
+					"nginx.ingress.kubernetes.io/canary-weight":    "30",
+				}),
+			},
+			strategy: "Canary (30% New)",
+			redeploy: 2,
+			undeploy: 2,
+		},
+		{
+			// the canary ingress of newly deployed model
+			name: "canary without weight and header",
+			objects: []runtime.Object{
+				readyDeployment(constants.ProdNamespace),
+				readyDeployment(constants.CanaryNamespace),
+				canaryIngress(map[string]string{"nginx.ingress.kubernetes.io/canary": "true"}),
+			},
+			strategy: "Current Model Only",
+			redeploy: 2,
+			undeploy: 2,
+		},
+		{
+			name: "targeted",
+			objects: []runtime.Object{
+				readyDeployment(constants.ProdNamespace),
+				readyDeployment(constants.CanaryNamespace),
+				canaryIngress(map[string]string{
+					"nginx.ingress.kubernetes.io/canary":                 "true",
+					"nginx.ingress.kubernetes.io/canary-by-header":       "X-Client-Id",
+					"nginx.ingress.kubernetes.io/canary-by-header-value": "alice",
+					traffic.TargetAnnotation:                             `{"header":"X-Client-Id","values":["alice"]}`,
+				}),
+			},
+			strategy: "Targeted (X-Client-Id: alice)",
+			redeploy: 2,
+			undeploy: 2,
+		},
+		{
+			name: "shadow",
+			objects: []runtime.Object{
+				readyDeployment(constants.ProdNamespace),
+				readyDeployment(constants.CanaryNamespace),
+				canaryIngress(map[string]string{
+					"nginx.ingress.kubernetes.io/canary":           "true",
+					"nginx.ingress.kubernetes.io/canary-by-header": constants.CanaryHeader,
+					traffic.ShadowAnnotation:                       "true",
+				}),
+			},
+			strategy: "Shadow",
+			redeploy: 2,
+			undeploy: 2,
+		},
+	}
+
+	for _, test := range tests {
+		s, _ := newTestServer(test.objects...)
+		body := renderRoot(t, s)
+
+		if !strings.Contains(body, "Strategy - "+test.strategy) {
+			t.Errorf("%v: strategy is not rendered", test.name)
+		}
+		if count := strings.Count(body, "Re-Deploy"); count != test.redeploy {
+			t.Errorf("%v: %d models are ready, want %d", test.name, count, test.redeploy)
+		}
+		// undeploy buttons of slots without deployment are hidden
+		if count := 2 - strings.Count(body, "hidden> Undeploy"); count != test.undeploy {
+			t.Errorf("%v: %d models can be undeployed, want %d", test.name, count, test.undeploy)
+		}
+	}
+}
+
+func TestRootControllerRevisions(t *testing.T) {
+	deployment := readyDeployment(constants.ProdNamespace)
+	for _, modelBaseDir := range []string{"gs://my-bucket/v1", "gs://my-bucket/v2"} {
+		recordRevision(deployment, Revision{ModelBaseDir: modelBaseDir, NumReplicas: 1, ChangeCause: "deploy"})
+	}
+	s, _ := newTestServer(deployment)
+	body := renderRoot(t, s)
+
+	// the newest revision is shown first and only older revisions can be restored
+	if !strings.Contains(body, "<td>2</td> <td>gs://my-bucket/v2</td>") || strings.Index(body, "gs://my-bucket/v2") > strings.Index(body, "gs://my-bucket/v1") {
+		t.Errorf("Revision history is not rendered")
+	}
+	if count := strings.Count(body, `data-revision=`); count != 1 {
+		t.Errorf("%d revisions can be restored, want 1", count)
+	}
+	if !strings.Contains(body, "Not deployed") {
+		t.Errorf("History of undeployed slot is not rendered")
+	}
+}
+
+func TestRootControllerCanaryAnalysis(t *testing.T) {
+	s, _ := newTestServer()
+	if body := renderRoot(t, s); !strings.Contains(body, `id="canary-analysis" hidden`) {
+		t.Errorf("Canary analysis is shown before canary")
+	}
+
+	finishedAt := time.Now()
+	s.canaries[constants.DefaultModelName] = &CanaryAnalysis{
+		ModelName: constants.DefaultModelName,
+		State:     CanaryAborted,
+		Reason:    "The new model served 3 requests, less than 10.",
+		Steps: []CanaryStep{
+			{Weight: 5, FinishedAt: &finishedAt, Prod: metrics.Stats{Requests: 57, ErrorRate: 0.0175}, Canary: metrics.Stats{Requests: 3, P95LatencyMs: 12.34}},
+		},
+	}
+	body := renderRoot(t, s)
+	if !strings.Contains(body, "Aborted - The new model served 3 requests, less than 10.") {
+		t.Errorf("Canary state is not rendered")
+	}
+	if !strings.Contains(body, "<td>5%</td> <td>57 / 3</td> <td>0.018 / 0.000</td> <td>0.0ms / 12.3ms</td> <td>Failed - </td>") {
+		t.Errorf("Canary steps are not rendered: %v", body)
+	}
 }
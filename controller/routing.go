@@ -0,0 +1,267 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/registry"
+	"github.com/josh9191/mini-mnist-serving/traffic"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// EnableDirectRouting makes the server route predictions to slotHost by the stored strategy
+// instead of relying on the canary annotations of nginx ingress
+func (s *Server) EnableDirectRouting(slotHost SlotHostFunc) {
+	s.slotHost = slotHost
+}
+
+// ParseSlotHosts returns SlotHostFunc of "<model>/<prod|canary>=<host:port>" pairs separated by commas,
+// e.g. the local ports forwarded to the services. The service DNS name is used for the other slots.
+func ParseSlotHosts(value string) (SlotHostFunc, error) {
+	return parseSlotHosts(value, ServiceHost)
+}
+
+// ParseGRPCSlotHosts returns SlotHostFunc of the gRPC ports in the same format as ParseSlotHosts
+func ParseGRPCSlotHosts(value string) (SlotHostFunc, error) {
+	return parseSlotHosts(value, GRPCServiceHost)
+}
+
+// parseSlotHosts returns SlotHostFunc of the pairs in value, which returns defaultHost for the other slots
+func parseSlotHosts(value string, defaultHost SlotHostFunc) (SlotHostFunc, error) {
+	hosts := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if pair == "" {
+			continue
+		}
+		keyValue := strings.SplitN(pair, "=", 2)
+		if len(keyValue) != 2 {
+			return nil, fmt.Errorf("Invalid slot host: %v", pair)
+		}
+		key := strings.SplitN(keyValue[0], "/", 2)
+		if len(key) != 2 || (key[1] != ProdSlot && key[1] != CanarySlot) {
+			return nil, fmt.Errorf("Invalid slot: %v", keyValue[0])
+		}
+		if _, _, err := net.SplitHostPort(keyValue[1]); err != nil {
+			return nil, fmt.Errorf("Invalid host of %v: %v", keyValue[0], err)
+		}
+		hosts[keyValue[0]] = keyValue[1]
+	}
+
+	return func(modelName string, isNewModel bool) string {
+		slot := ProdSlot
+		if isNewModel {
+			slot = CanarySlot
+		}
+		if host, ok := hosts[modelName+"/"+slot]; ok {
+			return host
+		}
+		return defaultHost(modelName, isNewModel)
+	}, nil
+}
+
+// getRoutingKey returns the key of sticky routing, which is the routing key header or the client address
+func getRoutingKey(r *http.Request) string {
+	if key := r.Header.Get(constants.RoutingKeyHeader); key != "" {
+		return key
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// routingStrategy stores the strategy routing predictions of a model
+type routingStrategy struct {
+	strategy constants.Strategy
+	weight   int
+	target   *registry.TargetRule
+}
+
+// getRoutingStrategy returns the strategy stored in the registry with direct routing,
+// or the strategy read back from the traffic backend
+// Every prediction reads the strategy, so it is read from the cache when it is enabled.
+// The registry is read instead of the traffic backends which are not cached.
+func (s *Server) getRoutingStrategy(ctx context.Context, modelName string) routingStrategy {
+	if s.cache != nil && s.traffic.Name() != traffic.Nginx {
+		return s.getRegistryStrategy(ctx, modelName)
+	}
+	return s.getRoutingStrategyFrom(ctx, s.cachedTraffic(), modelName)
+}
+
+// getRoutingStrategyFrom reads the strategy of model from backend unless the server routes predictions
+func (s *Server) getRoutingStrategyFrom(ctx context.Context, backend traffic.Backend, modelName string) routingStrategy {
+	if s.slotHost != nil {
+		return s.getRegistryStrategy(ctx, modelName)
+	}
+
+	strategy, err := backend.GetStrategy(ctx, modelName)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Printf("Error getting strategy of %v: %v", modelName, err)
+		}
+		return routingStrategy{strategy: constants.None}
+	}
+	return routingStrategy{strategy: strategy.Strategy, weight: strategy.Weight, target: strategy.Target}
+}
+
+// getRegistryStrategy returns the strategy of model stored in the registry, which is read from the cache when it is enabled
+func (s *Server) getRegistryStrategy(ctx context.Context, modelName string) routingStrategy {
+	model, err := s.cachedRegistry().Get(ctx, modelName)
+	if err != nil {
+		return routingStrategy{strategy: constants.None}
+	}
+	return routingStrategy{strategy: model.Strategy, weight: model.Weight, target: model.Target}
+}
+
+// routesToNewModel reports whether the request is sent to the new model by the strategy
+// The same routing key is always routed to the same model while the weight is not changed.
+func routesToNewModel(modelName string, routing routingStrategy, r *http.Request) bool {
+	switch routing.strategy {
+	case constants.NewModelOnly:
+		return true
+	case constants.Canary:
+		return stickyBucket(modelName, getRoutingKey(r)) < routing.weight
+	case constants.Targeted:
+		return targetMatches(routing.target, r)
+	default:
+		// the current model serves users in Shadow strategy
+		return false
+	}
+}
+
+// targetMatches reports whether the request is selected by the target rule in the same way as nginx
+func targetMatches(target *registry.TargetRule, r *http.Request) bool {
+	if target == nil {
+		return false
+	}
+	if target.Header != "" {
+		value := r.Header.Get(target.Header)
+		for _, targetValue := range target.Values {
+			if value == targetValue {
+				return true
+			}
+		}
+	}
+	if target.Cookie != "" {
+		cookie, err := r.Cookie(target.Cookie)
+		return err == nil && cookie.Value == "always"
+	}
+	return false
+}
+
+// targetHeader returns the header and cookie of the request selected by the target rule,
+// which are forwarded to the ingress
+func targetHeader(target *registry.TargetRule, r *http.Request) http.Header {
+	header := http.Header{}
+	if target == nil {
+		return header
+	}
+	if value := r.Header.Get(target.Header); target.Header != "" && value != "" {
+		header.Set(target.Header, value)
+	}
+	if target.Cookie != "" {
+		if cookie, err := r.Cookie(target.Cookie); err == nil {
+			header.Set("Cookie", (&http.Cookie{Name: cookie.Name, Value: cookie.Value}).String())
+		}
+	}
+	return header
+}
+
+// canaryHeader returns the header selecting the new model by canary-by-header annotation
+func canaryHeader(value string) http.Header {
+	header := http.Header{}
+	header.Set(constants.CanaryHeader, value)
+	return header
+}
+
+// stickyBucket returns the bucket of routingKey from 0 to 99
+func stickyBucket(modelName string, routingKey string) int {
+	h := fnv.New32a()
+	h.Write([]byte(modelName + "/" + routingKey))
+	return int(h.Sum32() % 100)
+}
+
+// SetSlotHosts makes the server send predictions to slotHost and select the slot by the strategy
+// when the traffic backend doesn't report which model served the request, e.g. the local ports forwarded to the services
+func (s *Server) SetSlotHosts(slotHost SlotHostFunc) {
+	s.slotHosts = slotHost
+}
+
+// predictionSlotHost returns the host of model slots which the server sends predictions to,
+// or nil when predictions are sent through the traffic backend
+// Without direct routing, predictions are sent through the traffic backend unless the slot hosts are set
+// while the backend doesn't report which model served the request.
+func (s *Server) predictionSlotHost() SlotHostFunc {
+	if s.slotHost != nil {
+		return s.slotHost
+	}
+	if !s.traffic.ReportsUpstream() {
+		return s.slotHosts
+	}
+	return nil
+}
+
+// attributesPredictions reports whether the server knows which model served each prediction of Canary strategy
+func (s *Server) attributesPredictions() bool {
+	return s.grpcClient != nil || s.traffic.ReportsUpstream() || s.predictionSlotHost() != nil
+}
+
+// inferSlot returns the slot which the traffic backend sends the request to by the strategy,
+// or UnknownSlot when the backend splits requests by the canary weight
+func inferSlot(modelName string, routing routingStrategy, r *http.Request) string {
+	if routing.strategy == constants.Canary && routing.weight > 0 && routing.weight < 100 {
+		return UnknownSlot
+	}
+	return getSlotName(routesToNewModel(modelName, routing, r))
+}
+
+// routePrediction sends the prediction request to the model selected by the strategy
+// and returns the slot which served it with the response and its body
+// The slot is UnknownSlot when the traffic backend selected the model by the weight without reporting it.
+// The slot is returned with the error when the request fails, so that the failure of the slot is recorded.
+func (s *Server) routePrediction(ctx context.Context, ingressHost string, modelName string, requestJson []byte, routing routingStrategy, r *http.Request) (string, *http.Response, []byte, error) {
+	if slotHost := s.predictionSlotHost(); slotHost != nil {
+		isNewModel := routesToNewModel(modelName, routing, r)
+		resp, body, err := sendSlotPrediction(ctx, slotHost(modelName, isNewModel), modelName, requestJson)
+		return getSlotName(isNewModel), resp, body, err
+	}
+
+	// the header will be ignored when non-canary model prediction
+	header := canaryHeader("always")
+	if routing.strategy == constants.Shadow {
+		header = canaryHeader("never")
+	} else if routing.strategy == constants.Targeted {
+		// nginx selects the model by the header and cookie of the user
+		header = targetHeader(routing.target, r)
+	}
+	resp, body, err := sendPrediction(ctx, ingressHost, modelName, requestJson, header)
+	if !s.traffic.ReportsUpstream() {
+		return inferSlot(modelName, routing, r), resp, body, err
+	}
+	if err != nil {
+		return UnknownSlot, nil, nil, err
+	}
+	return getSlotName(resp.Header.Get(constants.CanaryUpstreamHeader) != ""), resp, body, nil
+}
+
+// sendToNewModel sends the prediction request to the new model regardless of the weight
+// It fails when the traffic backend reports that the current model served the request.
+func (s *Server) sendToNewModel(ctx context.Context, ingressHost string, modelName string, requestJson []byte) (*http.Response, []byte, error) {
+	if slotHost := s.predictionSlotHost(); slotHost != nil {
+		return sendSlotPrediction(ctx, slotHost(modelName, true), modelName, requestJson)
+	}
+
+	resp, body, err := sendPrediction(ctx, ingressHost, modelName, requestJson, canaryHeader("always"))
+	if err == nil && s.traffic.ReportsUpstream() && resp.StatusCode == http.StatusOK && resp.Header.Get(constants.CanaryUpstreamHeader) == "" {
+		err = fmt.Errorf("The request is served by the current model.")
+	}
+	return resp, body, err
+}
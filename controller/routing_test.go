@@ -0,0 +1,413 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/metrics"
+	"github.com/josh9191/mini-mnist-serving/registry"
+	"github.com/josh9191/mini-mnist-serving/traffic"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestParseSlotHosts(t *testing.T) {
+	slotHost, err := ParseSlotHosts("mnist-cnn/prod=localhost:8501,mnist-cnn/canary=localhost:8502")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		modelName  string
+		isNewModel bool
+		host       string
+	}{
+		{"mnist-cnn", false, "localhost:8501"},
+		{"mnist-cnn", true, "localhost:8502"},
+		{"mnist-mlp", true, "mnist-mlp-svc.mnist-canary.svc:8501"},
+	} {
+		if host := slotHost(test.modelName, test.isNewModel); host != test.host {
+			t.Errorf("%v (new: %v): %v, expected: %v", test.modelName, test.isNewModel, host, test.host)
+		}
+	}
+
+	for _, value := range []string{"localhost:8501", "mnist-cnn=localhost:8501", "mnist-cnn/new=localhost:8501", "mnist-cnn/prod=localhost"} {
+		if _, err := ParseSlotHosts(value); err == nil {
+			t.Errorf("Invalid slot hosts are parsed: %v", value)
+		}
+	}
+}
+
+func requestWithRoutingKey(routingKey string) *http.Request {
+	r := httptest.NewRequest("POST", "/model:predict", nil)
+	r.Header.Set(constants.RoutingKeyHeader, routingKey)
+	return r
+}
+
+func TestRoutesToNewModel(t *testing.T) {
+	for _, test := range []struct {
+		strategy constants.Strategy
+		weight   int
+		min      int
+		max      int
+	}{
+		{constants.CurrentModelOnly, 0, 0, 0},
+		{constants.NewModelOnly, 0, 1000, 1000},
+		{constants.Shadow, 0, 0, 0},
+		{constants.Canary, 0, 0, 0},
+		{constants.Canary, 30, 250, 350},
+		{constants.Canary, 100, 1000, 1000},
+	} {
+		routed := 0
+		routing := routingStrategy{strategy: test.strategy, weight: test.weight}
+		for i := 0; i < 1000; i++ {
+			routingKey := fmt.Sprintf("user-%d", i)
+			isNewModel := routesToNewModel("mnist-cnn", routing, requestWithRoutingKey(routingKey))
+			if isNewModel != routesToNewModel("mnist-cnn", routing, requestWithRoutingKey(routingKey)) {
+				t.Fatalf("%v is routed to different models", routingKey)
+			}
+			if isNewModel {
+				routed++
+			}
+		}
+		if routed < test.min || routed > test.max {
+			t.Errorf("Strategy %v, weight %v: %d of 1000 requests are routed to the new model", test.strategy, test.weight, routed)
+		}
+	}
+}
+
+func TestPredictionSlotHost(t *testing.T) {
+	s, _ := newTestServer()
+	// predictions are sent through the ingress by default
+	if s.predictionSlotHost() != nil {
+		t.Errorf("Predictions are not sent through the ingress by default")
+	}
+
+	// the slot hosts are used when nginx doesn't tell the serving model without the configuration snippet
+	slotHost, _ := ParseSlotHosts("mnist-cnn/canary=localhost:8502")
+	s.SetSlotHosts(slotHost)
+	if slotHost := s.predictionSlotHost(); slotHost == nil || slotHost("mnist-cnn", true) != "localhost:8502" {
+		t.Errorf("Predictions are not sent to the slot hosts without the upstream header")
+	}
+
+	// so are the routes of Gateway API without the backendRef filter
+	backend, err := traffic.NewGatewayBackend(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), "gateway-ns/mnist-gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetTrafficBackend(backend)
+	if s.predictionSlotHost() == nil {
+		t.Errorf("Predictions are sent through the gateway without the upstream header")
+	}
+	backend.EnableUpstreamHeader()
+	if s.predictionSlotHost() != nil {
+		t.Errorf("Predictions are not sent through the gateway with the upstream header")
+	}
+
+	enableTestUpstreamHeader(s)
+	if s.predictionSlotHost() != nil {
+		t.Errorf("Predictions are not sent through the ingress with the upstream header")
+	}
+
+	s.EnableDirectRouting(slotHost)
+	if host := s.predictionSlotHost()("mnist-cnn", true); host != "localhost:8502" {
+		t.Errorf("Wrong host of direct routing: %v", host)
+	}
+}
+
+func TestModelPredictControllerWrapperIngress(t *testing.T) {
+	// the default nginx ingress doesn't report the serving model
+	var requests int32
+	ingressServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.9, 0.1, 0.0, 0.0, 0.0]]}`))
+	}))
+	defer ingressServer.Close()
+	ingressUrl, _ := url.Parse(ingressServer.URL)
+
+	s, _ := newTestServer()
+	deployBothSlots(t, s)
+	handler := s.ModelPredictControllerWrapper(ingressUrl.Host)
+	body, _ := json.Marshal(make([]float32, 784))
+	weight := 50
+	for _, test := range []struct {
+		request SetStrategyRequest
+		slot    string
+	}{
+		{SetStrategyRequest{Strategy: constants.CurrentModelOnly}, ProdSlot},
+		{SetStrategyRequest{Strategy: constants.NewModelOnly}, CanarySlot},
+		// nginx selects the model by the weight
+		{SetStrategyRequest{Strategy: constants.Canary, Weight: &weight}, UnknownSlot},
+	} {
+		if _, err := s.changeStrategy(context.TODO(), "mnist-cnn", test.request); err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/model:predict?model-name=mnist-cnn", bytes.NewReader(body)))
+		var prediction SlotPrediction
+		json.NewDecoder(w.Body).Decode(&prediction)
+		if w.Code != http.StatusOK || prediction.Argmax != 5 || prediction.Slot != test.slot {
+			t.Errorf("Strategy %v: wrong prediction %d %+v", test.request.Strategy, w.Code, prediction)
+		}
+	}
+	if requests != 3 {
+		t.Errorf("%d of 3 predictions are sent through the ingress", requests)
+	}
+
+	// the predictions split by the weight are not analyzed
+	stats := s.metrics.Stats(getNamespace(true)+"/mnist-cnn", time.Time{})
+	if stats.Requests != 1 {
+		t.Errorf("Wrong requests of the new model: %+v", stats)
+	}
+}
+
+func TestGetRoutingStrategyCache(t *testing.T) {
+	s, _ := newTestServer()
+	deployBothSlots(t, s)
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	backend, err := traffic.NewGatewayBackend(dynamicClient, "gateway-ns/mnist-gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetTrafficBackend(backend)
+	enableTestCache(t, s)
+
+	// the routes of Gateway API aren't cached, so the strategy stored in the registry is used
+	routing := s.getRoutingStrategy(context.TODO(), "mnist-cnn")
+	if routing.strategy != constants.Canary || routing.weight != 30 {
+		t.Errorf("Wrong strategy: %+v", routing)
+	}
+	if actions := dynamicClient.Actions(); len(actions) != 0 {
+		t.Errorf("Strategy is read from the traffic backend: %v", actions)
+	}
+}
+
+func TestModelPredictControllerWrapperCanaryRefused(t *testing.T) {
+	prodServer := newSlotServer(t, http.StatusOK, `{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.9, 0.1, 0.0, 0.0, 0.0]]}`)
+	defer prodServer.Close()
+	// the new model refuses connections
+	canaryServer := newSlotServer(t, http.StatusOK, "")
+	canaryServer.Close()
+	prodUrl, _ := url.Parse(prodServer.URL)
+	canaryUrl, _ := url.Parse(canaryServer.URL)
+	slotHost, err := ParseSlotHosts("mnist-cnn/prod=" + prodUrl.Host + ",mnist-cnn/canary=" + canaryUrl.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := newTestServer()
+	s.EnableDirectRouting(slotHost)
+	deployBothSlots(t, s)
+	if _, err := s.changeStrategy(context.TODO(), "mnist-cnn", SetStrategyRequest{Strategy: constants.NewModelOnly}); err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(make([]float32, 784))
+	w := httptest.NewRecorder()
+	s.ModelPredictControllerWrapper("").ServeHTTP(w, httptest.NewRequest("POST", "/model:predict?model-name=mnist-cnn", bytes.NewReader(body)))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Wrong status code: %d", w.Code)
+	}
+	batchBody, _ := json.Marshal([]interface{}{batchImage(0), batchImage(1)})
+	w = httptest.NewRecorder()
+	s.ModelPredictBatchControllerWrapper("", DefaultMaxBatchSize).ServeHTTP(w, httptest.NewRequest("POST", "/model:predictBatch?model-name=mnist-cnn", bytes.NewReader(batchBody)))
+	if w.Code != http.StatusOK {
+		t.Errorf("Wrong status code of batch: %d", w.Code)
+	}
+
+	// the failures of the new model abort the canary
+	stats := s.metrics.Stats(getNamespace(true)+"/mnist-cnn", time.Time{})
+	if stats.Requests != 2 || stats.ErrorRate != 1 {
+		t.Errorf("Wrong stats of the new model: %+v", stats)
+	}
+	canaryRequest := CanaryRequest{MinRequests: 1}
+	setCanaryDefaults(&canaryRequest)
+	if reason := analyzeCanary(canaryRequest, metrics.Stats{}, stats); reason == "" {
+		t.Errorf("The new model refusing connections passes the analysis")
+	}
+}
+
+func TestModelPredictControllerWrapperDirectRouting(t *testing.T) {
+	// the ingress is not used
+	prodServer := newSlotServer(t, http.StatusOK, `{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.9, 0.1, 0.0, 0.0, 0.0]]}`)
+	defer prodServer.Close()
+	canaryServer := newSlotServer(t, http.StatusOK, `{"predictions": [[0.0, 0.0, 0.0, 0.8, 0.0, 0.2, 0.0, 0.0, 0.0, 0.0]]}`)
+	defer canaryServer.Close()
+	prodUrl, _ := url.Parse(prodServer.URL)
+	canaryUrl, _ := url.Parse(canaryServer.URL)
+	slotHost, err := ParseSlotHosts("mnist-cnn/prod=" + prodUrl.Host + ",mnist-cnn/canary=" + canaryUrl.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := newTestServer()
+	s.EnableDirectRouting(slotHost)
+	// with Canary strategy and 30% weight
+	deployBothSlots(t, s)
+
+	handler := s.ModelPredictControllerWrapper(testIngressHost)
+	predict := func(routingKey string) SlotPrediction {
+		body, _ := json.Marshal(make([]float32, 784))
+		r := httptest.NewRequest("POST", "/model:predict?model-name=mnist-cnn", bytes.NewReader(body))
+		r.Header.Set(constants.RoutingKeyHeader, routingKey)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Error - Status Code: %d, %v", w.Code, w.Body.String())
+		}
+		var prediction SlotPrediction
+		json.NewDecoder(w.Body).Decode(&prediction)
+		return prediction
+	}
+
+	canaryPredictions := 0
+	for i := 0; i < 100; i++ {
+		routingKey := fmt.Sprintf("user-%d", i)
+		prediction := predict(routingKey)
+		isNewModel := routesToNewModel("mnist-cnn", routingStrategy{strategy: constants.Canary, weight: 30}, requestWithRoutingKey(routingKey))
+		if (prediction.Slot == CanarySlot) != isNewModel || (prediction.Argmax == 3) != isNewModel {
+			t.Errorf("%v: Wrong slot: %+v", routingKey, prediction)
+		}
+		if isNewModel {
+			canaryPredictions++
+		}
+	}
+	if canaryPredictions == 0 || canaryPredictions == 100 {
+		t.Errorf("Requests are not split: %d", canaryPredictions)
+	}
+
+	for _, test := range []struct {
+		strategy constants.Strategy
+		slot     string
+	}{
+		{constants.NewModelOnly, CanarySlot},
+		{constants.CurrentModelOnly, ProdSlot},
+		{constants.Shadow, ProdSlot},
+	} {
+		resp := setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": test.strategy})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+		}
+		if prediction := predict("user-0"); prediction.Slot != test.slot {
+			t.Errorf("Strategy %v: Wrong slot: %+v", test.strategy, prediction)
+		}
+	}
+
+	// the shadow request is sent to the new model without the ingress
+	shadowResponse := waitForComparisons(t, s, 1)
+	if shadowResponse.Comparisons != 1 || shadowResponse.AgreementRate != 0 {
+		t.Errorf("Wrong comparison: %+v", shadowResponse)
+	}
+}
+
+func TestTargetMatches(t *testing.T) {
+	target := &registry.TargetRule{Header: "X-Client-Id", Values: []string{"alice", "bob"}, Cookie: "mnist_canary"}
+	for _, test := range []struct {
+		name    string
+		header  string
+		cookie  string
+		matches bool
+	}{
+		{"header value", "bob", "", true},
+		{"other header value", "carol", "", false},
+		{"cookie", "", "always", true},
+		{"cookie never", "", "never", false},
+		{"no header and cookie", "", "", false},
+	} {
+		r := httptest.NewRequest("POST", "/model:predict", nil)
+		if test.header != "" {
+			r.Header.Set("X-Client-Id", test.header)
+		}
+		if test.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: "mnist_canary", Value: test.cookie})
+		}
+		if matches := targetMatches(target, r); matches != test.matches {
+			t.Errorf("%v: %v", test.name, matches)
+		}
+		if routing := (routingStrategy{strategy: constants.Targeted, target: target}); routesToNewModel("mnist-cnn", routing, r) != test.matches {
+			t.Errorf("%v: Wrong routing", test.name)
+		}
+	}
+}
+
+func TestModelPredictControllerWrapperTargeted(t *testing.T) {
+	// stub of nginx selecting the new model by the header or cookie
+	modelServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(constants.CanaryHeader) != "" {
+			t.Errorf("Canary header is sent: %v", r.Header.Get(constants.CanaryHeader))
+		}
+		cookie, err := r.Cookie("mnist_canary")
+		if r.Header.Get("X-Client-Id") == "alice" || (err == nil && cookie.Value == "always") {
+			w.Header().Set(constants.CanaryUpstreamHeader, "mnist-canary-mnist-cnn-svc-8501")
+		}
+		w.Write([]byte(`{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.9, 0.1, 0.0, 0.0, 0.0]]}`))
+	}))
+	defer modelServer.Close()
+	modelServerUrl, _ := url.Parse(modelServer.URL)
+	prodServer := newSlotServer(t, http.StatusOK, `{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.9, 0.1, 0.0, 0.0, 0.0]]}`)
+	defer prodServer.Close()
+	canaryServer := newSlotServer(t, http.StatusOK, `{"predictions": [[0.0, 0.0, 0.0, 0.8, 0.0, 0.2, 0.0, 0.0, 0.0, 0.0]]}`)
+	defer canaryServer.Close()
+	prodUrl, _ := url.Parse(prodServer.URL)
+	canaryUrl, _ := url.Parse(canaryServer.URL)
+	slotHost, err := ParseSlotHosts("mnist-cnn/prod=" + prodUrl.Host + ",mnist-cnn/canary=" + canaryUrl.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, direct := range []bool{false, true} {
+		s, _ := newTestServer()
+		if direct {
+			s.EnableDirectRouting(slotHost)
+		} else {
+			enableTestUpstreamHeader(s)
+		}
+		deployBothSlots(t, s)
+		resp := setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": constants.Targeted, "target": map[string]interface{}{
+			"header": "X-Client-Id", "values": []string{"alice"}, "cookie": "mnist_canary",
+		}})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+		}
+
+		handler := s.ModelPredictControllerWrapper(modelServerUrl.Host)
+		for _, test := range []struct {
+			clientId string
+			cookie   string
+			slot     string
+		}{
+			{"alice", "", CanarySlot},
+			{"bob", "", ProdSlot},
+			{"bob", "always", CanarySlot},
+			{"", "", ProdSlot},
+		} {
+			body, _ := json.Marshal(make([]float32, 784))
+			r := httptest.NewRequest("POST", "/model:predict?model-name=mnist-cnn", bytes.NewReader(body))
+			if test.clientId != "" {
+				r.Header.Set("X-Client-Id", test.clientId)
+			}
+			if test.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: "mnist_canary", Value: test.cookie})
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Error - Status Code: %d, %v", w.Code, w.Body.String())
+			}
+			var prediction SlotPrediction
+			json.NewDecoder(w.Body).Decode(&prediction)
+			if prediction.Slot != test.slot {
+				t.Errorf("Direct routing: %v, client %q, cookie %q: Wrong slot: %+v", direct, test.clientId, test.cookie, prediction)
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"context"
+	"sync"
+
+	"github.com/josh9191/mini-mnist-serving/cache"
+	"github.com/josh9191/mini-mnist-serving/clients"
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/jobs"
+	"github.com/josh9191/mini-mnist-serving/metrics"
+	"github.com/josh9191/mini-mnist-serving/registry"
+	"github.com/josh9191/mini-mnist-serving/tfserving"
+	"github.com/josh9191/mini-mnist-serving/traffic"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Server stores dependencies shared by controllers
+type Server struct {
+	kubeClientSet kubernetes.Interface
+	ingressClient clients.IngressClient
+	traffic       traffic.Backend
+	registry      *registry.Registry
+	// cache is set when the status of models is read from the informer cache
+	cache       *cache.Cache
+	jobs        *jobs.Manager
+	metrics     *metrics.Recorder
+	comparisons *metrics.ComparisonRecorder
+	// slotHost is set when the server routes predictions instead of the ingress
+	slotHost SlotHostFunc
+	// slotHosts is set when the server sends predictions to the slot hosts while the traffic backend doesn't report the serving model
+	slotHosts SlotHostFunc
+	// trustedProxyUser is set when the user headers are set by the authenticating proxy
+	trustedProxyUser bool
+	// grpcClient is set when predictions are sent to the gRPC port of TF Serving at grpcSlotHost
+	grpcClient   *tfserving.Client
+	grpcSlotHost SlotHostFunc
+	// limits the shadow requests in flight
+	shadowRequests chan struct{}
+
+	// the last canary analysis of each model
+	canariesMu sync.Mutex
+	canaries   map[string]*CanaryAnalysis
+}
+
+const (
+	// maxJobs is the number of finished jobs kept in memory
+	maxJobs = 100
+	// maxObservations is the number of prediction requests kept in memory for each model slot
+	maxObservations = 10000
+	// maxComparisons is the number of shadow predictions kept in memory for each model
+	maxComparisons = 10000
+	// maxShadowRequests is the number of shadow requests sent at the same time
+	maxShadowRequests = 10
+)
+
+// NewServer returns Server which accesses the cluster through kubeClientSet
+// and manages Ingress objects through ingressClient
+func NewServer(kubeClientSet kubernetes.Interface, ingressClient clients.IngressClient) *Server {
+	return &Server{
+		kubeClientSet: kubeClientSet,
+		ingressClient: ingressClient,
+		traffic:       traffic.NewNginxBackend(ingressClient),
+		// registered models are stored in the production namespace
+		registry:       registry.NewRegistry(kubeClientSet, constants.ProdNamespace),
+		jobs:           jobs.NewManager(maxJobs),
+		metrics:        metrics.NewRecorder(maxObservations),
+		comparisons:    metrics.NewComparisonRecorder(maxComparisons),
+		shadowRequests: make(chan struct{}, maxShadowRequests),
+		canaries:       make(map[string]*CanaryAnalysis),
+	}
+}
+
+// SetTrafficBackend replaces the nginx ingress backend routing the prediction requests
+func (s *Server) SetTrafficBackend(backend traffic.Backend) {
+	s.traffic = backend
+}
+
+// EnableCache makes the root page, the status API, the live updates and predictions read the cluster state from c
+// which should be synced already. Deploying models and changing the strategy still read the API server.
+func (s *Server) EnableCache(c *cache.Cache) {
+	s.cache = c
+}
+
+// getCachedDeployment returns the deployment of model slot from the cache when it is enabled
+// The returned object must not be modified.
+func (s *Server) getCachedDeployment(ctx context.Context, modelName string, isNewModel bool) (*appsv1.Deployment, error) {
+	namespace := getNamespace(isNewModel)
+	if s.cache != nil {
+		return s.cache.GetDeployment(namespace, registry.DeploymentName(modelName))
+	}
+	return s.kubeClientSet.AppsV1().Deployments(namespace).Get(ctx, registry.DeploymentName(modelName), metav1.GetOptions{})
+}
+
+// listCachedPods returns the Pods of model slot from the cache when it is enabled
+// The returned objects must not be modified.
+func (s *Server) listCachedPods(ctx context.Context, modelName string, isNewModel bool) ([]*apiv1.Pod, error) {
+	namespace := getNamespace(isNewModel)
+	selector := labels.SelectorFromSet(registry.Labels(modelName))
+	if s.cache != nil {
+		return s.cache.ListPods(namespace, selector)
+	}
+	pods, err := s.kubeClientSet.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	return podPointers(pods.Items), nil
+}
+
+// listCachedReplicaSets returns the ReplicaSets of model slot from the cache when it is enabled
+// The returned objects must not be modified.
+func (s *Server) listCachedReplicaSets(ctx context.Context, modelName string, isNewModel bool) ([]*appsv1.ReplicaSet, error) {
+	namespace := getNamespace(isNewModel)
+	selector := labels.SelectorFromSet(registry.Labels(modelName))
+	if s.cache != nil {
+		return s.cache.ListReplicaSets(namespace, selector)
+	}
+	replicaSets, err := s.kubeClientSet.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	return replicaSetPointers(replicaSets.Items), nil
+}
+
+// cachedRegistry returns the registry reading models from the cache when it is enabled
+func (s *Server) cachedRegistry() *registry.Registry {
+	if s.cache != nil {
+		return s.registry.WithConfigMapGetter(s.cache.GetConfigMap)
+	}
+	return s.registry
+}
+
+// cachedTraffic returns the traffic backend reading the strategy from the cache when it is enabled
+// Only the nginx backend is cached because the other backends use custom resources.
+func (s *Server) cachedTraffic() traffic.Backend {
+	if s.cache != nil && s.traffic.Name() == traffic.Nginx {
+		return traffic.NewNginxBackend(s.cache.IngressClient(s.ingressClient))
+	}
+	return s.traffic
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/josh9191/mini-mnist-serving/cache"
+	"github.com/josh9191/mini-mnist-serving/clients"
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/registry"
+	"github.com/josh9191/mini-mnist-serving/traffic"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// testPodTemplateHash is the pod-template-hash of the ReplicaSets created for the test Deployments
+const testPodTemplateHash = "6b474476c4"
+
+// newTestServer returns Server backed by a fake client set which already contains objects
+func newTestServer(objects ...runtime.Object) (*Server, *fake.Clientset) {
+	return newTestServerWithIngressClient(clients.NewNetworkingV1IngressClient, objects...)
+}
+
+// newTestServerWithIngressClient returns Server which uses the given Ingress API version
+func newTestServerWithIngressClient(newIngressClient func(kubernetes.Interface) clients.IngressClient, objects ...runtime.Object) (*Server, *fake.Clientset) {
+	kubeClientSet := fake.NewSimpleClientset(objects...)
+	createReplicaSets(kubeClientSet)
+	return NewServer(kubeClientSet, newIngressClient(kubeClientSet)), kubeClientSet
+}
+
+// createReplicaSets makes created or updated Deployments own the ReplicaSet of their template like the deployment controller,
+// so that Pods labeled with testPodTemplateHash belong to the current template
+func createReplicaSets(kubeClientSet *fake.Clientset) {
+	createReplicaSet := func(action k8stesting.Action) (bool, runtime.Object, error) {
+		// the status doesn't change the template
+		if action.GetSubresource() != "" {
+			return false, nil, nil
+		}
+		deployment := action.(k8stesting.CreateAction).GetObject().(*appsv1.Deployment)
+		template := deployment.Spec.Template.DeepCopy()
+		if template.Labels == nil {
+			template.Labels = make(map[string]string)
+		}
+		template.Labels[appsv1.DefaultDeploymentUniqueLabelKey] = testPodTemplateHash
+		replicaSet := &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            deployment.Name + "-" + testPodTemplateHash,
+				Namespace:       action.GetNamespace(),
+				Labels:          template.Labels,
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(deployment, appsv1.SchemeGroupVersion.WithKind("Deployment"))},
+			},
+			Spec: appsv1.ReplicaSetSpec{Replicas: deployment.Spec.Replicas, Template: *template},
+		}
+		err := kubeClientSet.Tracker().Add(replicaSet)
+		if errors.IsAlreadyExists(err) {
+			err = kubeClientSet.Tracker().Update(appsv1.SchemeGroupVersion.WithResource("replicasets"), replicaSet, replicaSet.Namespace)
+		}
+		return err != nil, nil, err
+	}
+	kubeClientSet.PrependReactor("create", "deployments", createReplicaSet)
+	kubeClientSet.PrependReactor("update", "deployments", createReplicaSet)
+}
+
+// enableTestUpstreamHeader makes the ingress report the new model serving the request,
+// so that predictions are sent to the stub of the ingress
+func enableTestUpstreamHeader(s *Server) {
+	backend := traffic.NewNginxBackend(s.ingressClient)
+	backend.EnableUpstreamHeader()
+	s.SetTrafficBackend(backend)
+}
+
+// enableTestCache starts the informers of both namespaces and makes s read the status from them
+// until the test is finished
+func enableTestCache(t *testing.T, s *Server) {
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+
+	clusterCache := cache.NewCache(s.kubeClientSet, s.ingressClient.APIVersion(), constants.ProdNamespace, constants.CanaryNamespace)
+	clusterCache.Start(stopCh)
+	if !clusterCache.WaitForCacheSync(stopCh) {
+		t.Fatal("The cache is not synced.")
+	}
+	s.EnableCache(clusterCache)
+}
+
+// registerModels registers models without deploying them
+func registerModels(t *testing.T, s *Server, modelNames ...string) {
+	for _, modelName := range modelNames {
+		err := s.registry.Create(context.TODO(), &registry.Model{Name: modelName})
+		if err != nil && err != registry.ErrAlreadyExists {
+			t.Fatal(err)
+		}
+	}
+}
+
+// writeTestCredsFile writes dummy Google application credentials and returns the file path
+func writeTestCredsFile(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "mini-mnist-serving")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	credsFilePath := filepath.Join(dir, "key.json")
+	err = ioutil.WriteFile(credsFilePath, []byte(`{"type": "service_account"}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return credsFilePath
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/metrics"
+)
+
+const (
+	// shadowRequestTimeout limits the shadow request, which no user waits for
+	shadowRequestTimeout     = 10 * time.Second
+	defaultRecentComparisons = 10
+)
+
+// ShadowResponse stores the comparison of the current and new models in Shadow strategy
+type ShadowResponse struct {
+	ModelName string `json:"model-name"`
+	// Active reports whether Shadow strategy is set now
+	Active bool `json:"active"`
+	metrics.ComparisonSummary
+	Recent []metrics.Comparison `json:"recent"`
+}
+
+// ShadowController returns the agreement rate, per-class disagreement and latency delta
+// of the new model to the current model, with the recent predictions of both models
+func (s *Server) ShadowController(w http.ResponseWriter, r *http.Request) {
+	modelName, err := s.getRequestModelName(r.URL.Query().Get("model-name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recent := defaultRecentComparisons
+	if recentStr := r.URL.Query().Get("recent"); recentStr != "" {
+		recent, err = strconv.Atoi(recentStr)
+		if err != nil || recent < 0 {
+			http.Error(w, fmt.Sprintf("Invalid number of recent comparisons: %v", recentStr), http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ShadowResponse{
+		ModelName:         modelName,
+		Active:            s.isShadowStrategy(r.Context(), modelName),
+		ComparisonSummary: s.comparisons.Summary(modelName),
+		Recent:            s.comparisons.Recent(modelName, recent),
+	})
+}
+
+// isShadowStrategy reports whether Shadow strategy is set to model
+func (s *Server) isShadowStrategy(ctx context.Context, modelName string) bool {
+	return s.getRoutingStrategy(ctx, modelName).strategy == constants.Shadow
+}
+
+// shadowPredict sends the copy of prediction request to the new model in the background
+// and records its prediction with the prediction of the current model.
+// The copy is sent by the same protocol as the request to the current model.
+// The request is dropped when too many shadow requests are in flight.
+func (s *Server) shadowPredict(ingressHost string, modelName string, pixels []float32, requestJson []byte, comparison metrics.Comparison) {
+	select {
+	case s.shadowRequests <- struct{}{}:
+	default:
+		log.Printf("Too many shadow requests of %v. The request is dropped.", modelName)
+		return
+	}
+
+	go func() {
+		defer func() { <-s.shadowRequests }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), shadowRequestTimeout)
+		defer cancel()
+
+		start := time.Now()
+		var probabilities []float32
+		var err error
+		if s.grpcClient != nil {
+			probabilities, err = s.sendGRPCSlotPrediction(ctx, modelName, true, pixels)
+		} else {
+			probabilities, err = s.shadowPredictREST(ctx, ingressHost, modelName, requestJson)
+		}
+		comparison.CanaryLatencyMs = float64(time.Since(start)) / float64(time.Millisecond)
+
+		if err != nil {
+			comparison.CanaryError = err.Error()
+		} else {
+			comparison.CanaryPrediction = probabilities
+		}
+		s.comparisons.Record(modelName, comparison)
+	}()
+}
+
+// shadowPredictREST sends the prediction request to the REST API of the new model and returns its probabilities
+func (s *Server) shadowPredictREST(ctx context.Context, ingressHost string, modelName string, requestJson []byte) ([]float32, error) {
+	resp, body, err := s.sendToNewModel(ctx, ingressHost, modelName, requestJson)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Status Code: %d, %v", resp.StatusCode, string(body))
+	}
+	var predResp PredictResponse
+	if err := json.Unmarshal(body, &predResp); err != nil {
+		return nil, err
+	}
+	if len(predResp.Predictions) == 0 {
+		return nil, fmt.Errorf("Invalid prediction response from the model server.")
+	}
+	return predResp.Predictions[0], nil
+}
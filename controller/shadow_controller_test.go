@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/registry"
+	"github.com/josh9191/mini-mnist-serving/traffic"
+)
+
+func getShadow(t *testing.T, s *Server, target string) (*http.Response, ShadowResponse) {
+	w := httptest.NewRecorder()
+	s.ShadowController(w, httptest.NewRequest("GET", target, nil))
+
+	var shadowResponse ShadowResponse
+	json.NewDecoder(w.Result().Body).Decode(&shadowResponse)
+	return w.Result(), shadowResponse
+}
+
+// waitForComparisons waits until n shadow predictions of mnist-cnn are recorded
+func waitForComparisons(t *testing.T, s *Server, n int) ShadowResponse {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, shadowResponse := getShadow(t, s, "/model/shadow?model-name=mnist-cnn")
+		if shadowResponse.Comparisons+shadowResponse.CanaryErrors >= n {
+			return shadowResponse
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Shadow predictions are not recorded: %+v", shadowResponse)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestModelStrategyControllerShadow(t *testing.T) {
+	s, _ := newTestServer()
+	deployBothSlots(t, s)
+
+	resp := setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": constants.Shadow})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+	ingress, err := s.ingressClient.Get(context.TODO(), constants.CanaryNamespace, registry.IngressName("mnist-cnn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingress.Annotations["nginx.ingress.kubernetes.io/canary-by-header"] != constants.CanaryHeader || ingress.Annotations[traffic.ShadowAnnotation] != "true" {
+		t.Errorf("Wrong annotations: %v", ingress.Annotations)
+	}
+	if _, ok := ingress.Annotations["nginx.ingress.kubernetes.io/canary-weight"]; ok {
+		t.Errorf("Canary weight is kept: %v", ingress.Annotations)
+	}
+
+	resp = setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": constants.Canary, "weight": 10})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+	ingress, err = s.ingressClient.Get(context.TODO(), constants.CanaryNamespace, registry.IngressName("mnist-cnn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ingress.Annotations[traffic.ShadowAnnotation]; ok {
+		t.Errorf("Shadow annotation is kept: %v", ingress.Annotations)
+	}
+}
+
+func TestModelPredictControllerWrapperShadow(t *testing.T) {
+	// the new model predicts 3 for the second request
+	var canaryRequests int32
+	modelServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get(constants.CanaryHeader) {
+		case "never":
+			w.Write([]byte(`{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.9, 0.1, 0.0, 0.0, 0.0]]}`))
+		case "always":
+			w.Header().Set(constants.CanaryUpstreamHeader, "mnist-canary-mnist-cnn-svc-8501")
+			if atomic.AddInt32(&canaryRequests, 1) == 2 {
+				w.Write([]byte(`{"predictions": [[0.0, 0.0, 0.0, 0.8, 0.0, 0.2, 0.0, 0.0, 0.0, 0.0]]}`))
+			} else {
+				w.Write([]byte(`{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.7, 0.3, 0.0, 0.0, 0.0]]}`))
+			}
+		default:
+			t.Errorf("Wrong canary header: %q", r.Header.Get(constants.CanaryHeader))
+		}
+	}))
+	defer modelServer.Close()
+	modelServerUrl, _ := url.Parse(modelServer.URL)
+
+	s, _ := newTestServer()
+	enableTestUpstreamHeader(s)
+	deployBothSlots(t, s)
+	resp := setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": constants.Shadow})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+
+	handler := s.ModelPredictControllerWrapper(modelServerUrl.Host)
+	body, _ := json.Marshal(make([]float32, 784))
+	for i := 1; i <= 2; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/model:predict?model-name=mnist-cnn", bytes.NewReader(body)))
+
+		// users get the prediction of the current model
+		var prediction SlotPrediction
+		json.NewDecoder(w.Body).Decode(&prediction)
+		if w.Code != http.StatusOK || prediction.Argmax != 5 || prediction.Slot != ProdSlot {
+			t.Errorf("Wrong prediction: %d, %+v", w.Code, prediction)
+		}
+		waitForComparisons(t, s, i)
+	}
+
+	shadowResponse := waitForComparisons(t, s, 2)
+	if !shadowResponse.Active || shadowResponse.Comparisons != 2 || shadowResponse.AgreementRate != 0.5 {
+		t.Errorf("Wrong agreement: %+v", shadowResponse)
+	}
+	if len(shadowResponse.Classes) != 1 || shadowResponse.Classes[0].Class != 5 || shadowResponse.Classes[0].Disagreements != 1 || shadowResponse.Classes[0].CanaryClasses[3] != 1 {
+		t.Errorf("Wrong classes: %+v", shadowResponse.Classes)
+	}
+	if len(shadowResponse.Recent) != 2 || shadowResponse.Recent[0].CanaryPrediction[3] != 0.8 || shadowResponse.Recent[0].ProdPrediction[5] != 0.9 {
+		t.Errorf("Wrong recent predictions: %+v", shadowResponse.Recent)
+	}
+
+	// the comparisons are reset when Shadow strategy is set again
+	setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": constants.Shadow})
+	_, shadowResponse = getShadow(t, s, "/model/shadow?model-name=mnist-cnn&recent=0")
+	if shadowResponse.Comparisons != 0 || len(shadowResponse.Recent) != 0 {
+		t.Errorf("Comparisons are not reset: %+v", shadowResponse)
+	}
+}
+
+func TestModelPredictControllerWrapperShadowFailure(t *testing.T) {
+	// the ingress doesn't route the shadow request to the new model
+	modelServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"predictions": [[0.0, 0.0, 0.0, 0.0, 0.0, 0.9, 0.1, 0.0, 0.0, 0.0]]}`))
+	}))
+	defer modelServer.Close()
+	modelServerUrl, _ := url.Parse(modelServer.URL)
+
+	s, _ := newTestServer()
+	enableTestUpstreamHeader(s)
+	deployBothSlots(t, s)
+	setStrategy(t, s, map[string]interface{}{"model-name": "mnist-cnn", "strategy": constants.Shadow})
+
+	body, _ := json.Marshal(make([]float32, 784))
+	w := httptest.NewRecorder()
+	s.ModelPredictControllerWrapper(modelServerUrl.Host).ServeHTTP(w, httptest.NewRequest("POST", "/model:predict?model-name=mnist-cnn", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Error - Status Code: %d", w.Code)
+	}
+
+	shadowResponse := waitForComparisons(t, s, 1)
+	if shadowResponse.CanaryErrors != 1 || shadowResponse.Comparisons != 0 || shadowResponse.Recent[0].CanaryError == "" {
+		t.Errorf("Shadow failure is not recorded: %+v", shadowResponse)
+	}
+}
+
+func TestShadowControllerInvalidRequest(t *testing.T) {
+	s, _ := newTestServer()
+	registerModels(t, s, "mnist-cnn")
+
+	for _, target := range []string{"/model/shadow?model-name=mnist-rnn", "/model/shadow?model-name=mnist-cnn&recent=-1", "/model/shadow?model-name=mnist-cnn&recent=all"} {
+		resp, _ := getShadow(t, s, target)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%v: Status Code: %d", target, resp.StatusCode)
+		}
+	}
+
+	resp, shadowResponse := getShadow(t, s, "/model/shadow?model-name=mnist-cnn")
+	if resp.StatusCode != http.StatusOK || shadowResponse.Active || shadowResponse.Comparisons != 0 {
+		t.Errorf("Wrong response without Shadow strategy: %d, %+v", resp.StatusCode, shadowResponse)
+	}
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/registry"
+	"github.com/josh9191/mini-mnist-serving/traffic"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// statusTimeout limits the time reading the status of model from the API server
+const statusTimeout = 10 * time.Second
+
+// SlotStatus stores the deployment of model slot
+type SlotStatus struct {
+	Slot              string `json:"slot"`
+	Deployed          bool   `json:"deployed"`
+	Ready             bool   `json:"ready"`
+	Replicas          int32  `json:"replicas"`
+	ReadyReplicas     int32  `json:"ready-replicas"`
+	AvailableReplicas int32  `json:"available-replicas"`
+	ModelBasePath     string `json:"model-base-path,omitempty"`
+	ServingModelName  string `json:"serving-model-name,omitempty"`
+	Image             string `json:"image,omitempty"`
+	// ChangedAt is when the last revision is deployed
+	ChangedAt *time.Time `json:"changed-at,omitempty"`
+	// Revisions are the revision history from the newest one
+	Revisions []Revision `json:"revisions"`
+	// Rollout is the rollout progress of deployed slot
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+}
+
+// StrategyStatus stores the strategy read back from the traffic backend
+type StrategyStatus struct {
+	Strategy constants.Strategy   `json:"strategy"`
+	Name     string               `json:"name"`
+	Weight   *int                 `json:"weight,omitempty"`
+	Target   *registry.TargetRule `json:"target,omitempty"`
+	// ChangedAt is when the strategy is set last through the server
+	ChangedAt *time.Time `json:"changed-at,omitempty"`
+}
+
+// ModelStatus stores both slots and the strategy of model
+type ModelStatus struct {
+	ModelName      string         `json:"model-name"`
+	TrafficBackend string         `json:"traffic-backend"`
+	Prod           SlotStatus     `json:"prod"`
+	Canary         SlotStatus     `json:"canary"`
+	Strategy       StrategyStatus `json:"strategy"`
+}
+
+// ModelStatusController returns the status of model
+func (s *Server) ModelStatusController(w http.ResponseWriter, r *http.Request) {
+	modelName, err := s.getRequestModelName(r.URL.Query().Get("model-name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), statusTimeout)
+	defer cancel()
+	modelStatus, err := s.getModelStatus(ctx, modelName)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(modelStatus)
+}
+
+// getModelStatus reads the status of model, which is also rendered by the root page
+func (s *Server) getModelStatus(ctx context.Context, modelName string) (*ModelStatus, error) {
+	modelStatus := &ModelStatus{
+		ModelName:      modelName,
+		TrafficBackend: s.traffic.Name(),
+	}
+
+	for _, isNewModel := range []bool{false, true} {
+		slotStatus, err := s.getSlotStatus(ctx, modelName, isNewModel)
+		if err != nil {
+			return nil, err
+		}
+		if isNewModel {
+			modelStatus.Canary = *slotStatus
+		} else {
+			modelStatus.Prod = *slotStatus
+		}
+	}
+
+	routing := s.getRoutingStrategyFrom(ctx, s.cachedTraffic(), modelName)
+	modelStatus.Strategy = StrategyStatus{
+		Strategy: routing.strategy,
+		Name:     traffic.StrategyName(routing.strategy),
+		Target:   routing.target,
+	}
+	if routing.strategy == constants.Canary {
+		weight := routing.weight
+		modelStatus.Strategy.Weight = &weight
+	}
+	// the strategy of unregistered model is never set through the server
+	model, err := s.cachedRegistry().Get(ctx, modelName)
+	if err == nil {
+		modelStatus.Strategy.ChangedAt = model.StrategyChangedAt
+	} else if err != registry.ErrNotFound {
+		return nil, err
+	}
+	return modelStatus, nil
+}
+
+// getSlotStatus reads the deployment of model slot
+func (s *Server) getSlotStatus(ctx context.Context, modelName string, isNewModel bool) (*SlotStatus, error) {
+	slotStatus := &SlotStatus{
+		Slot:      getSlotName(isNewModel),
+		Revisions: []Revision{},
+	}
+
+	deployment, err := s.getCachedDeployment(ctx, modelName, isNewModel)
+	if errors.IsNotFound(err) {
+		return slotStatus, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	slotStatus.Deployed = true
+	slotStatus.Ready = deployment.Status.AvailableReplicas > 0
+	if deployment.Spec.Replicas != nil {
+		slotStatus.Replicas = *deployment.Spec.Replicas
+	}
+	slotStatus.ReadyReplicas = deployment.Status.ReadyReplicas
+	slotStatus.AvailableReplicas = deployment.Status.AvailableReplicas
+	slotStatus.ModelBasePath = getModelBasePath(deployment)
+	slotStatus.ServingModelName = getModelName(deployment)
+	if containers := deployment.Spec.Template.Spec.Containers; len(containers) > 0 {
+		slotStatus.Image = containers[0].Image
+	}
+
+	slotStatus.Revisions = reverseRevisions(getRevisions(deployment))
+	if len(slotStatus.Revisions) > 0 {
+		changedAt := slotStatus.Revisions[0].DeployedAt
+		slotStatus.ChangedAt = &changedAt
+	} else {
+		// deployed before the revision history is recorded
+		changedAt := deployment.CreationTimestamp.Time
+		slotStatus.ChangedAt = &changedAt
+	}
+
+	replicaSets, err := s.listCachedReplicaSets(ctx, modelName, isNewModel)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := s.listCachedPods(ctx, modelName, isNewModel)
+	if err != nil {
+		return nil, err
+	}
+	slotStatus.Rollout = newRolloutStatus(modelName, isNewModel, deployment, replicaSets, pods)
+	return slotStatus, nil
+}
+
+func getSlotName(isNewModel bool) string {
+	if isNewModel {
+		return CanarySlot
+	}
+	return ProdSlot
+}
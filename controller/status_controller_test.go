@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func getModelStatus(t *testing.T, s *Server, modelName string) (int, *ModelStatus) {
+	w := httptest.NewRecorder()
+	s.ModelStatusController(w, httptest.NewRequest("GET", "/model/status?model-name="+modelName, nil))
+
+	var modelStatus ModelStatus
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&modelStatus); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code, &modelStatus
+}
+
+func TestModelStatusController(t *testing.T) {
+	prodDeployment := readyDeployment(constants.ProdNamespace)
+	prodDeployment.Spec.Replicas = int32Ptr(2)
+	prodDeployment.Spec.Template.Spec.Containers = []apiv1.Container{
+		{
+			Image: "tensorflow/serving:2.3.0",
+			Env: []apiv1.EnvVar{
+				{Name: "MODEL_BASE_PATH", Value: "gs://my-bucket/v1"},
+				{Name: "MODEL_NAME", Value: "model"},
+			},
+		},
+	}
+	recordRevision(prodDeployment, Revision{ModelBaseDir: "gs://my-bucket/v1", NumReplicas: 2, ChangeCause: "deploy"})
+
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		strategy constants.Strategy
+		weight   *int
+	}{
+		{
+			name:     "not deployed",
+			strategy: constants.None,
+		},
+		{
+			name:     "canary false",
+			objects:  []runtime.Object{prodDeployment, canaryIngress(map[string]string{"nginx.ingress.kubernetes.io/canary": "false"})},
+			strategy: constants.CurrentModelOnly,
+		},
+		{
+			// nginx sends no request to the canary ingress without weight and header
+			name:     "new canary ingress",
+			objects:  []runtime.Object{prodDeployment, canaryIngress(map[string]string{"nginx.ingress.kubernetes.io/canary": "true"})},
+			strategy: constants.CurrentModelOnly,
+		},
+		{
+			name: "canary weight",
+			objects: []runtime.Object{prodDeployment, canaryIngress(map[string]string{
+				"nginx.ingress.kubernetes.io/canary":        "true",
+				"nginx.ingress.kubernetes.io/canary-weight": "35",
+			})},
+			strategy: constants.Canary,
+			weight:   intPtr(35),
+		},
+		{
+			name: "canary weight 0",
+			objects: []runtime.Object{prodDeployment, canaryIngress(map[string]string{
+				"nginx.ingress.kubernetes.io/canary":        "true",
+				"nginx.ingress.kubernetes.io/canary-weight": "0",
+			})},
+			strategy: constants.Canary,
+			weight:   intPtr(0),
+		},
+	}
+
+	for _, test := range tests {
+		// the status is read from the API server or from the informer cache
+		for _, withCache := range []bool{false, true} {
+			s, kubeClientSet := newTestServer(test.objects...)
+			registerModels(t, s, constants.DefaultModelName)
+			if withCache {
+				enableTestCache(t, s)
+				kubeClientSet.ClearActions()
+			}
+
+			code, modelStatus := getModelStatus(t, s, constants.DefaultModelName)
+			if code != http.StatusOK {
+				t.Fatalf("%v (cache %v): Status Code: %d", test.name, withCache, code)
+			}
+			if withCache {
+				// the registry is read from the cache as well as the root page
+				renderRoot(t, s)
+				if actions := kubeClientSet.Actions(); len(actions) != 0 {
+					t.Errorf("%v: status is read from the API server: %v", test.name, actions)
+				}
+			}
+			if modelStatus.Strategy.Strategy != test.strategy {
+				t.Errorf("%v (cache %v): strategy %v, want %v", test.name, withCache, modelStatus.Strategy.Strategy, test.strategy)
+			}
+			if (modelStatus.Strategy.Weight == nil) != (test.weight == nil) || (test.weight != nil && *modelStatus.Strategy.Weight != *test.weight) {
+				t.Errorf("%v (cache %v): weight %v, want %v", test.name, withCache, modelStatus.Strategy.Weight, test.weight)
+			}
+			if modelStatus.Canary.Deployed || modelStatus.Canary.Slot != CanarySlot {
+				t.Errorf("%v (cache %v): wrong new model: %+v", test.name, withCache, modelStatus.Canary)
+			}
+			if test.objects == nil {
+				continue
+			}
+
+			prod := modelStatus.Prod
+			if !prod.Deployed || !prod.Ready || prod.Replicas != 2 || prod.AvailableReplicas != 1 {
+				t.Errorf("%v (cache %v): wrong replicas of current model: %+v", test.name, withCache, prod)
+			}
+			if prod.ModelBasePath != "gs://my-bucket/v1" || prod.ServingModelName != "model" || prod.Image != "tensorflow/serving:2.3.0" {
+				t.Errorf("%v (cache %v): wrong model of current model: %+v", test.name, withCache, prod)
+			}
+			if len(prod.Revisions) != 1 || prod.ChangedAt == nil || !prod.ChangedAt.Equal(prod.Revisions[0].DeployedAt) {
+				t.Errorf("%v (cache %v): wrong revisions of current model: %+v", test.name, withCache, prod)
+			}
+		}
+	}
+}
+
+func TestModelStatusControllerStrategyChangedAt(t *testing.T) {
+	s, _ := newTestServer(canaryIngress(map[string]string{"nginx.ingress.kubernetes.io/canary": "true"}))
+	registerModels(t, s, constants.DefaultModelName)
+
+	_, modelStatus := getModelStatus(t, s, constants.DefaultModelName)
+	if modelStatus.Strategy.ChangedAt != nil {
+		t.Errorf("Strategy is never changed: %v", modelStatus.Strategy.ChangedAt)
+	}
+
+	resp := setStrategy(t, s, map[string]interface{}{"strategy": constants.Canary, "weight": 40})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Error - Status Code: %d", resp.StatusCode)
+	}
+	_, modelStatus = getModelStatus(t, s, constants.DefaultModelName)
+	if modelStatus.Strategy.Name != "Canary" || *modelStatus.Strategy.Weight != 40 || modelStatus.Strategy.ChangedAt == nil {
+		t.Errorf("Wrong strategy: %+v", modelStatus.Strategy)
+	}
+
+	if code, _ := getModelStatus(t, s, "unknown"); code != http.StatusBadRequest {
+		t.Errorf("Status of unknown model: %d", code)
+	}
+}
+
+func intPtr(i int) *int { return &i }
@@ -3,8 +3,12 @@ module github.com/josh9191/mini-mnist-serving
 go 1.14
 
 require (
+	github.com/golang/protobuf v1.4.3
 	github.com/gorilla/mux v1.8.0
+	google.golang.org/grpc v1.43.0
+	google.golang.org/protobuf v1.25.0
 	k8s.io/api v0.19.0
 	k8s.io/apimachinery v0.19.0
 	k8s.io/client-go v0.19.0
+	sigs.k8s.io/yaml v1.2.0
 )
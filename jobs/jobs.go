@@ -0,0 +1,319 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// State is the state of job or step
+type State string
+
+const (
+	Pending   State = "Pending"
+	Running   State = "Running"
+	Succeeded State = "Succeeded"
+	Failed    State = "Failed"
+	Canceled  State = "Canceled"
+)
+
+var (
+	// ErrNotFound is returned when the job doesn't exist or is evicted
+	ErrNotFound = errors.New("The job is not found.")
+	// ErrFinished is returned when canceling a finished job
+	ErrFinished = errors.New("The job is finished already.")
+)
+
+// Step stores the progress of a step of job
+type Step struct {
+	Name       string     `json:"name"`
+	State      State      `json:"state"`
+	Message    string     `json:"message,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  *time.Time `json:"started-at,omitempty"`
+	FinishedAt *time.Time `json:"finished-at,omitempty"`
+}
+
+// Job stores the progress of a long running operation
+type Job struct {
+	ID         string      `json:"id"`
+	Kind       string      `json:"kind"`
+	Key        string      `json:"key"`
+	State      State       `json:"state"`
+	Steps      []Step      `json:"steps"`
+	Error      string      `json:"error,omitempty"`
+	Result     interface{} `json:"result,omitempty"`
+	CreatedAt  time.Time   `json:"created-at"`
+	StartedAt  *time.Time  `json:"started-at,omitempty"`
+	FinishedAt *time.Time  `json:"finished-at,omitempty"`
+}
+
+// Finished reports whether the job is succeeded, failed or canceled
+func (j *Job) Finished() bool {
+	return j.State == Succeeded || j.State == Failed || j.State == Canceled
+}
+
+// RunFunc runs a job and returns its result
+type RunFunc func(ctx context.Context, progress *Progress) (interface{}, error)
+
+// Manager runs jobs in the background and keeps the recent jobs in memory
+// Jobs of the same key run one by one in the order they are started.
+type Manager struct {
+	mu      sync.Mutex
+	jobs    map[string]*entry
+	order   []string
+	tails   map[string]chan struct{}
+	maxJobs int
+}
+
+type entry struct {
+	job    Job
+	cancel context.CancelFunc
+	done   chan struct{}
+	// released is closed when the next job of the same key can run
+	released chan struct{}
+}
+
+// NewManager returns Manager keeping at most maxJobs finished jobs
+func NewManager(maxJobs int) *Manager {
+	return &Manager{
+		jobs:    make(map[string]*entry),
+		tails:   make(map[string]chan struct{}),
+		maxJobs: maxJobs,
+	}
+}
+
+// Start creates a job with the steps and runs it in the background
+func (m *Manager) Start(kind string, key string, stepNames []string, run RunFunc) Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &entry{
+		job: Job{
+			ID:        newID(),
+			Kind:      kind,
+			Key:       key,
+			State:     Pending,
+			Steps:     make([]Step, len(stepNames)),
+			CreatedAt: time.Now().UTC(),
+		},
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		released: make(chan struct{}),
+	}
+	for i, name := range stepNames {
+		e.job.Steps[i] = Step{Name: name, State: Pending}
+	}
+
+	m.mu.Lock()
+	m.jobs[e.job.ID] = e
+	m.order = append(m.order, e.job.ID)
+	previous := m.tails[key]
+	m.tails[key] = e.released
+	m.evict()
+	job := copyJob(&e.job)
+	m.mu.Unlock()
+
+	go m.run(ctx, e, previous, run)
+	return job
+}
+
+// Get returns the job
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return copyJob(&e.job), nil
+}
+
+// List returns the jobs from the oldest one
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]Job, 0, len(m.order))
+	for _, id := range m.order {
+		jobs = append(jobs, copyJob(&m.jobs[id].job))
+	}
+	return jobs
+}
+
+// Cancel stops the job
+// The running step is interrupted through the context and the remaining steps are not run.
+func (m *Manager) Cancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if e.job.Finished() {
+		return ErrFinished
+	}
+	e.cancel()
+	return nil
+}
+
+// Wait blocks until the job is finished and returns it
+func (m *Manager) Wait(ctx context.Context, id string) (Job, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+
+	select {
+	case <-e.done:
+		return m.Get(id)
+	case <-ctx.Done():
+		return Job{}, ctx.Err()
+	}
+}
+
+func (m *Manager) run(ctx context.Context, e *entry, previous chan struct{}, run RunFunc) {
+	defer m.release(e, previous)
+	defer close(e.done)
+	defer e.cancel()
+
+	// wait for the previous job of the same key
+	if previous != nil {
+		select {
+		case <-previous:
+		case <-ctx.Done():
+			m.finish(e, nil, ctx.Err(), true)
+			return
+		}
+	}
+
+	m.mu.Lock()
+	now := time.Now().UTC()
+	e.job.State = Running
+	e.job.StartedAt = &now
+	m.mu.Unlock()
+
+	result, err := run(ctx, &Progress{manager: m, entry: e, ctx: ctx})
+	// errors of canceled API calls don't wrap context.Canceled, so check the context
+	m.finish(e, result, err, ctx.Err() == context.Canceled)
+}
+
+func (m *Manager) finish(e *entry, result interface{}, err error, canceled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	e.job.FinishedAt = &now
+	e.job.Result = result
+	if err == nil {
+		e.job.State = Succeeded
+	} else {
+		e.job.Error = err.Error()
+		e.job.State = Failed
+		if canceled {
+			e.job.State = Canceled
+		}
+	}
+}
+
+// release lets the next job of the same key run after the previous jobs are released
+// so that a job canceled while pending doesn't let the next one overtake a running job.
+func (m *Manager) release(e *entry, previous chan struct{}) {
+	if previous != nil {
+		<-previous
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	close(e.released)
+	if m.tails[e.job.Key] == e.released {
+		delete(m.tails, e.job.Key)
+	}
+}
+
+// evict removes the oldest finished jobs exceeding maxJobs
+func (m *Manager) evict() {
+	for i := 0; len(m.order) > m.maxJobs && i < len(m.order); {
+		id := m.order[i]
+		if m.jobs[id].job.Finished() {
+			delete(m.jobs, id)
+			m.order = append(m.order[:i], m.order[i+1:]...)
+		} else {
+			i++
+		}
+	}
+}
+
+// Progress records the steps of running job
+type Progress struct {
+	manager *Manager
+	entry   *entry
+	ctx     context.Context
+}
+
+// Run runs the step and records its message or error
+// The step is not run when the job is canceled.
+func (p *Progress) Run(name string, step func() (string, error)) error {
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+	p.update(name, func(s *Step) {
+		now := time.Now().UTC()
+		s.State = Running
+		s.StartedAt = &now
+	})
+
+	message, err := step()
+	if err == nil && p.ctx.Err() != nil {
+		err = p.ctx.Err()
+	}
+	p.update(name, func(s *Step) {
+		now := time.Now().UTC()
+		s.FinishedAt = &now
+		s.Message = message
+		if err == nil {
+			s.State = Succeeded
+		} else if p.ctx.Err() == context.Canceled {
+			s.State = Canceled
+			s.Error = err.Error()
+		} else {
+			s.State = Failed
+			s.Error = err.Error()
+		}
+	})
+	return err
+}
+
+func (p *Progress) update(name string, modify func(s *Step)) {
+	p.manager.mu.Lock()
+	defer p.manager.mu.Unlock()
+
+	steps := p.entry.job.Steps
+	for i := range steps {
+		if steps[i].Name == name {
+			modify(&steps[i])
+			return
+		}
+	}
+	// steps not declared when the job is started
+	step := Step{Name: name}
+	modify(&step)
+	p.entry.job.Steps = append(steps, step)
+}
+
+func copyJob(job *Job) Job {
+	copied := *job
+	copied.Steps = append([]Step(nil), job.Steps...)
+	return copied
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
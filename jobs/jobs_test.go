@@ -0,0 +1,170 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func wait(t *testing.T, m *Manager, id string) Job {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	job, err := m.Wait(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestManager(t *testing.T) {
+	m := NewManager(10)
+
+	job := m.Start("deploy", "mnist-prod/model", []string{"first", "second", "third"}, func(ctx context.Context, progress *Progress) (interface{}, error) {
+		if err := progress.Run("first", func() (string, error) { return "done", nil }); err != nil {
+			return nil, err
+		}
+		if err := progress.Run("second", func() (string, error) { return "", errors.New("failure") }); err != nil {
+			return "partial", err
+		}
+		return nil, progress.Run("third", func() (string, error) { return "", nil })
+	})
+	if job.ID == "" || job.State != Pending || len(job.Steps) != 3 {
+		t.Fatalf("Wrong started job: %+v", job)
+	}
+
+	job = wait(t, m, job.ID)
+	if job.State != Failed || job.Error != "failure" || job.Result != "partial" {
+		t.Errorf("Wrong job: %+v", job)
+	}
+	if job.StartedAt == nil || job.FinishedAt == nil {
+		t.Errorf("Timestamps are not set: %+v", job)
+	}
+	for i, want := range []Step{
+		{Name: "first", State: Succeeded, Message: "done"},
+		{Name: "second", State: Failed, Error: "failure"},
+		{Name: "third", State: Pending},
+	} {
+		step := job.Steps[i]
+		if step.Name != want.Name || step.State != want.State || step.Message != want.Message || step.Error != want.Error {
+			t.Errorf("Wrong step: %+v", step)
+		}
+		if (step.State == Pending) != (step.StartedAt == nil) {
+			t.Errorf("Wrong start time: %+v", step)
+		}
+	}
+
+	if err := m.Cancel(job.ID); err != ErrFinished {
+		t.Errorf("Canceling finished job returned %v", err)
+	}
+	if err := m.Cancel("unknown"); err != ErrNotFound {
+		t.Errorf("Canceling unknown job returned %v", err)
+	}
+	if _, err := m.Get("unknown"); err != ErrNotFound {
+		t.Errorf("Unknown job is found: %v", err)
+	}
+}
+
+func TestManagerCancel(t *testing.T) {
+	m := NewManager(10)
+
+	started := make(chan struct{})
+	running := m.Start("deploy", "key", []string{"block", "next"}, func(ctx context.Context, progress *Progress) (interface{}, error) {
+		err := progress.Run("block", func() (string, error) {
+			close(started)
+			<-ctx.Done()
+			return "", ctx.Err()
+		})
+		if err != nil {
+			return nil, err
+		}
+		return nil, progress.Run("next", func() (string, error) { return "", nil })
+	})
+	// the pending job of the same key is canceled before running
+	pending := m.Start("deploy", "key", nil, func(ctx context.Context, progress *Progress) (interface{}, error) {
+		t.Errorf("Canceled job is run")
+		return nil, nil
+	})
+
+	<-started
+	for _, id := range []string{pending.ID, running.ID} {
+		if err := m.Cancel(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	job := wait(t, m, running.ID)
+	if job.State != Canceled || job.Steps[0].State != Canceled || job.Steps[1].State != Pending {
+		t.Errorf("Wrong canceled job: %+v", job)
+	}
+	job = wait(t, m, pending.ID)
+	if job.State != Canceled || job.StartedAt != nil {
+		t.Errorf("Wrong canceled job: %+v", job)
+	}
+}
+
+func TestManagerSerializesJobsOfSameKey(t *testing.T) {
+	m := NewManager(100)
+
+	var mu sync.Mutex
+	running := map[string]int{}
+	ids := []string{}
+	for i := 0; i < 20; i++ {
+		key := []string{"a", "b"}[i%2]
+		job := m.Start("deploy", key, nil, func(ctx context.Context, progress *Progress) (interface{}, error) {
+			mu.Lock()
+			running[key]++
+			if running[key] > 1 {
+				t.Errorf("Jobs of %v run concurrently", key)
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			running[key]--
+			mu.Unlock()
+			return nil, nil
+		})
+		ids = append(ids, job.ID)
+	}
+
+	for _, id := range ids {
+		if job := wait(t, m, id); job.State != Succeeded {
+			t.Errorf("Wrong job: %+v", job)
+		}
+	}
+	if jobs := m.List(); len(jobs) != 20 || jobs[0].ID != ids[0] {
+		t.Errorf("Jobs are not listed in order")
+	}
+}
+
+func TestManagerEvictsFinishedJobs(t *testing.T) {
+	m := NewManager(2)
+
+	block := make(chan struct{})
+	running := m.Start("deploy", "running", nil, func(ctx context.Context, progress *Progress) (interface{}, error) {
+		<-block
+		return nil, nil
+	})
+	defer close(block)
+
+	ids := []string{}
+	for i := 0; i < 3; i++ {
+		job := m.Start("deploy", "finished", nil, func(ctx context.Context, progress *Progress) (interface{}, error) {
+			return nil, nil
+		})
+		wait(t, m, job.ID)
+		ids = append(ids, job.ID)
+	}
+
+	// the running job is kept even if it's the oldest one
+	jobs := m.List()
+	if len(jobs) != 2 || jobs[0].ID != running.ID || jobs[1].ID != ids[2] {
+		t.Errorf("Wrong jobs are evicted: %+v", jobs)
+	}
+	if _, err := m.Get(ids[0]); err != ErrNotFound {
+		t.Errorf("Evicted job is found: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Comparison stores the predictions of the current and new models for the same input
+// CanaryError is set instead of CanaryPrediction when the new model fails.
+type Comparison struct {
+	Time             time.Time `json:"time"`
+	ProdPrediction   []float32 `json:"prod-prediction"`
+	CanaryPrediction []float32 `json:"canary-prediction,omitempty"`
+	ProdLatencyMs    float64   `json:"prod-latency-ms"`
+	CanaryLatencyMs  float64   `json:"canary-latency-ms"`
+	CanaryError      string    `json:"canary-error,omitempty"`
+}
+
+// ClassAgreement stores how often the new model disagrees when the current model predicts a class
+type ClassAgreement struct {
+	Class            int     `json:"class"`
+	Comparisons      int     `json:"comparisons"`
+	Disagreements    int     `json:"disagreements"`
+	DisagreementRate float64 `json:"disagreement-rate"`
+	// CanaryClasses counts the classes predicted by the new model
+	CanaryClasses map[int]int `json:"canary-classes"`
+}
+
+// ComparisonSummary stores the agreement and latency differences of the compared predictions
+type ComparisonSummary struct {
+	Comparisons   int              `json:"comparisons"`
+	CanaryErrors  int              `json:"canary-errors"`
+	Agreements    int              `json:"agreements"`
+	AgreementRate float64          `json:"agreement-rate"`
+	Classes       []ClassAgreement `json:"classes"`
+	// latency of the new model minus latency of the current model
+	MeanLatencyDeltaMs float64 `json:"mean-latency-delta-ms"`
+	P95LatencyDeltaMs  float64 `json:"p95-latency-delta-ms"`
+}
+
+// ComparisonRecorder keeps the recent comparisons of each key in memory
+type ComparisonRecorder struct {
+	mu             sync.Mutex
+	comparisons    map[string][]Comparison
+	maxComparisons int
+}
+
+// NewComparisonRecorder returns ComparisonRecorder keeping at most maxComparisons comparisons per key
+func NewComparisonRecorder(maxComparisons int) *ComparisonRecorder {
+	return &ComparisonRecorder{
+		comparisons:    make(map[string][]Comparison),
+		maxComparisons: maxComparisons,
+	}
+}
+
+// Record appends the comparison of key, dropping the oldest one when full
+func (r *ComparisonRecorder) Record(key string, comparison Comparison) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comparisons := append(r.comparisons[key], comparison)
+	if len(comparisons) > r.maxComparisons {
+		comparisons = comparisons[len(comparisons)-r.maxComparisons:]
+	}
+	r.comparisons[key] = comparisons
+}
+
+// Reset removes the comparisons of key
+func (r *ComparisonRecorder) Reset(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.comparisons, key)
+}
+
+// Recent returns at most n comparisons of key from the newest one
+func (r *ComparisonRecorder) Recent(key string, n int) []Comparison {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comparisons := r.comparisons[key]
+	recent := []Comparison{}
+	for i := len(comparisons) - 1; i >= 0 && len(recent) < n; i-- {
+		recent = append(recent, comparisons[i])
+	}
+	return recent
+}
+
+// Summary returns the agreement of the comparisons of key
+// Failed predictions of the new model are counted only in CanaryErrors.
+func (r *ComparisonRecorder) Summary(key string) ComparisonSummary {
+	r.mu.Lock()
+	comparisons := append([]Comparison{}, r.comparisons[key]...)
+	r.mu.Unlock()
+
+	summary := ComparisonSummary{Classes: []ClassAgreement{}}
+	classes := make(map[int]*ClassAgreement)
+	deltas := []float64{}
+	for _, comparison := range comparisons {
+		if comparison.CanaryError != "" {
+			summary.CanaryErrors++
+			continue
+		}
+		summary.Comparisons++
+
+		prodClass := Argmax(comparison.ProdPrediction)
+		canaryClass := Argmax(comparison.CanaryPrediction)
+		class, ok := classes[prodClass]
+		if !ok {
+			class = &ClassAgreement{Class: prodClass, CanaryClasses: make(map[int]int)}
+			classes[prodClass] = class
+		}
+		class.Comparisons++
+		class.CanaryClasses[canaryClass]++
+		if prodClass == canaryClass {
+			summary.Agreements++
+		} else {
+			class.Disagreements++
+		}
+		deltas = append(deltas, comparison.CanaryLatencyMs-comparison.ProdLatencyMs)
+	}
+	if summary.Comparisons == 0 {
+		return summary
+	}
+	summary.AgreementRate = float64(summary.Agreements) / float64(summary.Comparisons)
+
+	for _, class := range classes {
+		class.DisagreementRate = float64(class.Disagreements) / float64(class.Comparisons)
+		summary.Classes = append(summary.Classes, *class)
+	}
+	sort.Slice(summary.Classes, func(i, j int) bool { return summary.Classes[i].Class < summary.Classes[j].Class })
+
+	total := 0.0
+	for _, delta := range deltas {
+		total += delta
+	}
+	summary.MeanLatencyDeltaMs = total / float64(len(deltas))
+	sort.Float64s(deltas)
+	summary.P95LatencyDeltaMs = deltas[p95Rank(len(deltas))]
+	return summary
+}
+
+// Argmax returns the index of the largest probability, or -1 when there is no probability
+func Argmax(probabilities []float32) int {
+	argmax := -1
+	for i, probability := range probabilities {
+		if argmax < 0 || probability > probabilities[argmax] {
+			argmax = i
+		}
+	}
+	return argmax
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+// prediction returns probabilities predicting class
+func prediction(class int) []float32 {
+	probabilities := make([]float32, 10)
+	probabilities[class] = 0.9
+	return probabilities
+}
+
+func TestComparisonRecorder(t *testing.T) {
+	r := NewComparisonRecorder(100)
+
+	if summary := r.Summary("mnist-cnn"); summary.Comparisons != 0 || len(summary.Classes) != 0 {
+		t.Errorf("Summary of unknown key: %+v", summary)
+	}
+
+	for _, comparison := range []Comparison{
+		{ProdPrediction: prediction(7), CanaryPrediction: prediction(7), ProdLatencyMs: 10, CanaryLatencyMs: 12},
+		{ProdPrediction: prediction(7), CanaryPrediction: prediction(1), ProdLatencyMs: 10, CanaryLatencyMs: 14},
+		{ProdPrediction: prediction(3), CanaryPrediction: prediction(3), ProdLatencyMs: 10, CanaryLatencyMs: 8},
+		{ProdPrediction: prediction(7), CanaryPrediction: prediction(7), ProdLatencyMs: 10, CanaryLatencyMs: 10},
+		{ProdPrediction: prediction(3), CanaryError: "connection refused", ProdLatencyMs: 10},
+	} {
+		comparison.Time = time.Now()
+		r.Record("mnist-cnn", comparison)
+	}
+
+	summary := r.Summary("mnist-cnn")
+	if summary.Comparisons != 4 || summary.CanaryErrors != 1 || summary.Agreements != 3 || summary.AgreementRate != 0.75 {
+		t.Errorf("Wrong agreement: %+v", summary)
+	}
+	if summary.MeanLatencyDeltaMs != 1 || summary.P95LatencyDeltaMs != 4 {
+		t.Errorf("Wrong latency delta: %+v", summary)
+	}
+	if len(summary.Classes) != 2 {
+		t.Fatalf("Wrong classes: %+v", summary.Classes)
+	}
+	three, seven := summary.Classes[0], summary.Classes[1]
+	if three.Class != 3 || three.Comparisons != 1 || three.Disagreements != 0 {
+		t.Errorf("Wrong class 3: %+v", three)
+	}
+	if seven.Class != 7 || seven.Comparisons != 3 || seven.Disagreements != 1 || seven.CanaryClasses[1] != 1 || seven.CanaryClasses[7] != 2 {
+		t.Errorf("Wrong class 7: %+v", seven)
+	}
+
+	recent := r.Recent("mnist-cnn", 2)
+	if len(recent) != 2 || recent[0].CanaryError == "" {
+		t.Errorf("Wrong recent comparisons: %+v", recent)
+	}
+
+	r.Reset("mnist-cnn")
+	if summary := r.Summary("mnist-cnn"); summary.Comparisons != 0 || summary.CanaryErrors != 0 {
+		t.Errorf("Comparisons are not reset: %+v", summary)
+	}
+}
+
+func TestArgmax(t *testing.T) {
+	if argmax := Argmax([]float32{0.1, 0.7, 0.2}); argmax != 1 {
+		t.Errorf("Wrong argmax: %d", argmax)
+	}
+	if argmax := Argmax(nil); argmax != -1 {
+		t.Errorf("Argmax of no probability: %d", argmax)
+	}
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Observation stores the result of a proxied request
+type Observation struct {
+	Time    time.Time
+	Latency time.Duration
+	Failed  bool
+}
+
+// Stats stores the error rate and latency of requests
+type Stats struct {
+	Requests      int     `json:"requests"`
+	Errors        int     `json:"errors"`
+	ErrorRate     float64 `json:"error-rate"`
+	MeanLatencyMs float64 `json:"mean-latency-ms"`
+	P95LatencyMs  float64 `json:"p95-latency-ms"`
+}
+
+// Recorder keeps the recent observations of each key in memory
+type Recorder struct {
+	mu              sync.Mutex
+	observations    map[string][]Observation
+	maxObservations int
+}
+
+// NewRecorder returns Recorder keeping at most maxObservations observations per key
+func NewRecorder(maxObservations int) *Recorder {
+	return &Recorder{
+		observations:    make(map[string][]Observation),
+		maxObservations: maxObservations,
+	}
+}
+
+// Record appends the observation of key, dropping the oldest one when full
+func (r *Recorder) Record(key string, observation Observation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	observations := append(r.observations[key], observation)
+	if len(observations) > r.maxObservations {
+		observations = observations[len(observations)-r.maxObservations:]
+	}
+	r.observations[key] = observations
+}
+
+// Stats returns the statistics of observations of key made at or after since
+func (r *Recorder) Stats(key string, since time.Time) Stats {
+	r.mu.Lock()
+	latencies := []time.Duration{}
+	stats := Stats{}
+	for _, observation := range r.observations[key] {
+		if observation.Time.Before(since) {
+			continue
+		}
+		stats.Requests++
+		if observation.Failed {
+			stats.Errors++
+		}
+		latencies = append(latencies, observation.Latency)
+	}
+	r.mu.Unlock()
+
+	if stats.Requests == 0 {
+		return stats
+	}
+	stats.ErrorRate = float64(stats.Errors) / float64(stats.Requests)
+
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	stats.MeanLatencyMs = milliseconds(total / time.Duration(len(latencies)))
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	stats.P95LatencyMs = milliseconds(latencies[p95Rank(len(latencies))])
+	return stats
+}
+
+// p95Rank returns the index of the nearest-rank 95th percentile of n sorted values
+func p95Rank(n int) int {
+	return (95*n+99)/100 - 1
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(100)
+	start := time.Now()
+
+	if stats := r.Stats("mnist-prod/mnist-cnn", start); stats != (Stats{}) {
+		t.Errorf("Stats of unknown key: %+v", stats)
+	}
+
+	// observations before the window are ignored
+	r.Record("mnist-prod/mnist-cnn", Observation{Time: start.Add(-time.Second), Latency: time.Hour, Failed: true})
+	for i := 1; i <= 20; i++ {
+		r.Record("mnist-prod/mnist-cnn", Observation{
+			Time:    start.Add(time.Duration(i) * time.Millisecond),
+			Latency: time.Duration(i) * time.Millisecond,
+			Failed:  i%10 == 0,
+		})
+	}
+	r.Record("mnist-canary/mnist-cnn", Observation{Time: start, Latency: time.Second})
+
+	stats := r.Stats("mnist-prod/mnist-cnn", start)
+	if stats.Requests != 20 || stats.Errors != 2 || stats.ErrorRate != 0.1 {
+		t.Errorf("Wrong error rate: %+v", stats)
+	}
+	if stats.MeanLatencyMs != 10.5 || stats.P95LatencyMs != 19 {
+		t.Errorf("Wrong latency: %+v", stats)
+	}
+
+	stats = r.Stats("mnist-canary/mnist-cnn", start)
+	if stats.Requests != 1 || stats.P95LatencyMs != 1000 {
+		t.Errorf("Wrong stats of another key: %+v", stats)
+	}
+}
+
+func TestRecorderDropsOldestObservations(t *testing.T) {
+	r := NewRecorder(3)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		r.Record("key", Observation{Time: start, Latency: time.Duration(i) * time.Millisecond})
+	}
+
+	stats := r.Stats("key", start)
+	if stats.Requests != 3 || stats.MeanLatencyMs != 3 {
+		t.Errorf("Wrong observations are kept: %+v", stats)
+	}
+}
@@ -0,0 +1,115 @@
+package preprocess
+
+import "math"
+
+const (
+	// digitSize is the size of the box which the digit is fitted into like MNIST
+	digitSize = 20
+	// inkThreshold is the minimum value of the pixels in the bounding box of the digit
+	// so that faint noise such as JPEG artifacts doesn't enlarge the box
+	inkThreshold = 0.1
+)
+
+// normalizeMNIST returns the 28x28 plane of the digit in ink, which is light on dark background, normalized like MNIST
+//  1. crop to the bounding box of the digit
+//  2. scale into 20x20 preserving the aspect ratio, where area averaging anti-aliases the strokes
+//  3. stretch the contrast so that the darkest stroke is 1
+//  4. place it in 28x28 so that its center of mass is at the center, keeping the whole digit inside
+func normalizeMNIST(ink *plane) *plane {
+	dst := newPlane(Size, Size)
+	left, top, right, bottom, ok := ink.boundingBox(inkThreshold)
+	if !ok {
+		return dst
+	}
+
+	width, height := right-left, bottom-top
+	scale := float64(digitSize) / math.Max(float64(width), float64(height))
+	digit := resize(ink.crop(left, top, right, bottom), scaledSize(width, scale), scaledSize(height, scale))
+	digit.stretch()
+
+	centerX, centerY := digit.centerOfMass()
+	offsetX := clampOffset(float64(Size)/2-centerX, Size-digit.width)
+	offsetY := clampOffset(float64(Size)/2-centerY, Size-digit.height)
+	for y := 0; y < digit.height; y++ {
+		for x := 0; x < digit.width; x++ {
+			dst.set(offsetX+x, offsetY+y, digit.at(x, y))
+		}
+	}
+	return dst
+}
+
+// boundingBox returns the smallest rectangle [left, right) x [top, bottom) containing the pixels over threshold
+// ok is false if there is no such pixel.
+func (p *plane) boundingBox(threshold float64) (left int, top int, right int, bottom int, ok bool) {
+	left, top = p.width, p.height
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			if p.at(x, y) <= threshold {
+				continue
+			}
+			if x < left {
+				left = x
+			}
+			if x >= right {
+				right = x + 1
+			}
+			if y < top {
+				top = y
+			}
+			if y >= bottom {
+				bottom = y + 1
+			}
+		}
+	}
+	return left, top, right, bottom, right > left
+}
+
+// crop returns the plane of [left, right) x [top, bottom) of p
+func (p *plane) crop(left int, top int, right int, bottom int) *plane {
+	dst := newPlane(right-left, bottom-top)
+	for y := 0; y < dst.height; y++ {
+		copy(dst.pix[y*dst.width:(y+1)*dst.width], p.pix[(top+y)*p.width+left:(top+y)*p.width+right])
+	}
+	return dst
+}
+
+// stretch scales the pixels of p so that the maximum is 1
+func (p *plane) stretch() {
+	var max float64
+	for _, value := range p.pix {
+		max = math.Max(max, value)
+	}
+	if max <= 0 {
+		return
+	}
+	for i := range p.pix {
+		p.pix[i] /= max
+	}
+}
+
+// centerOfMass returns the center of mass of p weighted by the pixels, where the center of the pixel (x, y) is (x+0.5, y+0.5)
+func (p *plane) centerOfMass() (float64, float64) {
+	var sumX, sumY, mass float64
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			value := p.at(x, y)
+			sumX += value * (float64(x) + 0.5)
+			sumY += value * (float64(y) + 0.5)
+			mass += value
+		}
+	}
+	if mass == 0 {
+		return float64(p.width) / 2, float64(p.height) / 2
+	}
+	return sumX / mass, sumY / mass
+}
+
+// scaledSize returns the size scaled by scale, which is at least 1 pixel
+func scaledSize(size int, scale float64) int {
+	return int(math.Max(1, math.Round(float64(size)*scale)))
+}
+
+// clampOffset rounds offset to the nearest pixel from 0 to max
+func clampOffset(offset float64, max int) int {
+	return int(math.Max(0, math.Min(float64(max), math.Round(offset))))
+}
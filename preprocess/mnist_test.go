@@ -0,0 +1,228 @@
+package preprocess
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+// fillImage returns the white image of width x height with black rectangles
+func fillImage(width int, height int, rects ...image.Rectangle) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			value := uint8(255)
+			for _, rect := range rects {
+				if image.Pt(x, y).In(rect) {
+					value = 0
+				}
+			}
+			img.SetGray(x, y, color.Gray{Y: value})
+		}
+	}
+	return img
+}
+
+// checkRects checks that the pixels in rects are 1 and the others are 0
+func checkRects(t *testing.T, name string, pixels []float32, rects ...image.Rectangle) {
+	for i, pixel := range pixels {
+		want := float32(0)
+		for _, rect := range rects {
+			if image.Pt(i%Size, i/Size).In(rect) {
+				want = 1
+			}
+		}
+		if !almostEqual(pixel, want) {
+			t.Errorf("%v: pixel (%d, %d) is %v, want %v", name, i%Size, i/Size, pixel, want)
+			return
+		}
+	}
+}
+
+func TestParseNormalization(t *testing.T) {
+	for name, want := range map[string]Normalization{"": Resize, "resize": Resize, "mnist": MNIST} {
+		if normalization, err := ParseNormalization(name); err != nil || normalization != want {
+			t.Errorf("%q: %v, %v", name, normalization, err)
+		}
+	}
+	if _, err := ParseNormalization("MNIST"); err == nil {
+		t.Errorf("Unknown normalization is parsed")
+	}
+}
+
+func TestNormalizeMNIST(t *testing.T) {
+	options := Options{Invert: true, Normalization: MNIST}
+	for _, test := range []struct {
+		name string
+		img  image.Image
+		want []image.Rectangle
+	}{
+		// 10x40 is scaled to 5x20 and its center (2.5, 10) is moved to (14, 14)
+		{"tall bar", fillImage(40, 80, image.Rect(10, 20, 20, 60)), []image.Rectangle{image.Rect(12, 4, 17, 24)}},
+		{"wide bar", fillImage(100, 30, image.Rect(20, 10, 80, 25)), []image.Rectangle{image.Rect(4, 12, 24, 17)}},
+		// the center of mass (6.44, 6.44) of the 20x20 box is moved to (14, 14) instead of the center of the box
+		{"corner", fillImage(100, 100, image.Rect(30, 50, 70, 58), image.Rect(30, 58, 38, 90)), []image.Rectangle{image.Rect(8, 8, 28, 12), image.Rect(8, 12, 12, 28)}},
+		{"blank", fillImage(50, 50), nil},
+	} {
+		checkRects(t, test.name, Pixels(test.img, options), test.want...)
+	}
+}
+
+func TestNormalizeMNISTHandComputed(t *testing.T) {
+	// a "T" whose 40x40 bounding box is halved into 20x20, so that every pixel is the average of 2x2 source pixels:
+	// the bar of 3 rows and the stem of 5 columns end in the middle of a pixel, which becomes gray
+	img := fillImage(60, 60, image.Rect(10, 10, 50, 13), image.Rect(28, 10, 33, 50))
+	pixels := Pixels(img, Options{Invert: true, Normalization: MNIST})
+
+	// the 20x20 digit has the mass 76.25 = 20 (row 0) + 11.25 (row 1) + 18 * 2.5 (stem)
+	// and the center of mass (776.375 / 76.25, 521.875 / 76.25) = (10.18, 6.84),
+	// which is moved to (14, 14) by the offset (4, 7)
+	want := make([]float32, Size*Size)
+	set := func(x int, y int, value float32) {
+		want[(7+y)*Size+4+x] = value
+	}
+	for x := 0; x < 20; x++ {
+		set(x, 0, 1)
+		// the third row of the bar covers the upper half
+		set(x, 1, 0.5)
+	}
+	for y := 1; y < 20; y++ {
+		set(9, y, 1)
+		set(10, y, 1)
+		// the fifth column of the stem covers the left half
+		set(11, y, 0.5)
+	}
+	// the third row of the bar and the fifth column of the stem cover 3 quarters
+	set(11, 1, 0.75)
+
+	for i, pixel := range pixels {
+		if !almostEqual(pixel, want[i]) {
+			t.Errorf("Wrong pixels\n%s\nwant\n%s", formatPixels(pixels), formatPixels(want))
+			break
+		}
+	}
+}
+
+func TestNormalizeMNISTKeepsDigitInside(t *testing.T) {
+	// the center of mass (5.48, 9.55) would move the 20x20 digit to x = 9 out of 28x28
+	data := make([]byte, Size*Size)
+	for y := 3; y < 23; y++ {
+		for x := 5; x < 15; x++ {
+			data[y*Size+x] = 255
+		}
+	}
+	for x := 15; x < 25; x++ {
+		data[3*Size+x] = 255
+	}
+	pixels, err := RawPixels(data, Options{Normalization: MNIST})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRects(t, "raw", pixels, image.Rect(8, 4, 18, 24), image.Rect(18, 4, 28, 5))
+}
+
+func TestNormalizeMNISTAntiAliasing(t *testing.T) {
+	// the edge of a disk is drawn with gray pixels
+	img := fillImage(200, 200)
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 200; x++ {
+			if math.Hypot(float64(x)-100, float64(y)-100) < 83 {
+				img.SetGray(x, y, color.Gray{})
+			}
+		}
+	}
+	pixels := Pixels(img, Options{Invert: true, Normalization: MNIST})
+
+	var gray int
+	for _, pixel := range pixels {
+		if pixel > 0.01 && pixel < 0.99 {
+			gray++
+		}
+	}
+	if gray == 0 {
+		t.Errorf("The disk is not anti-aliased: %v", pixels)
+	}
+}
+
+// TestNormalizeMNISTFixtures compares the digits drawn on the web page with their golden files,
+// which are updated by "go test ./preprocess -update". The golden files only catch regressions,
+// so the expected pixels are derived by hand in TestNormalizeMNISTHandComputed.
+func TestNormalizeMNISTFixtures(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.png"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("No fixture: %v", err)
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := DecodeImage(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pixels := Pixels(img, Options{Invert: true, Normalization: MNIST})
+
+		golden := strings.TrimSuffix(file, ".png") + ".golden"
+		if *update {
+			if err := ioutil.WriteFile(golden, formatPixels(pixels), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// allow rounding errors of the platforms fusing multiply and add
+		wantValues := strings.Fields(string(want))
+		if len(wantValues) != len(pixels) {
+			t.Fatalf("%v: wrong golden file of %d pixels", golden, len(wantValues))
+		}
+		for i, pixel := range pixels {
+			wantValue, err := strconv.Atoi(wantValues[i])
+			if err != nil || math.Abs(float64(pixel)*255-float64(wantValue)) > 1 {
+				t.Errorf("%v: wrong pixels\n%s\nwant\n%s", file, formatPixels(pixels), want)
+				break
+			}
+		}
+
+		// the digit is fitted into 20x20 and centered by its center of mass
+		p := &plane{Size, Size, make([]float64, len(pixels))}
+		for i, pixel := range pixels {
+			p.pix[i] = float64(pixel)
+		}
+		left, top, right, bottom, _ := p.boundingBox(0)
+		centerX, centerY := p.centerOfMass()
+		if right-left > digitSize || bottom-top > digitSize || math.Max(float64(right-left), float64(bottom-top)) < digitSize {
+			t.Errorf("%v: wrong bounding box: (%d, %d) - (%d, %d)", file, left, top, right, bottom)
+		}
+		if math.Abs(centerX-Size/2) > 0.5 || math.Abs(centerY-Size/2) > 0.5 {
+			t.Errorf("%v: wrong center of mass: (%v, %v)", file, centerX, centerY)
+		}
+	}
+}
+
+// formatPixels returns the rows of pixels from 0 to 255
+func formatPixels(pixels []float32) []byte {
+	var buf bytes.Buffer
+	for i, pixel := range pixels {
+		fmt.Fprintf(&buf, "%3d", int(math.Round(float64(pixel)*255)))
+		if i%Size == Size-1 {
+			buf.WriteString("\n")
+		} else {
+			buf.WriteString(" ")
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,208 @@
+package preprocess
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	// PNG and JPEG images are decoded by image.Decode
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"strings"
+)
+
+// Size is the width and height of MNIST images
+const Size = 28
+
+// MaxImagePixels limits the number of pixels of decoded images
+const MaxImagePixels = 4096 * 4096
+
+// Normalization selects how images are fitted into 28x28
+type Normalization string
+
+const (
+	// Resize stretches the whole image to 28x28
+	Resize Normalization = "resize"
+	// MNIST crops the digit and centers it in 28x28 like MNIST
+	MNIST Normalization = "mnist"
+)
+
+// ParseNormalization returns the normalization of name, which is Resize by default
+func ParseNormalization(name string) (Normalization, error) {
+	switch Normalization(name) {
+	case "", Resize:
+		return Resize, nil
+	case MNIST:
+		return MNIST, nil
+	}
+	return "", fmt.Errorf("Unknown normalization: %v", name)
+}
+
+// Options selects the preprocessing of images
+type Options struct {
+	// Invert turns dark digits on light background into light digits on dark background like MNIST
+	Invert bool
+	// Normalization is Resize if empty
+	Normalization Normalization
+}
+
+// apply returns the pixels of the grayscale plane preprocessed by options
+func (options Options) apply(p *plane) []float32 {
+	if options.Invert {
+		p = p.inverted()
+	}
+	if options.Normalization == MNIST {
+		p = normalizeMNIST(p)
+	} else {
+		p = resize(p, Size, Size)
+	}
+	return p.pixels()
+}
+
+// DecodeImage decodes PNG or JPEG image
+func DecodeImage(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Unsupported image: %v", err)
+	}
+	if config.Width*config.Height > MaxImagePixels {
+		return nil, fmt.Errorf("The image is too large: %dx%d", config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Unsupported image: %v", err)
+	}
+	return img, nil
+}
+
+// DecodeBase64Image decodes base64-encoded PNG or JPEG image, which can be a data URL
+func DecodeBase64Image(data string) (image.Image, error) {
+	// data:image/png;base64,<data>
+	if strings.HasPrefix(data, "data:") {
+		i := strings.Index(data, ",")
+		if i < 0 || !strings.HasSuffix(data[:i], ";base64") {
+			return nil, fmt.Errorf("The data URL is not base64-encoded.")
+		}
+		data = data[i+1:]
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid base64 image: %v", err)
+	}
+	return DecodeImage(decoded)
+}
+
+// Pixels returns 784 pixels of img from 0 (black) to 1 (white) fitted into 28x28
+// Transparent pixels are white, so the strokes drawn on a transparent canvas are kept.
+func Pixels(img image.Image, options Options) []float32 {
+	return options.apply(grayscale(img))
+}
+
+// RawPixels returns 784 pixels from 0 to 1 of 28x28 grayscale bytes
+func RawPixels(data []byte, options Options) ([]float32, error) {
+	if len(data) != Size*Size {
+		return nil, fmt.Errorf("Raw image should have %d bytes.", Size*Size)
+	}
+	p := newPlane(Size, Size)
+	for i, value := range data {
+		p.pix[i] = float64(value) / 255
+	}
+	return options.apply(p), nil
+}
+
+// FloatPixels returns the 784 pixels from 0 to 1 of 28x28 grayscale image preprocessed by options
+func FloatPixels(pixels []float32, options Options) ([]float32, error) {
+	if len(pixels) != Size*Size {
+		return nil, fmt.Errorf("Pixels should have %d values.", Size*Size)
+	}
+	// resizing 28x28 pixels keeps them as they are
+	if !options.Invert && options.Normalization != MNIST {
+		return pixels, nil
+	}
+	p := newPlane(Size, Size)
+	for i, value := range pixels {
+		p.pix[i] = float64(value)
+	}
+	return options.apply(p), nil
+}
+
+// plane stores the luminance of grayscale image from 0 to 1
+type plane struct {
+	width  int
+	height int
+	pix    []float64
+}
+
+func newPlane(width int, height int) *plane {
+	return &plane{width, height, make([]float64, width*height)}
+}
+
+func (p *plane) at(x int, y int) float64 {
+	return p.pix[y*p.width+x]
+}
+
+func (p *plane) set(x int, y int, value float64) {
+	p.pix[y*p.width+x] = value
+}
+
+// pixels returns the pixels of plane clamped from 0 to 1
+func (p *plane) pixels() []float32 {
+	result := make([]float32, len(p.pix))
+	for i, value := range p.pix {
+		result[i] = float32(math.Max(0, math.Min(1, value)))
+	}
+	return result
+}
+
+// inverted returns the plane of inverted colors
+func (p *plane) inverted() *plane {
+	dst := newPlane(p.width, p.height)
+	for i, value := range p.pix {
+		dst.pix[i] = 1 - value
+	}
+	return dst
+}
+
+// grayscale returns the luminance of img composed over white background
+func grayscale(img image.Image) *plane {
+	bounds := img.Bounds()
+	p := newPlane(bounds.Dx(), bounds.Dy())
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			// alpha-premultiplied 16-bit values
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			luminance := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0xffff
+			p.set(x, y, luminance+1-float64(a)/0xffff)
+		}
+	}
+	return p
+}
+
+// resize returns the plane of width x height whose pixels are the area average of src
+func resize(src *plane, width int, height int) *plane {
+	dst := newPlane(width, height)
+	scaleX := float64(src.width) / float64(width)
+	scaleY := float64(src.height) / float64(height)
+	for y := 0; y < height; y++ {
+		y0, y1 := float64(y)*scaleY, float64(y+1)*scaleY
+		for x := 0; x < width; x++ {
+			x0, x1 := float64(x)*scaleX, float64(x+1)*scaleX
+
+			// weight the source pixels by the area covered by the destination pixel
+			var sum, area float64
+			for sy := int(y0); sy < src.height && float64(sy) < y1; sy++ {
+				wy := math.Min(y1, float64(sy+1)) - math.Max(y0, float64(sy))
+				for sx := int(x0); sx < src.width && float64(sx) < x1; sx++ {
+					wx := math.Min(x1, float64(sx+1)) - math.Max(x0, float64(sx))
+					sum += src.at(sx, sy) * wx * wy
+					area += wx * wy
+				}
+			}
+			if area > 0 {
+				dst.set(x, y, sum/area)
+			}
+		}
+	}
+	return dst
+}
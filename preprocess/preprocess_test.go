@@ -0,0 +1,148 @@
+package preprocess
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+)
+
+// encodePNG returns the PNG data of img
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// halfImage returns the image whose left half is black and right half is white
+func halfImage(width int, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := width / 2; x < width; x++ {
+			img.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+	return img
+}
+
+func almostEqual(a float32, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-3
+}
+
+func TestPixels(t *testing.T) {
+	for _, invert := range []bool{false, true} {
+		pixels := Pixels(halfImage(56, 84), Options{Invert: invert})
+		if len(pixels) != Size*Size {
+			t.Fatalf("Wrong number of pixels: %d", len(pixels))
+		}
+		for i, pixel := range pixels {
+			want := float32(0)
+			if (i%Size >= Size/2) != invert {
+				want = 1
+			}
+			if pixel != want {
+				t.Fatalf("Invert %v: pixel %d is %v, want %v", invert, i, pixel, want)
+			}
+		}
+	}
+}
+
+func TestPixelsTransparent(t *testing.T) {
+	// a blue stroke drawn on a transparent canvas
+	img := image.NewNRGBA(image.Rect(0, 0, Size, Size))
+	img.SetNRGBA(3, 4, color.NRGBA{B: 255, A: 255})
+	pixels := Pixels(img, Options{Invert: true})
+	if !almostEqual(pixels[4*Size+3], 0.886) || pixels[0] != 0 {
+		t.Errorf("Wrong pixels: stroke %v, background %v", pixels[4*Size+3], pixels[0])
+	}
+}
+
+func TestResize(t *testing.T) {
+	src := &plane{3, 1, []float64{0, 1, 0.5}}
+	dst := resize(src, 2, 1)
+	if math.Abs(dst.pix[0]-1.0/3) > 1e-9 || math.Abs(dst.pix[1]-2.0/3) > 1e-9 {
+		t.Errorf("Wrong area average: %v", dst.pix)
+	}
+
+	// enlarged pixels keep their values
+	dst = resize(&plane{2, 1, []float64{0, 1}}, 4, 2)
+	for i, want := range []float64{0, 0, 1, 1, 0, 0, 1, 1} {
+		if dst.pix[i] != want {
+			t.Errorf("Wrong enlarged pixels: %v", dst.pix)
+			break
+		}
+	}
+}
+
+func TestRawPixels(t *testing.T) {
+	data := make([]byte, Size*Size)
+	data[0] = 255
+	data[1] = 51
+	pixels, err := RawPixels(data, Options{})
+	if err != nil || pixels[0] != 1 || !almostEqual(pixels[1], 0.2) || pixels[2] != 0 {
+		t.Errorf("Wrong pixels: %v, %v", pixels[:3], err)
+	}
+	pixels, _ = RawPixels(data, Options{Invert: true})
+	if pixels[0] != 0 || pixels[2] != 1 {
+		t.Errorf("Wrong inverted pixels: %v", pixels[:3])
+	}
+	if _, err := RawPixels(data[1:], Options{}); err == nil {
+		t.Errorf("Raw image of 783 bytes is accepted")
+	}
+}
+
+func TestFloatPixels(t *testing.T) {
+	values := make([]float32, Size*Size)
+	values[0] = 1
+	values[1] = 0.2
+	pixels, err := FloatPixels(values, Options{})
+	if err != nil || pixels[0] != 1 || pixels[1] != 0.2 || pixels[2] != 0 {
+		t.Errorf("Wrong pixels: %v, %v", pixels[:3], err)
+	}
+	pixels, _ = FloatPixels(values, Options{Invert: true})
+	if pixels[0] != 0 || !almostEqual(pixels[1], 0.8) || pixels[2] != 1 {
+		t.Errorf("Wrong inverted pixels: %v", pixels[:3])
+	}
+	if _, err := FloatPixels(values[1:], Options{}); err == nil {
+		t.Errorf("783 pixels are accepted")
+	}
+}
+
+func TestDecodeBase64Image(t *testing.T) {
+	pngData := encodePNG(t, halfImage(4, 2))
+	var jpegData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, halfImage(16, 16), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, data := range []string{
+		base64.StdEncoding.EncodeToString(pngData),
+		"data:image/png;base64," + base64.StdEncoding.EncodeToString(pngData),
+		base64.StdEncoding.EncodeToString(jpegData.Bytes()),
+	} {
+		if _, err := DecodeBase64Image(data); err != nil {
+			t.Errorf("%.30v: %v", data, err)
+		}
+	}
+	for _, data := range []string{
+		"not base64",
+		"data:image/png," + base64.StdEncoding.EncodeToString(pngData),
+		base64.StdEncoding.EncodeToString([]byte("GIF89a")),
+	} {
+		if _, err := DecodeBase64Image(data); err == nil {
+			t.Errorf("%.30v: invalid image is decoded", data)
+		}
+	}
+}
+
+func TestDecodeImageTooLarge(t *testing.T) {
+	if _, err := DecodeImage(encodePNG(t, image.NewGray(image.Rect(0, 0, 1, MaxImagePixels+1)))); err == nil {
+		t.Errorf("Too large image is decoded")
+	}
+}
@@ -0,0 +1,192 @@
+package rbac
+
+//go:generate go run ../cmd/gen-rbac -o ../deploy/rbac.yaml
+
+import (
+	"bytes"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+
+	apiv1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// Name is used for the ServiceAccount, roles and role bindings of mini-mnist-serving
+	Name = "mini-mnist-serving"
+	// Namespace is where mini-mnist-serving itself runs
+	Namespace = "mini-mnist-serving"
+)
+
+// ClusterRules lists the permissions on cluster-scoped resources used by controllers
+var ClusterRules = []rbacv1.PolicyRule{
+	{
+		APIGroups: []string{""},
+		Resources: []string{"namespaces"},
+		Verbs:     []string{"create"},
+	},
+}
+
+// NamespaceRules lists the permissions used by controllers in the model namespaces
+var NamespaceRules = []rbacv1.PolicyRule{
+	{
+		APIGroups: []string{""},
+		Resources: []string{"secrets"},
+		Verbs:     []string{"create", "delete"},
+	},
+	{
+		APIGroups: []string{""},
+		Resources: []string{"services"},
+		Verbs:     []string{"create", "list", "watch", "delete"},
+	},
+	{
+		// model registry
+		APIGroups: []string{""},
+		Resources: []string{"configmaps"},
+		Verbs:     []string{"create", "get", "list", "update", "watch"},
+	},
+	{
+		APIGroups: []string{"apps"},
+		Resources: []string{"deployments"},
+		Verbs:     []string{"create", "get", "list", "watch", "update", "delete"},
+	},
+	{
+		// rollout status
+		APIGroups: []string{"apps"},
+		Resources: []string{"replicasets"},
+		Verbs:     []string{"list", "watch"},
+	},
+	{
+		APIGroups: []string{""},
+		Resources: []string{"pods"},
+		Verbs:     []string{"list", "watch"},
+	},
+	{
+		APIGroups: []string{""},
+		Resources: []string{"pods/log"},
+		Verbs:     []string{"get"},
+	},
+	{
+		APIGroups: []string{"networking.k8s.io", "extensions"},
+		Resources: []string{"ingresses"},
+		Verbs:     []string{"create", "get", "list", "watch", "update", "delete"},
+	},
+	{
+		// gateway traffic backend
+		APIGroups: []string{"gateway.networking.k8s.io"},
+		Resources: []string{"httproutes"},
+		Verbs:     []string{"create", "get", "update", "delete"},
+	},
+	{
+		APIGroups: []string{"gateway.networking.k8s.io"},
+		Resources: []string{"referencegrants"},
+		Verbs:     []string{"create"},
+	},
+	{
+		// istio traffic backend
+		APIGroups: []string{"networking.istio.io"},
+		Resources: []string{"virtualservices"},
+		Verbs:     []string{"create", "get", "update", "delete"},
+	},
+	{
+		APIGroups: []string{"networking.istio.io"},
+		Resources: []string{"destinationrules"},
+		Verbs:     []string{"create", "delete"},
+	},
+}
+
+// Allows reports whether rules grant verb on resource of API group
+func Allows(rules []rbacv1.PolicyRule, group string, resource string, verb string) bool {
+	for _, rule := range rules {
+		if contains(rule.APIGroups, group) && contains(rule.Resources, resource) && contains(rule.Verbs, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// Manifest returns YAML manifest of the ServiceAccount, roles and role bindings
+// which let mini-mnist-serving run as a Pod
+func Manifest() ([]byte, error) {
+	subjects := []rbacv1.Subject{
+		{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      Name,
+			Namespace: Namespace,
+		},
+	}
+
+	objects := []interface{}{
+		&apiv1.Namespace{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+			ObjectMeta: metav1.ObjectMeta{Name: Namespace},
+		},
+		&apiv1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+			ObjectMeta: metav1.ObjectMeta{Name: Name, Namespace: Namespace},
+		},
+		&rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole"},
+			ObjectMeta: metav1.ObjectMeta{Name: Name},
+			Rules:      ClusterRules,
+		},
+		&rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: Name},
+			Subjects:   subjects,
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     Name,
+			},
+		},
+	}
+
+	// Roles can only be bound in existing namespaces, so the model namespaces are created here as well
+	for _, namespace := range []string{constants.ProdNamespace, constants.CanaryNamespace} {
+		objects = append(objects,
+			&apiv1.Namespace{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+				ObjectMeta: metav1.ObjectMeta{Name: namespace},
+			},
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+				ObjectMeta: metav1.ObjectMeta{Name: Name, Namespace: namespace},
+				Rules:      NamespaceRules,
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: Name, Namespace: namespace},
+				Subjects:   subjects,
+				RoleRef: rbacv1.RoleRef{
+					APIGroup: rbacv1.GroupName,
+					Kind:     "Role",
+					Name:     Name,
+				},
+			},
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Code generated by cmd/gen-rbac. DO NOT EDIT.\n")
+	for _, object := range objects {
+		content, err := yaml.Marshal(object)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString("---\n")
+		buf.Write(content)
+	}
+	return buf.Bytes(), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value || v == "*" {
+			return true
+		}
+	}
+	return false
+}
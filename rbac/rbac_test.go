@@ -0,0 +1,43 @@
+package rbac
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestManifestUpToDate(t *testing.T) {
+	manifest, err := Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile("../deploy/rbac.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(manifest, content) {
+		t.Errorf("deploy/rbac.yaml is outdated. Run \"go generate ./rbac\".")
+	}
+}
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		group    string
+		resource string
+		verb     string
+		allowed  bool
+	}{
+		{"apps", "deployments", "update", true},
+		{"", "secrets", "create", true},
+		{"", "secrets", "get", false},
+		{"apps", "secrets", "create", false},
+	}
+
+	for _, test := range tests {
+		if Allows(NamespaceRules, test.group, test.resource, test.verb) != test.allowed {
+			t.Errorf("Allows(%q, %q, %q) != %v", test.group, test.resource, test.verb, test.allowed)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// ConfigMapName is the name of ConfigMap storing the registered models
+const ConfigMapName = "mnist-model-registry"
+
+// MaxNameLength is the maximum length of model name leaving room for the suffixes of object names
+const MaxNameLength = validation.DNS1035LabelMaxLength - len("-deploy")
+
+var (
+	// ErrNotFound is returned when the model is not registered
+	ErrNotFound = errors.New("The model is not registered.")
+	// ErrAlreadyExists is returned when the model is registered already
+	ErrAlreadyExists = errors.New("The model is registered already.")
+)
+
+// Model stores a model registered in mini-mnist-serving
+// Each model has its own prod (current) and canary (new) slots.
+type Model struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created-at"`
+	// Strategy, Weight and Target are the routing strategy last set to the model
+	Strategy constants.Strategy `json:"strategy"`
+	Weight   int                `json:"weight,omitempty"`
+	Target   *TargetRule        `json:"target,omitempty"`
+	// StrategyChangedAt is when the strategy is set last
+	StrategyChangedAt *time.Time `json:"strategy-changed-at,omitempty"`
+}
+
+// TargetRule selects the requests sent to the new model with Targeted strategy
+// A request matches when its Header is one of Values, or its Cookie is "always".
+type TargetRule struct {
+	Header string   `json:"header,omitempty"`
+	Values []string `json:"values,omitempty"`
+	Cookie string   `json:"cookie,omitempty"`
+}
+
+// ConfigMapGetter returns the ConfigMap of namespace, e.g. from the cache of informers
+type ConfigMapGetter func(namespace string, name string) (*apiv1.ConfigMap, error)
+
+// Registry stores models in a ConfigMap so that they survive restarts of the server
+type Registry struct {
+	kubeClientSet kubernetes.Interface
+	namespace     string
+	// getConfigMap reads the models instead of the API server when it is set
+	getConfigMap ConfigMapGetter
+}
+
+// NewRegistry returns Registry storing models in the namespace
+func NewRegistry(kubeClientSet kubernetes.Interface, namespace string) *Registry {
+	return &Registry{
+		kubeClientSet: kubeClientSet,
+		namespace:     namespace,
+	}
+}
+
+// WithConfigMapGetter returns Registry of the same models which reads them by getConfigMap
+// Models are still created and updated through the API server.
+func (r *Registry) WithConfigMapGetter(getConfigMap ConfigMapGetter) *Registry {
+	return &Registry{
+		kubeClientSet: r.kubeClientSet,
+		namespace:     r.namespace,
+		getConfigMap:  getConfigMap,
+	}
+}
+
+// List returns registered models sorted by name
+func (r *Registry) List(ctx context.Context) ([]Model, error) {
+	configMap, err := r.readConfigMap(ctx)
+	if apierrors.IsNotFound(err) {
+		return []Model{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	models := make([]Model, 0, len(configMap.Data))
+	for _, value := range configMap.Data {
+		var model Model
+		if err := json.Unmarshal([]byte(value), &model); err != nil {
+			return nil, err
+		}
+		models = append(models, model)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].Name < models[j].Name
+	})
+	return models, nil
+}
+
+// Get returns the registered model
+func (r *Registry) Get(ctx context.Context, name string) (*Model, error) {
+	configMap, err := r.readConfigMap(ctx)
+	if apierrors.IsNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := configMap.Data[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var model Model
+	if err := json.Unmarshal([]byte(value), &model); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// Create registers the model
+func (r *Registry) Create(ctx context.Context, model *Model) error {
+	if model.CreatedAt.IsZero() {
+		model.CreatedAt = time.Now().UTC()
+	}
+	value, err := json.Marshal(model)
+	if err != nil {
+		return err
+	}
+
+	return r.update(ctx, func(data map[string]string) error {
+		if _, ok := data[model.Name]; ok {
+			return ErrAlreadyExists
+		}
+		data[model.Name] = string(value)
+		return nil
+	})
+}
+
+// Delete unregisters the model
+func (r *Registry) Delete(ctx context.Context, name string) error {
+	return r.update(ctx, func(data map[string]string) error {
+		if _, ok := data[name]; !ok {
+			return ErrNotFound
+		}
+		delete(data, name)
+		return nil
+	})
+}
+
+// SetStrategy stores the routing strategy of the model
+func (r *Registry) SetStrategy(ctx context.Context, name string, strategy constants.Strategy, weight int, target *TargetRule) error {
+	return r.update(ctx, func(data map[string]string) error {
+		value, ok := data[name]
+		if !ok {
+			return ErrNotFound
+		}
+		var model Model
+		if err := json.Unmarshal([]byte(value), &model); err != nil {
+			return err
+		}
+
+		model.Strategy = strategy
+		model.Weight = weight
+		model.Target = target
+		changedAt := time.Now().UTC()
+		model.StrategyChangedAt = &changedAt
+		updated, err := json.Marshal(model)
+		if err != nil {
+			return err
+		}
+		data[name] = string(updated)
+		return nil
+	})
+}
+
+// readConfigMap returns the ConfigMap of models, which must not be modified
+func (r *Registry) readConfigMap(ctx context.Context) (*apiv1.ConfigMap, error) {
+	if r.getConfigMap != nil {
+		return r.getConfigMap(r.namespace, ConfigMapName)
+	}
+	return r.kubeClientSet.CoreV1().ConfigMaps(r.namespace).Get(ctx, ConfigMapName, metav1.GetOptions{})
+}
+
+// update modifies data of ConfigMap, retrying when the ConfigMap is changed concurrently
+func (r *Registry) update(ctx context.Context, modify func(data map[string]string) error) error {
+	configMapsClient := r.kubeClientSet.CoreV1().ConfigMaps(r.namespace)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := configMapsClient.Get(ctx, ConfigMapName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			configMap = &apiv1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: ConfigMapName,
+				},
+				Data: make(map[string]string),
+			}
+			if err := modify(configMap.Data); err != nil {
+				return err
+			}
+			_, err = configMapsClient.Create(ctx, configMap, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// created concurrently, so retry with the latest ConfigMap
+				return apierrors.NewConflict(apiv1.Resource("configmaps"), ConfigMapName, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+		if err := modify(configMap.Data); err != nil {
+			return err
+		}
+		_, err = configMapsClient.Update(ctx, configMap, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// ValidateName checks that the model name can be used in the REST API path of Tensorflow Serving,
+// the model base path and the names of Kubernetes objects
+func ValidateName(name string) error {
+	errs := validation.IsDNS1035Label(name)
+	if len(name) > MaxNameLength {
+		errs = append(errs, validation.MaxLenError(MaxNameLength))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Invalid model name %q: %v", name, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// DeploymentName returns the name of Deployment serving the model
+func DeploymentName(modelName string) string {
+	return modelName + "-deploy"
+}
+
+// ServiceName returns the name of Service exposing the model
+func ServiceName(modelName string) string {
+	return modelName + "-svc"
+}
+
+// IngressName returns the name of Ingress routing requests to the model
+func IngressName(modelName string) string {
+	return modelName + "-ingress"
+}
+
+// RouteName returns the name of HTTPRoute or VirtualService routing requests to the model
+func RouteName(modelName string) string {
+	return modelName + "-route"
+}
+
+// DestinationRuleName returns the name of DestinationRule selecting the Pods of the model
+func DestinationRuleName(modelName string) string {
+	return modelName + "-dr"
+}
+
+// IngressPath returns the ingress path of the model
+func IngressPath(modelName string) string {
+	return "/predict/" + modelName
+}
+
+// Labels returns the labels of Pods serving the model
+func Labels(modelName string) map[string]string {
+	return map[string]string{
+		"app":   constants.LabelAppSelector,
+		"model": modelName,
+	}
+}
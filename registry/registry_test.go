@@ -0,0 +1,132 @@
+package registry
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(fake.NewSimpleClientset(), "mnist-prod")
+
+	models, err := r.List(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 0 {
+		t.Errorf("Registry should be empty: %v", models)
+	}
+
+	for _, name := range []string{"mnist-mlp", "mnist-cnn"} {
+		if err := r.Create(context.TODO(), &Model{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Create(context.TODO(), &Model{Name: "mnist-cnn"}); err != ErrAlreadyExists {
+		t.Errorf("Duplicated model is registered: %v", err)
+	}
+
+	models, err = r.List(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 2 || models[0].Name != "mnist-cnn" || models[1].Name != "mnist-mlp" {
+		t.Errorf("Models are not listed in order: %v", models)
+	}
+	if models[0].CreatedAt.IsZero() {
+		t.Errorf("Creation time is not set")
+	}
+
+	model, err := r.Get(context.TODO(), "mnist-mlp")
+	if err != nil || model.Name != "mnist-mlp" {
+		t.Errorf("Get returned %v, %v", model, err)
+	}
+
+	if err := r.SetStrategy(context.TODO(), "mnist-mlp", constants.Canary, 30, nil); err != nil {
+		t.Fatal(err)
+	}
+	model, err = r.Get(context.TODO(), "mnist-mlp")
+	if err != nil || model.Strategy != constants.Canary || model.Weight != 30 || model.Target != nil || model.CreatedAt.IsZero() || model.StrategyChangedAt == nil {
+		t.Errorf("Strategy is not stored: %+v, %v", model, err)
+	}
+	if err := r.SetStrategy(context.TODO(), "mnist-mlp", constants.Targeted, 0, &TargetRule{Header: "X-Client-Id", Values: []string{"alice"}}); err != nil {
+		t.Fatal(err)
+	}
+	model, err = r.Get(context.TODO(), "mnist-mlp")
+	if err != nil || model.Strategy != constants.Targeted || model.Weight != 0 || model.Target == nil || model.Target.Values[0] != "alice" {
+		t.Errorf("Targeted strategy is not stored: %+v, %v", model, err)
+	}
+	if err := r.SetStrategy(context.TODO(), "mnist-rnn", constants.Canary, 30, nil); err != ErrNotFound {
+		t.Errorf("Strategy of unknown model is stored: %v", err)
+	}
+
+	if err := r.Delete(context.TODO(), "mnist-mlp"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(context.TODO(), "mnist-mlp"); err != ErrNotFound {
+		t.Errorf("Deleted model is found: %v", err)
+	}
+	if err := r.Delete(context.TODO(), "mnist-mlp"); err != ErrNotFound {
+		t.Errorf("Deleting unknown model returned %v", err)
+	}
+}
+
+func TestRegistryWithConfigMapGetter(t *testing.T) {
+	r := NewRegistry(fake.NewSimpleClientset(), "mnist-prod")
+	if err := r.Create(context.TODO(), &Model{Name: "mnist-cnn"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the getter is read instead of the API server
+	cached := r.WithConfigMapGetter(func(namespace string, name string) (*apiv1.ConfigMap, error) {
+		if namespace != "mnist-prod" || name != ConfigMapName {
+			t.Errorf("Wrong ConfigMap: %v/%v", namespace, name)
+		}
+		return nil, apierrors.NewNotFound(apiv1.Resource("configmaps"), name)
+	})
+	if _, err := cached.Get(context.TODO(), "mnist-cnn"); err != ErrNotFound {
+		t.Errorf("Model is not read by the getter: %v", err)
+	}
+	if models, err := cached.List(context.TODO()); err != nil || len(models) != 0 {
+		t.Errorf("Models are not read by the getter: %v, %v", models, err)
+	}
+
+	// models are still updated through the API server
+	if err := cached.Create(context.TODO(), &Model{Name: "mnist-mlp"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(context.TODO(), "mnist-mlp"); err != nil {
+		t.Errorf("Model is not created: %v", err)
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"model", "mnist-cnn", "m1", strings.Repeat("m", MaxNameLength)} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("%q should be valid: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "Model", "mnist_cnn", "1model", "model-", "model:predict", strings.Repeat("m", MaxNameLength+1)} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("%q should be invalid", name)
+		}
+	}
+}
+
+func TestObjectNames(t *testing.T) {
+	if DeploymentName("mnist-cnn") != "mnist-cnn-deploy" || ServiceName("mnist-cnn") != "mnist-cnn-svc" || IngressName("mnist-cnn") != "mnist-cnn-ingress" {
+		t.Errorf("Wrong object names")
+	}
+	if IngressPath("mnist-cnn") != "/predict/mnist-cnn" {
+		t.Errorf("Wrong ingress path: %v", IngressPath("mnist-cnn"))
+	}
+	if Labels("mnist-cnn")["model"] != "mnist-cnn" {
+		t.Errorf("Wrong labels: %v", Labels("mnist-cnn"))
+	}
+}
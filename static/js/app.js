@@ -31,19 +31,203 @@ $(document).ready(function() {
         canvas.clear();
     });
 
+    var curModelName = $("#cur-model-name").val()
+
+    $("#model-select").change(function() {
+        window.location.href = "/?model=" + encodeURIComponent($(this).val())
+    })
+
+    // describes the rollout progress of model slot
+    function describeRollout(rollout) {
+        var text = rollout["state"] + " (" + rollout["available-replicas"] + "/" + rollout["replicas"] + " available)"
+        var failedPods = rollout["pods"].filter(function(pod) {
+            return pod["reason"]
+        })
+        if (failedPods.length > 0) {
+            text += " - " + failedPods[0]["name"] + ": " + failedPods[0]["reason"]
+        }
+        return text
+    }
+
+    // formats the time like the server rendered page
+    function formatTime(value) {
+        var t = new Date(value)
+        var pad = function(n) { return ("0" + n).slice(-2) }
+        return t.getFullYear() + "-" + pad(t.getMonth() + 1) + "-" + pad(t.getDate()) + " " + pad(t.getHours()) + ":" + pad(t.getMinutes()) + ":" + pad(t.getSeconds())
+    }
+
+    // describes the strategy like the server rendered page
+    function describeStrategy(strategy) {
+        var text = strategy["name"]
+        if (strategy["weight"] !== undefined) {
+            text += " (" + strategy["weight"] + "% New)"
+        }
+        var target = strategy["target"]
+        if (strategy["strategy"] == 4 && target) {
+            var rules = []
+            if (target["header"]) {
+                rules.push(target["header"] + ": " + (target["values"] || []).join(", "))
+            }
+            if (target["cookie"]) {
+                rules.push("cookie " + target["cookie"])
+            }
+            text += " (" + rules.join(" / ") + ")"
+        }
+        return "Strategy - " + text
+    }
+
+    // slots whose deploy job is running show the job instead of the rollout
+    var deployingSlots = {}
+
+    // updates the cards and buttons with the status of model
+    function applyStatus(status) {
+        [["cur", status["prod"]], ["new", status["canary"]]].forEach(function(slot) {
+            var prefix = slot[0], slotStatus = slot[1]
+            $("#deploy-" + prefix + "-model-btn")
+                .toggleClass("btn-info", slotStatus["ready"])
+                .toggleClass("btn-primary", !slotStatus["ready"])
+                .text(slotStatus["ready"] ? "Re-Deploy" : "Deploy")
+            $("#undeploy-" + prefix + "-model-btn").prop("hidden", !slotStatus["deployed"])
+            $("#" + prefix + "-model-only-btn").prop("disabled", !slotStatus["ready"])
+
+            var statusText = ""
+            if (slotStatus["deployed"]) {
+                statusText = slotStatus["available-replicas"] + "/" + slotStatus["replicas"] + " available - " + slotStatus["model-base-path"] + " (" + slotStatus["image"] + ")"
+                if (slotStatus["changed-at"]) {
+                    statusText += " since " + formatTime(slotStatus["changed-at"])
+                }
+            }
+            $("#" + prefix + "-status-text").text(statusText)
+
+            if (!deployingSlots[prefix]) {
+                var rollout = slotStatus["rollout"]
+                $("#" + prefix + "-rollout-text").text(rollout && rollout["state"] != "Complete" ? describeRollout(rollout) : "")
+            }
+        })
+
+        var bothReady = status["prod"]["ready"] && status["canary"]["ready"]
+        var anyReady = status["prod"]["ready"] || status["canary"]["ready"]
+        $("#canary-btn").prop("disabled", !bothReady)
+        $("#canary-range").prop("disabled", !bothReady)
+        $("#canary-weight-text").prop("hidden", !bothReady)
+        $("#shadow-btn").prop("disabled", !bothReady)
+        $("#targeted-btn").prop("disabled", !bothReady)
+        $(".targeted-input").prop("disabled", !bothReady)
+        $("#auto-canary-btn").prop("disabled", !bothReady || /Pending|Running/.test($("#canary-analysis-text").text()))
+        $("#compare-btn").prop("disabled", !bothReady)
+        $("#promote-model-btn").prop("hidden", !bothReady)
+        $("#set-strategy-btn").prop("disabled", !anyReady)
+        $("#predict-btn").prop("disabled", !anyReady)
+
+        $("#cur-strategy-text").text(describeStrategy(status["strategy"]))
+    }
+
+    // reads the status of model right after changing it
+    function refreshStatus() {
+        $.ajax({
+            url: '/model/status?model-name=' + encodeURIComponent(curModelName),
+            type: "GET",
+            dataType: 'json',
+            success : applyStatus,
+            error: function(xhr, resp, text) {
+                console.log(xhr, resp, text);
+            }
+        })
+    }
+
+    // the server pushes the status whenever the deployments, Pods or strategy change
+    if (curModelName && window.EventSource) {
+        var events = new EventSource('/model/events?model-name=' + encodeURIComponent(curModelName))
+        events.addEventListener("status", function(event) {
+            applyStatus(JSON.parse(event.data))
+        })
+        events.addEventListener("error", function(event) {
+            if (event.data) {
+                console.log(JSON.parse(event.data))
+            }
+        })
+    }
+
+    $("#modal-add-model-ok").click(function() {
+        var modelName = $("#new-model-name").val()
+        $.ajax({
+            url: '/models',
+            type: "POST",
+            dataType: 'json',
+            contentType: "application/json; charset=utf-8",
+            data: JSON.stringify(
+                {
+                    "name": modelName,
+                    "description": $("#new-model-description").val()
+                }
+            ),
+            success : function(result) {
+                window.location.href = "/?model=" + encodeURIComponent(modelName)
+            },
+            error: function(xhr, resp, text) {
+                console.log(xhr, resp, text);
+            }
+        })
+    })
+
+    $("#delete-model-btn").click(function() {
+        if (!confirm("Delete the model " + curModelName + " and all of its deployments?")) {
+            return
+        }
+        $.ajax({
+            url: '/models/' + encodeURIComponent(curModelName),
+            type: "DELETE",
+            dataType: 'text',
+            success : function(result) {
+                window.location.href = "/"
+            },
+            error: function(xhr, resp, text) {
+                console.log(xhr, resp, text);
+            }
+        })
+    })
+
     $('#modal-deploy-model').on('show.bs.modal', function (event) {
         var button = $(event.relatedTarget) // Button that triggered the modal
         var kind = button.data('kind') // Extract info from data-* attributes
         $("#is-new-model").val(kind)
     })
 
+    // poll the deploy, promote, rollback or undeploy job until it is finished
+    function watchJob(jobId, isNewModel, onSucceeded) {
+        var rolloutText = $(isNewModel ? "#new-rollout-text" : "#cur-rollout-text")
+        $.ajax({
+            url: '/jobs/' + jobId,
+            type: "GET",
+            dataType: 'json',
+            success : function(job) {
+                var runningSteps = job["steps"].filter(function(step) {
+                    return step["state"] == "Running"
+                })
+                if (job["state"] == "Pending" || job["state"] == "Running") {
+                    var action = {"promote": "Promoting", "rollback": "Rolling back", "undeploy": "Undeploying"}[job["kind"]] || "Deploying"
+                    rolloutText.text(action + (runningSteps.length > 0 ? " - " + runningSteps[0]["name"] : ""))
+                    setTimeout(function() { watchJob(jobId, isNewModel, onSucceeded) }, 1000)
+                } else if (job["state"] == "Succeeded") {
+                    onSucceeded()
+                } else {
+                    rolloutText.text(job["state"] + " - " + job["error"])
+                }
+            },
+            error: function(xhr, resp, text) {
+                rolloutText.text("")
+                console.log(xhr, resp, text);
+            }
+        })
+    }
+
     $("#modal-deploy-ok").click(function() {
         // send ajax
         var isNewModel = $("#is-new-model").val() == "new"
         $.ajax({
             url: '/model:deploy',
             type: "POST",
-            dataType: 'text',
+            dataType: 'json',
             contentType: "application/json; charset=utf-8",
             data: JSON.stringify(
                 {
@@ -53,32 +237,212 @@ $(document).ready(function() {
                     "is-new-model": isNewModel
                 }
             ),
-            success : function(result) {
+            success : function(job) {
                 $("#modal-deploy-model").modal("hide")
-                
-                // change statuses
-                if (isNewModel) {
-                    $("#deploy-new-model-btn").removeClass("btn-primary").addClass("btn-info")
-                    $("#new-model-only-btn").prop("disabled", false)
-                    $("#deploy-new-model-btn").text("Re-Deploy")
+
+                // another model is deployed
+                if ($("#model-name").val() != curModelName) {
+                    window.location.href = "/?model=" + encodeURIComponent($("#model-name").val())
+                    return
+                }
+
+                deployingSlots[isNewModel ? "new" : "cur"] = true
+                watchJob(job["id"], isNewModel, function() {
+                    deployingSlots[isNewModel ? "new" : "cur"] = false
+                    refreshStatus()
+                })
+            },
+            error: function(xhr, resp, text) {
+                console.log(xhr, resp, text);
+            }
+        })
+    })
+
+    $(".undeploy-btn").click(function() {
+        var isNewModel = $(this).data('kind') == "new"
+        if (!confirm("Undeploy the " + (isNewModel ? "new" : "current") + " model " + curModelName + "?")) {
+            return
+        }
+        $.ajax({
+            url: '/model:undeploy',
+            type: "DELETE",
+            dataType: 'json',
+            contentType: "application/json; charset=utf-8",
+            data: JSON.stringify(
+                {
+                    "model-name": curModelName,
+                    "is-new-model": isNewModel
+                }
+            ),
+            success : function(job) {
+                watchJob(job["id"], isNewModel, refreshStatus)
+            },
+            error: function(xhr, resp, text) {
+                alert(xhr.responseText)
+                console.log(xhr, resp, text);
+            }
+        })
+    })
+
+    $("#promote-model-btn").click(function() {
+        if (!confirm("Replace the current model " + curModelName + " with the new model?")) {
+            return
+        }
+        var scaleDownCanary = confirm("Scale the new model down after promotion?")
+        $("#promote-model-btn").prop("disabled", true)
+        $.ajax({
+            url: '/model:promote',
+            type: "POST",
+            dataType: 'json',
+            contentType: "application/json; charset=utf-8",
+            data: JSON.stringify(
+                {
+                    "model-name": curModelName,
+                    "scale-down-canary": scaleDownCanary
+                }
+            ),
+            success : function(job) {
+                // the promotion rolls the current model
+                deployingSlots["cur"] = true
+                watchJob(job["id"], false, function() {
+                    window.location.href = "/?model=" + encodeURIComponent(curModelName)
+                })
+            },
+            error: function(xhr, resp, text) {
+                alert("Promotion failed.\n" + xhr.responseText)
+                $("#promote-model-btn").prop("disabled", false)
+                console.log(xhr, resp, text);
+            }
+        })
+    })
+
+    // render the canary analysis and poll it until the canary is finished
+    function watchCanary() {
+        $.ajax({
+            url: '/model/canary?model-name=' + encodeURIComponent(curModelName),
+            type: "GET",
+            dataType: 'json',
+            success : function(analysis) {
+                var text = analysis["state"]
+                if (analysis["weight"]) {
+                    text += " - " + analysis["weight"] + "% (New)"
+                }
+                if (analysis["reason"]) {
+                    text += " - " + analysis["reason"]
+                }
+                $("#canary-analysis-text").text(text)
+
+                var rows = analysis["steps"].map(function(step) {
+                    var result = !step["finished-at"] ? "Analyzing" : (step["passed"] ? "Passed" : "Failed - " + step["reason"])
+                    return $("<tr>").append(
+                        $("<td>").text(step["weight"] + "%"),
+                        $("<td>").text(step["prod"]["requests"] + " / " + step["canary"]["requests"]),
+                        $("<td>").text(step["prod"]["error-rate"].toFixed(3) + " / " + step["canary"]["error-rate"].toFixed(3)),
+                        $("<td>").text(step["prod"]["p95-latency-ms"].toFixed(1) + "ms / " + step["canary"]["p95-latency-ms"].toFixed(1) + "ms"),
+                        $("<td>").text(result)
+                    )
+                })
+                $("#canary-analysis-steps").empty().append(rows)
+                $("#canary-analysis").prop("hidden", false)
+
+                if (analysis["state"] == "Pending" || analysis["state"] == "Running") {
+                    $("#auto-canary-btn").prop("disabled", true)
+                    setTimeout(watchCanary, 3000)
                 } else {
-                    $("#deploy-cur-model-btn").removeClass("btn-primary").addClass("btn-info")
-                    $("#cur-model-only-btn").prop("disabled", false)
-                    $("#deploy-cur-model-btn").text("Re-Deploy")
+                    // the strategy and models are changed
+                    window.location.href = "/?model=" + encodeURIComponent(curModelName)
                 }
+            },
+            error: function(xhr, resp, text) {
+                console.log(xhr, resp, text);
+            }
+        })
+    }
+
+    if (!$("#canary-analysis").prop("hidden") && /Pending|Running/.test($("#canary-analysis-text").text())) {
+        watchCanary()
+    }
+
+    // render the agreement of the new model to the current model in Shadow strategy
+    function refreshShadow() {
+        $.ajax({
+            url: '/model/shadow?model-name=' + encodeURIComponent(curModelName) + '&recent=0',
+            type: "GET",
+            dataType: 'json',
+            success : function(shadow) {
+                $("#shadow-analysis-text").text("Agreement " + (shadow["agreement-rate"] * 100).toFixed(1) + "% of " + shadow["comparisons"] + " predictions, "
+                    + shadow["canary-errors"] + " errors, latency delta " + shadow["mean-latency-delta-ms"].toFixed(1) + "ms (p95 " + shadow["p95-latency-delta-ms"].toFixed(1) + "ms)")
 
-                var curModelDisabled = $("#cur-model-only-btn").prop("disabled")
-                var newModelDisabled = $("#new-model-only-btn").prop("disabled")
-                if (!curModelDisabled && !newModelDisabled) {
-                    $("#canary-btn").prop("disabled", false)
-                    $("#canary-range").prop("disabled", false)
-                    $("#canary-weight-text").prop("hidden", false)
+                var rows = shadow["classes"].map(function(cls) {
+                    var canaryClasses = Object.keys(cls["canary-classes"]).map(function(canaryClass) {
+                        return canaryClass + ": " + cls["canary-classes"][canaryClass]
+                    })
+                    return $("<tr>").append(
+                        $("<td>").text(cls["class"]),
+                        $("<td>").text(cls["comparisons"]),
+                        $("<td>").text(cls["disagreements"] + " (" + (cls["disagreement-rate"] * 100).toFixed(1) + "%)"),
+                        $("<td>").text(canaryClasses.join(" "))
+                    )
+                })
+                $("#shadow-analysis-classes").empty().append(rows)
+                $("#shadow-analysis").prop("hidden", false)
+            },
+            error: function(xhr, resp, text) {
+                console.log(xhr, resp, text);
+            }
+        })
+    }
+
+    $("#auto-canary-btn").click(function() {
+        if (!confirm("Step the canary weight of " + curModelName + " automatically and promote the new model when it is as good as the current model?")) {
+            return
+        }
+        $.ajax({
+            url: '/model:canary',
+            type: "POST",
+            dataType: 'json',
+            contentType: "application/json; charset=utf-8",
+            data: JSON.stringify(
+                {
+                    "model-name": curModelName,
+                    "scale-down-canary": true
                 }
+            ),
+            success : function(job) {
+                watchCanary()
+            },
+            error: function(xhr, resp, text) {
+                alert(xhr.responseText)
+                console.log(xhr, resp, text);
+            }
+        })
+    })
 
-                $("#set-strategy-btn").prop("disabled", false)
-                $("#predict-btn").prop("disabled", false)
+    $(".rollback-btn").click(function() {
+        var isNewModel = $(this).data('kind') == "new"
+        var revision = $(this).data('revision')
+        if (!confirm("Roll the " + (isNewModel ? "new" : "current") + " model back to revision " + revision + "?")) {
+            return
+        }
+        $.ajax({
+            url: '/model:rollback',
+            type: "POST",
+            dataType: 'json',
+            contentType: "application/json; charset=utf-8",
+            data: JSON.stringify(
+                {
+                    "model-name": curModelName,
+                    "is-new-model": isNewModel,
+                    "revision": revision
+                }
+            ),
+            success : function(job) {
+                watchJob(job["id"], isNewModel, function() {
+                    window.location.href = "/?model=" + encodeURIComponent(curModelName)
+                })
             },
             error: function(xhr, resp, text) {
+                alert(xhr.responseText)
                 console.log(xhr, resp, text);
             }
         })
@@ -88,6 +452,11 @@ $(document).ready(function() {
         // set strategy
         var strategyBtns = $("input:radio[name='predict-radio-options']");
         var strategyIdx = strategyBtns.index(strategyBtns.filter(':checked'))
+        var target = {
+            "header": $("#target-header").val().trim(),
+            "values": $("#target-values").val().split(",").map(function(value) { return value.trim() }).filter(function(value) { return value != "" }),
+            "cookie": $("#target-cookie").val().trim()
+        }
         $.ajax({
             url: '/model/strategy',
             type: "PUT",
@@ -95,63 +464,99 @@ $(document).ready(function() {
             contentType: "application/json; charset=utf-8",
             data: JSON.stringify(
                 {
+                    "model-name": curModelName,
                     "strategy": strategyIdx,
-                    "weight": parseInt($("#canary-range").val())
+                    "weight": parseInt($("#canary-range").val()),
+                    "target": strategyIdx == 4 ? target : undefined
                 }
             ),
             success : function(result) {
-                switch (strategyIdx) {
-                    case 0:
-                        $("#cur-strategy-text").text("Strategy - Current Model Only")
-                        break;
-                    case 1:
-                        $("#cur-strategy-text").text("Strategy - New Model Only")
-                        break;
-                    case 2:
-                        $("#cur-strategy-text").text("Strategy - Canary")
-                        break;
-                    default:
-                        console.log("Unknown strategy")
-                }
+                refreshStatus()
             },
             error: function(xhr, resp, text) {
+                alert(xhr.responseText)
                 console.log(xhr, resp, text);
             }
         })
     })
 
-    $("#predict-btn").click(function() {
-        var hiddenCanvasCtx = $("#hidden-resized-canvas")[0].getContext('2d');
-        hiddenCanvasCtx.clearRect(0, 0, 28, 28)
-        hiddenCanvasCtx.drawImage($("#canvas")[0], 0, 0, 28, 28);
-        var imageData = hiddenCanvasCtx.getImageData(0, 0, 28, 28)
-        var pixelData = imageData.data
-        var greyScaledPixelData = []
-        for (var i=0; i<pixelData.length; i++) {
-            // only use B in RGBA
-            if (i % 4 == 2) {
-                // MNIST data has black background
-                greyScaledPixelData.push((pixelData[i] / 255.0))
-            }
+    function predictionDataset(label, color, data) {
+        return {
+            label: label,
+            backgroundColor: color,
+            borderColor: color,
+            data: data
+        }
+    }
+
+    // describes the class predicted by the model and the deployed model
+    function describePrediction(name, prediction) {
+        if (prediction["error"]) {
+            return name + " failed - " + prediction["error"]
+        }
+        var text = name + " " + prediction["argmax"] + " (" + (prediction["confidence"] * 100).toFixed(1) + "%, " + prediction["latency-ms"].toFixed(1) + "ms"
+        if (prediction["model-base-path"]) {
+            text += ", " + prediction["model-base-path"] + " rev " + prediction["revision"]
+        }
+        return text + ")"
+    }
+
+    // returns the drawn digit, which is inverted and normalized like MNIST by the server
+    function getImage() {
+        return {
+            "image": canvas.toDataURL({format: "png"}),
+            "invert": true,
+            "normalize": "mnist"
         }
+    }
 
+    $("#predict-btn").click(function() {
         $.ajax({
-            url: '/model:predict',
+            url: '/model:predict?model-name=' + encodeURIComponent(curModelName),
             type: "POST",
             dataType: 'json',
             contentType: "application/json; charset=utf-8",
             data: JSON.stringify(
-                greyScaledPixelData
+                getImage()
             ),
             success : function(result) {
-                chart.data.datasets.forEach((dataset) => {
-                    dataset.data.pop();
-                });
+                chart.data.datasets = [predictionDataset('Prediction', 'rgb(255, 99, 132)', result["probabilities"])]
+                chart.update();
+                $("#prediction-text").text(describePrediction(result["slot"] == "canary" ? "New" : "Current", result))
 
-                chart.data.datasets[0].data = result
+                if (/Shadow/.test($("#cur-strategy-text").text())) {
+                    // the new model answers in the background
+                    setTimeout(refreshShadow, 1000)
+                }
+            },
+            error: function(xhr, resp, text) {
+                console.log(xhr, resp, text);
+            }
+        })
+    })
+
+
+    $("#compare-btn").click(function() {
+        $.ajax({
+            url: '/model:compare?model-name=' + encodeURIComponent(curModelName),
+            type: "POST",
+            dataType: 'json',
+            contentType: "application/json; charset=utf-8",
+            data: JSON.stringify(
+                getImage()
+            ),
+            success : function(result) {
+                chart.data.datasets = [
+                    predictionDataset('Current Model', 'rgb(255, 99, 132)', result["prod"]["probabilities"] || []),
+                    predictionDataset('New Model', 'rgb(54, 162, 235)', result["canary"]["probabilities"] || [])
+                ]
                 chart.update();
+
+                $("#prediction-text").text((result["agree"] ? "Agree" : "Disagree") + " - "
+                    + describePrediction("Current", result["prod"]) + ", " + describePrediction("New", result["canary"]))
             },
             error: function(xhr, resp, text) {
+                alert(xhr.responseText)
                 console.log(xhr, resp, text);
             }
         })
@@ -0,0 +1,149 @@
+package tfserving
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/josh9191/mini-mnist-serving/tfserving/apis"
+	"github.com/josh9191/mini-mnist-serving/tfserving/framework"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// DefaultInputName is the input of the serving signature of the Keras model trained by train/train-mnist.ipynb
+const DefaultInputName = "input_1"
+
+// Client sends prediction requests to PredictionService of Tensorflow Serving and keeps the connection of each host
+type Client struct {
+	inputName   string
+	dialOptions []grpc.DialOption
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// NewClient returns Client sending images as the input tensor inputName
+// The connections are not encrypted unless dialOptions set the transport credentials.
+func NewClient(inputName string, dialOptions ...grpc.DialOption) *Client {
+	options := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	return &Client{
+		inputName:   inputName,
+		dialOptions: append(options, dialOptions...),
+		conns:       make(map[string]*grpc.ClientConn),
+	}
+}
+
+// Predict sends images to the model served at host (with port) and returns the probabilities of each image
+// shape is the shape of each image, e.g. 28, 28, 1.
+func (c *Client) Predict(ctx context.Context, host string, modelName string, images [][]float32, shape ...int64) ([][]float32, error) {
+	conn, err := c.conn(host)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := apis.NewPredictionServiceClient(conn).Predict(ctx, NewPredictRequest(modelName, c.inputName, images, shape...))
+	if err != nil {
+		return nil, err
+	}
+	return Probabilities(resp, len(images))
+}
+
+// Close closes the connections of every host
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var firstErr error
+	for host, conn := range c.conns {
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(c.conns, host)
+	}
+	return firstErr
+}
+
+// conn returns the connection of host, which connects in the background
+func (c *Client) conn(host string) (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if conn, ok := c.conns[host]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.Dial(host, c.dialOptions...)
+	if err != nil {
+		return nil, err
+	}
+	c.conns[host] = conn
+	return conn, nil
+}
+
+// NewPredictRequest returns the request of images as a float tensor of [len(images)] + shape
+func NewPredictRequest(modelName string, inputName string, images [][]float32, shape ...int64) *apis.PredictRequest {
+	dims := []*framework.TensorShapeProto_Dim{{Size: int64(len(images))}}
+	for _, dim := range shape {
+		dims = append(dims, &framework.TensorShapeProto_Dim{Size: dim})
+	}
+	var values []float32
+	for _, image := range images {
+		values = append(values, image...)
+	}
+
+	return &apis.PredictRequest{
+		ModelSpec: &apis.ModelSpec{Name: modelName},
+		Inputs: map[string]*framework.TensorProto{
+			inputName: {
+				Dtype:       framework.DataType_DT_FLOAT,
+				TensorShape: &framework.TensorShapeProto{Dim: dims},
+				FloatVal:    values,
+			},
+		},
+	}
+}
+
+// Probabilities returns the only output of the response split into numImages rows
+func Probabilities(resp *apis.PredictResponse, numImages int) ([][]float32, error) {
+	if len(resp.GetOutputs()) != 1 {
+		return nil, fmt.Errorf("The model should have one output, but has %d outputs.", len(resp.GetOutputs()))
+	}
+	var values []float32
+	for _, output := range resp.GetOutputs() {
+		var err error
+		values, err = FloatValues(output)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if numImages == 0 || len(values) == 0 || len(values)%numImages != 0 {
+		return nil, fmt.Errorf("The output of %d values doesn't match %d images.", len(values), numImages)
+	}
+
+	rowSize := len(values) / numImages
+	probabilities := make([][]float32, numImages)
+	for i := range probabilities {
+		probabilities[i] = values[i*rowSize : (i+1)*rowSize]
+	}
+	return probabilities, nil
+}
+
+// FloatValues returns the values of the float tensor, which are in either tensor_content or float_val
+func FloatValues(tensor *framework.TensorProto) ([]float32, error) {
+	if tensor.GetDtype() != framework.DataType_DT_FLOAT {
+		return nil, fmt.Errorf("Unsupported output type: %v", tensor.GetDtype())
+	}
+	content := tensor.GetTensorContent()
+	if len(content) == 0 {
+		return tensor.GetFloatVal(), nil
+	}
+	if len(content)%4 != 0 {
+		return nil, fmt.Errorf("Invalid tensor content of %d bytes.", len(content))
+	}
+	// the raw content of tensors is little-endian
+	values := make([]float32, len(content)/4)
+	for i := range values {
+		values[i] = math.Float32frombits(binary.LittleEndian.Uint32(content[i*4:]))
+	}
+	return values, nil
+}
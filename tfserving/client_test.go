@@ -0,0 +1,130 @@
+package tfserving
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/josh9191/mini-mnist-serving/tfserving/apis"
+	"github.com/josh9191/mini-mnist-serving/tfserving/framework"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakePredictionServer predicts the first value of each image as its class out of 10 classes
+type fakePredictionServer struct {
+	apis.UnimplementedPredictionServiceServer
+	// tensorContent returns the output in tensor_content instead of float_val
+	tensorContent bool
+	requests      []*apis.PredictRequest
+}
+
+func (s *fakePredictionServer) Predict(ctx context.Context, req *apis.PredictRequest) (*apis.PredictResponse, error) {
+	s.requests = append(s.requests, req)
+	input, ok := req.GetInputs()[DefaultInputName]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "input %v missing", DefaultInputName)
+	}
+	dims := input.GetTensorShape().GetDim()
+	numImages := int(dims[0].GetSize())
+	imageSize := len(input.GetFloatVal()) / numImages
+
+	probabilities := make([]float32, numImages*10)
+	for i := 0; i < numImages; i++ {
+		probabilities[i*10+int(input.GetFloatVal()[i*imageSize])] = 1
+	}
+	output := &framework.TensorProto{
+		Dtype: framework.DataType_DT_FLOAT,
+		TensorShape: &framework.TensorShapeProto{Dim: []*framework.TensorShapeProto_Dim{
+			{Size: int64(numImages)}, {Size: 10},
+		}},
+	}
+	if s.tensorContent {
+		output.TensorContent = make([]byte, len(probabilities)*4)
+		for i, value := range probabilities {
+			binary.LittleEndian.PutUint32(output.TensorContent[i*4:], math.Float32bits(value))
+		}
+	} else {
+		output.FloatVal = probabilities
+	}
+	return &apis.PredictResponse{
+		ModelSpec: req.GetModelSpec(),
+		Outputs:   map[string]*framework.TensorProto{"dense_1": output},
+	}, nil
+}
+
+// newBufconnClient returns Client connected to predictionServer in memory
+func newBufconnClient(t *testing.T, predictionServer apis.PredictionServiceServer) *Client {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	apis.RegisterPredictionServiceServer(server, predictionServer)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	client := NewClient(DefaultInputName, grpc.WithContextDialer(func(ctx context.Context, host string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestClientPredict(t *testing.T) {
+	for _, tensorContent := range []bool{false, true} {
+		predictionServer := &fakePredictionServer{tensorContent: tensorContent}
+		client := newBufconnClient(t, predictionServer)
+
+		images := [][]float32{make([]float32, 4), make([]float32, 4)}
+		images[0][0] = 3
+		images[1][0] = 7
+		probabilities, err := client.Predict(context.TODO(), "bufconn", "mnist-cnn", images, 2, 2, 1)
+		if err != nil {
+			t.Fatalf("Tensor content %v: %v", tensorContent, err)
+		}
+		for i, class := range []int{3, 7} {
+			if len(probabilities[i]) != 10 || probabilities[i][class] != 1 {
+				t.Errorf("Tensor content %v: wrong probabilities of image %d: %v", tensorContent, i, probabilities[i])
+			}
+		}
+
+		req := predictionServer.requests[0]
+		var shape []int64
+		for _, dim := range req.GetInputs()[DefaultInputName].GetTensorShape().GetDim() {
+			shape = append(shape, dim.GetSize())
+		}
+		if req.GetModelSpec().GetName() != "mnist-cnn" || !reflect.DeepEqual(shape, []int64{2, 2, 2, 1}) {
+			t.Errorf("Wrong request: %v", req)
+		}
+	}
+}
+
+func TestClientPredictError(t *testing.T) {
+	client := newBufconnClient(t, &fakePredictionServer{})
+	client.inputName = "images"
+	_, err := client.Predict(context.TODO(), "bufconn", "mnist-cnn", [][]float32{{0}}, 1)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
+
+func TestProbabilities(t *testing.T) {
+	float := func(values ...float32) *framework.TensorProto {
+		return &framework.TensorProto{Dtype: framework.DataType_DT_FLOAT, FloatVal: values}
+	}
+	for _, resp := range []*apis.PredictResponse{
+		{},
+		{Outputs: map[string]*framework.TensorProto{"a": float(1), "b": float(1)}},
+		{Outputs: map[string]*framework.TensorProto{"a": {Dtype: framework.DataType_DT_INT64, Int64Val: []int64{1, 2}}}},
+		{Outputs: map[string]*framework.TensorProto{"a": {Dtype: framework.DataType_DT_FLOAT, TensorContent: []byte{1, 2, 3}}}},
+		{Outputs: map[string]*framework.TensorProto{"a": float(1, 2, 3)}},
+	} {
+		if _, err := Probabilities(resp, 2); err == nil {
+			t.Errorf("Invalid response is accepted: %v", resp)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+// Package tfserving is the gRPC client of PredictionService of Tensorflow Serving.
+// The messages are generated from the protos of Tensorflow Serving copied to proto/, which keep only what predictions need.
+package tfserving
+
+//go:generate protoc -I proto --go_out=. --go_opt=module=github.com/josh9191/mini-mnist-serving/tfserving --go-grpc_out=. --go-grpc_opt=module=github.com/josh9191/mini-mnist-serving/tfserving tensorflow/core/framework/types.proto tensorflow/core/framework/tensor_shape.proto tensorflow/core/framework/tensor.proto tensorflow_serving/apis/model.proto tensorflow_serving/apis/predict.proto tensorflow_serving/apis/prediction_service.proto
@@ -0,0 +1,177 @@
+package traffic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/registry"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	// HTTPRouteResource is the resource of Gateway API HTTPRoute
+	HTTPRouteResource = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "httproutes"}
+	// ReferenceGrantResource is the resource of Gateway API ReferenceGrant
+	ReferenceGrantResource = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1beta1", Resource: "referencegrants"}
+)
+
+// referenceGrantName is the name of ReferenceGrant allowing the routes in the production namespace
+// to send requests to the services of the canary namespace
+const referenceGrantName = "mnist-prod-routes"
+
+// GatewayBackend routes requests by an HTTPRoute of each model attached to the Gateway,
+// which splits them between the services of slots by weighted backendRefs
+type GatewayBackend struct {
+	*routes
+	gatewayNamespace string
+	gatewayName      string
+	// upstreamHeader adds the filter setting constants.CanaryUpstreamHeader to the backendRef of the new model
+	upstreamHeader bool
+}
+
+// NewGatewayBackend returns GatewayBackend attaching HTTPRoute objects to the gateway "<namespace>/<name>"
+func NewGatewayBackend(client dynamic.Interface, gateway string) (*GatewayBackend, error) {
+	parts := strings.Split(gateway, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("Invalid gateway %q: it should be <namespace>/<name>", gateway)
+	}
+
+	b := &GatewayBackend{gatewayNamespace: parts[0], gatewayName: parts[1]}
+	b.routes = &routes{
+		client:    client,
+		resource:  HTTPRouteResource,
+		kind:      "HTTPRoute",
+		buildSpec: b.buildSpec,
+	}
+	return b, nil
+}
+
+// Name returns the name of backend
+func (b *GatewayBackend) Name() string {
+	return Gateway
+}
+
+// EnableUpstreamHeader makes the route report the new model serving the request by a ResponseHeaderModifier filter
+// of its backendRef. The filters of backendRefs are not supported by every Gateway API implementation,
+// which may reject the route or ignore the filter.
+func (b *GatewayBackend) EnableUpstreamHeader() {
+	b.upstreamHeader = true
+}
+
+// ReportsUpstream reports whether the backendRef of the new model has the header filter
+func (b *GatewayBackend) ReportsUpstream() bool {
+	return b.upstreamHeader
+}
+
+// Deploy adds the slot to the HTTPRoute of model
+// The ReferenceGrant is created with the new model, because the HTTPRoute refers to its service in another namespace.
+func (b *GatewayBackend) Deploy(ctx context.Context, modelName string, isNewModel bool, host string) (string, error) {
+	if isNewModel {
+		_, err := createIfNotExists(ctx, b.client.Resource(ReferenceGrantResource).Namespace(constants.CanaryNamespace), referenceGrant())
+		if err != nil {
+			return "", err
+		}
+	}
+	return b.deploy(ctx, modelName, isNewModel, host)
+}
+
+// Undeploy removes the slot from the HTTPRoute of model
+// The ReferenceGrant is kept, because it is shared by the models.
+func (b *GatewayBackend) Undeploy(ctx context.Context, modelName string, isNewModel bool) ([]string, error) {
+	return b.undeploy(ctx, modelName, isNewModel)
+}
+
+func (b *GatewayBackend) buildSpec(modelName string, state *routeState) map[string]interface{} {
+	rules := []interface{}{}
+	for _, rule := range routeRules(state) {
+		backendRefs := []interface{}{}
+		for _, isNewModel := range []bool{false, true} {
+			weight, ok := slotWeights(state, rule)[isNewModel]
+			if !ok {
+				continue
+			}
+			backendRef := map[string]interface{}{
+				"name":      registry.ServiceName(modelName),
+				"namespace": slotNamespace(isNewModel),
+				"port":      int64(8501),
+				"weight":    weight,
+			}
+			if isNewModel && b.upstreamHeader {
+				backendRef["filters"] = []interface{}{
+					map[string]interface{}{
+						"type": "ResponseHeaderModifier",
+						"responseHeaderModifier": map[string]interface{}{
+							"set": []interface{}{
+								map[string]interface{}{"name": constants.CanaryUpstreamHeader, "value": upstreamName(modelName)},
+							},
+						},
+					},
+				}
+			}
+			backendRefs = append(backendRefs, backendRef)
+		}
+		if len(backendRefs) == 0 {
+			continue
+		}
+
+		match := map[string]interface{}{
+			"path": map[string]interface{}{"type": "PathPrefix", "value": registry.IngressPath(modelName)},
+		}
+		if rule.header != "" {
+			matchType := "Exact"
+			if rule.regex {
+				matchType = "RegularExpression"
+			}
+			match["headers"] = []interface{}{
+				map[string]interface{}{"type": matchType, "name": rule.header, "value": rule.value},
+			}
+		}
+		rules = append(rules, map[string]interface{}{
+			"matches": []interface{}{match},
+			"filters": []interface{}{
+				map[string]interface{}{
+					"type": "URLRewrite",
+					"urlRewrite": map[string]interface{}{
+						"path": map[string]interface{}{"type": "ReplaceFullPath", "replaceFullPath": predictPath(modelName)},
+					},
+				},
+			},
+			"backendRefs": backendRefs,
+		})
+	}
+
+	spec := map[string]interface{}{
+		"parentRefs": []interface{}{
+			map[string]interface{}{"namespace": b.gatewayNamespace, "name": b.gatewayName},
+		},
+		"rules": rules,
+	}
+	if state.Host != "" {
+		spec["hostnames"] = []interface{}{state.Host}
+	}
+	return spec
+}
+
+// referenceGrant returns the ReferenceGrant of the canary namespace
+func referenceGrant() *unstructured.Unstructured {
+	grant := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"from": []interface{}{
+				map[string]interface{}{"group": HTTPRouteResource.Group, "kind": "HTTPRoute", "namespace": constants.ProdNamespace},
+			},
+			"to": []interface{}{
+				map[string]interface{}{"group": "", "kind": "Service"},
+			},
+		},
+	}}
+	grant.SetAPIVersion(ReferenceGrantResource.GroupVersion().String())
+	grant.SetKind("ReferenceGrant")
+	grant.SetName(referenceGrantName)
+	grant.SetNamespace(constants.CanaryNamespace)
+	return grant
+}
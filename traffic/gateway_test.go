@@ -0,0 +1,205 @@
+package traffic
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/registry"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+// getSpecSlice returns the slice in the spec of object
+func getSpecSlice(t *testing.T, client *fake.FakeDynamicClient, resource schema.GroupVersionResource, namespace string, name string, field string) []interface{} {
+	obj, err := client.Resource(resource).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, _, err := unstructured.NestedSlice(obj.Object, "spec", field)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+// backendWeights returns the weight of each namespace of backendRefs in HTTPRoute rule
+func backendWeights(rule interface{}) map[string]int64 {
+	weights := make(map[string]int64)
+	backendRefs, _, _ := unstructured.NestedSlice(rule.(map[string]interface{}), "backendRefs")
+	for _, backendRef := range backendRefs {
+		backendRef := backendRef.(map[string]interface{})
+		weights[backendRef["namespace"].(string)] = backendRef["weight"].(int64)
+	}
+	return weights
+}
+
+func TestNewGatewayBackend(t *testing.T) {
+	for _, gateway := range []string{"", "mnist-gateway", "/mnist-gateway", "gateway-ns/", "a/b/c"} {
+		if _, err := NewGatewayBackend(fake.NewSimpleDynamicClient(runtime.NewScheme()), gateway); err == nil {
+			t.Errorf("Invalid gateway %q is accepted", gateway)
+		}
+	}
+}
+
+func TestGatewayBackend(t *testing.T) {
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	b, err := NewGatewayBackend(client, "gateway-ns/mnist-gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.TODO()
+	routeName := registry.RouteName(testModelName)
+
+	if _, err := b.Deploy(ctx, testModelName, false, "mini-serving.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.GetStrategy(ctx, testModelName); !errors.IsNotFound(err) {
+		t.Errorf("Strategy without new model: %v", err)
+	}
+	if _, err := b.Deploy(ctx, testModelName, true, "mini-serving.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Resource(ReferenceGrantResource).Namespace(constants.CanaryNamespace).Get(ctx, referenceGrantName, metav1.GetOptions{}); err != nil {
+		t.Errorf("ReferenceGrant is not created: %v", err)
+	}
+
+	obj, err := client.Resource(HTTPRouteResource).Namespace(constants.ProdNamespace).Get(ctx, routeName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parentRefs, _, _ := unstructured.NestedSlice(obj.Object, "spec", "parentRefs")
+	hostnames, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "hostnames")
+	if !reflect.DeepEqual(parentRefs, []interface{}{map[string]interface{}{"namespace": "gateway-ns", "name": "mnist-gateway"}}) {
+		t.Errorf("Wrong parentRefs: %v", parentRefs)
+	}
+	if !reflect.DeepEqual(hostnames, []string{"mini-serving.example.com"}) {
+		t.Errorf("Wrong hostnames: %v", hostnames)
+	}
+	// the new model receives no request after deployment
+	rules := getSpecSlice(t, client, HTTPRouteResource, constants.ProdNamespace, routeName, "rules")
+	if len(rules) != 1 || !reflect.DeepEqual(backendWeights(rules[0]), map[string]int64{constants.ProdNamespace: 100}) {
+		t.Errorf("Wrong rules of new model: %v", rules)
+	}
+	rewrite, _, _ := unstructured.NestedString(rules[0].(map[string]interface{})["filters"].([]interface{})[0].(map[string]interface{}), "urlRewrite", "path", "replaceFullPath")
+	if rewrite != "/v1/models/mnist-cnn:predict" {
+		t.Errorf("Wrong rewrite: %v", rewrite)
+	}
+
+	// canary splits the default rule
+	if _, err := b.SetStrategy(ctx, testModelName, Strategy{Strategy: constants.Canary, Weight: 30}); err != nil {
+		t.Fatal(err)
+	}
+	rules = getSpecSlice(t, client, HTTPRouteResource, constants.ProdNamespace, routeName, "rules")
+	if len(rules) != 1 || !reflect.DeepEqual(backendWeights(rules[0]), map[string]int64{constants.ProdNamespace: 70, constants.CanaryNamespace: 30}) {
+		t.Errorf("Wrong rules of canary: %v", rules)
+	}
+
+	// targeted matches the header values and the cookie before the default rule
+	target := &registry.TargetRule{Header: "X-Client-Id", Values: []string{"alice", "bob"}, Cookie: "beta"}
+	if _, err := b.SetStrategy(ctx, testModelName, Strategy{Strategy: constants.Targeted, Target: target}); err != nil {
+		t.Fatal(err)
+	}
+	rules = getSpecSlice(t, client, HTTPRouteResource, constants.ProdNamespace, routeName, "rules")
+	if len(rules) != 4 {
+		t.Fatalf("Wrong rules of targeted: %v", rules)
+	}
+	for i, want := range []string{"alice", "bob"} {
+		headers, _, _ := unstructured.NestedSlice(rules[i].(map[string]interface{})["matches"].([]interface{})[0].(map[string]interface{}), "headers")
+		if !reflect.DeepEqual(headers, []interface{}{map[string]interface{}{"type": "Exact", "name": "X-Client-Id", "value": want}}) {
+			t.Errorf("Wrong header match: %v", headers)
+		}
+		if !reflect.DeepEqual(backendWeights(rules[i]), map[string]int64{constants.CanaryNamespace: 100}) {
+			t.Errorf("Wrong backends of header match: %v", rules[i])
+		}
+	}
+	if !reflect.DeepEqual(backendWeights(rules[3]), map[string]int64{constants.ProdNamespace: 100}) {
+		t.Errorf("Wrong backends of default rule: %v", rules[3])
+	}
+
+	strategy, err := b.GetStrategy(ctx, testModelName)
+	if err != nil || !reflect.DeepEqual(*strategy, Strategy{Strategy: constants.Targeted, Target: target}) {
+		t.Errorf("Wrong strategy: %+v, %v", strategy, err)
+	}
+	if _, err := b.SetStrategy(ctx, testModelName, Strategy{Strategy: constants.Targeted}); err == nil {
+		t.Errorf("Targeted strategy without target is set")
+	}
+
+	deleted, err := b.Undeploy(ctx, testModelName, true)
+	if err != nil || len(deleted) != 0 {
+		t.Errorf("Wrong deleted objects of new model: %v, %v", deleted, err)
+	}
+	if err := b.CheckSlot(ctx, testModelName, true); !errors.IsNotFound(err) {
+		t.Errorf("New model is routed after undeploy: %v", err)
+	}
+	if err := b.CheckSlot(ctx, testModelName, false); err != nil {
+		t.Errorf("Current model is not routed: %v", err)
+	}
+	rules = getSpecSlice(t, client, HTTPRouteResource, constants.ProdNamespace, routeName, "rules")
+	if len(rules) != 1 || !reflect.DeepEqual(backendWeights(rules[0]), map[string]int64{constants.ProdNamespace: 100}) {
+		t.Errorf("Wrong rules after undeploy: %v", rules)
+	}
+
+	deleted, err = b.Undeploy(ctx, testModelName, false)
+	if err != nil || !reflect.DeepEqual(deleted, []string{"httproute/" + routeName}) {
+		t.Errorf("Wrong deleted objects of current model: %v, %v", deleted, err)
+	}
+}
+
+func TestGatewayBackendUpstreamHeader(t *testing.T) {
+	ctx := context.TODO()
+	for _, upstreamHeader := range []bool{false, true} {
+		client := fake.NewSimpleDynamicClient(runtime.NewScheme())
+		b, err := NewGatewayBackend(client, "gateway-ns/mnist-gateway")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if upstreamHeader {
+			b.EnableUpstreamHeader()
+		}
+		for _, isNewModel := range []bool{false, true} {
+			if _, err := b.Deploy(ctx, testModelName, isNewModel, "mini-serving.example.com"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := b.SetStrategy(ctx, testModelName, Strategy{Strategy: constants.Canary, Weight: 30}); err != nil {
+			t.Fatal(err)
+		}
+
+		// the filters of backendRefs are not supported by every implementation
+		filters := 0
+		for _, rule := range getSpecSlice(t, client, HTTPRouteResource, constants.ProdNamespace, registry.RouteName(testModelName), "rules") {
+			for _, backendRef := range rule.(map[string]interface{})["backendRefs"].([]interface{}) {
+				if _, ok := backendRef.(map[string]interface{})["filters"]; ok {
+					filters++
+				}
+			}
+		}
+		if (filters == 1) != upstreamHeader || b.ReportsUpstream() != upstreamHeader {
+			t.Errorf("Upstream header %v: %d backendRef filters", upstreamHeader, filters)
+		}
+	}
+}
+
+func TestCookiePattern(t *testing.T) {
+	pattern := regexp.MustCompile(cookiePattern("beta"))
+	for cookie, want := range map[string]bool{
+		"beta=always":                 true,
+		"session=1; beta=always":      true,
+		"beta=always; session=1":      true,
+		"beta=never":                  false,
+		"alphabeta=always":            false,
+		"session=1; beta=alwaysfalse": false,
+	} {
+		if pattern.MatchString(cookie) != want {
+			t.Errorf("%q: got %v, want %v", cookie, !want, want)
+		}
+	}
+}
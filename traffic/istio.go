@@ -0,0 +1,172 @@
+package traffic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/registry"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	// VirtualServiceResource is the resource of Istio VirtualService
+	VirtualServiceResource = schema.GroupVersionResource{Group: "networking.istio.io", Version: "v1beta1", Resource: "virtualservices"}
+	// DestinationRuleResource is the resource of Istio DestinationRule
+	DestinationRuleResource = schema.GroupVersionResource{Group: "networking.istio.io", Version: "v1beta1", Resource: "destinationrules"}
+)
+
+// IstioBackend routes requests by a VirtualService of each model bound to the Istio gateway,
+// which splits them between the subsets of slots defined by a DestinationRule of each slot
+type IstioBackend struct {
+	*routes
+	gateway string
+}
+
+// NewIstioBackend returns IstioBackend binding VirtualService objects to the gateway "<namespace>/<name>"
+func NewIstioBackend(client dynamic.Interface, gateway string) (*IstioBackend, error) {
+	parts := strings.Split(gateway, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("Invalid gateway %q: it should be <namespace>/<name>", gateway)
+	}
+
+	b := &IstioBackend{gateway: gateway}
+	b.routes = &routes{
+		client:    client,
+		resource:  VirtualServiceResource,
+		kind:      "VirtualService",
+		buildSpec: b.buildSpec,
+	}
+	return b, nil
+}
+
+// Name returns the name of backend
+func (b *IstioBackend) Name() string {
+	return Istio
+}
+
+// ReportsUpstream returns true because the destination of the new model sets constants.CanaryUpstreamHeader
+func (b *IstioBackend) ReportsUpstream() bool {
+	return true
+}
+
+// Deploy creates the DestinationRule of slot and adds the slot to the VirtualService of model
+func (b *IstioBackend) Deploy(ctx context.Context, modelName string, isNewModel bool, host string) (string, error) {
+	_, err := createIfNotExists(ctx, b.client.Resource(DestinationRuleResource).Namespace(slotNamespace(isNewModel)), destinationRule(modelName, isNewModel))
+	if err != nil {
+		return "", err
+	}
+	return b.deploy(ctx, modelName, isNewModel, host)
+}
+
+// Undeploy removes the slot from the VirtualService of model and deletes the DestinationRule of slot
+func (b *IstioBackend) Undeploy(ctx context.Context, modelName string, isNewModel bool) ([]string, error) {
+	deleted, err := b.undeploy(ctx, modelName, isNewModel)
+	if err != nil {
+		return deleted, err
+	}
+
+	name := registry.DestinationRuleName(modelName)
+	err = b.client.Resource(DestinationRuleResource).Namespace(slotNamespace(isNewModel)).Delete(ctx, name, metav1.DeleteOptions{})
+	if err == nil {
+		deleted = append(deleted, "destinationrule/"+name)
+	} else if !errors.IsNotFound(err) {
+		return deleted, err
+	}
+	return deleted, nil
+}
+
+func (b *IstioBackend) buildSpec(modelName string, state *routeState) map[string]interface{} {
+	http := []interface{}{}
+	for _, rule := range routeRules(state) {
+		route := []interface{}{}
+		for _, isNewModel := range []bool{false, true} {
+			weight, ok := slotWeights(state, rule)[isNewModel]
+			if !ok {
+				continue
+			}
+			destination := map[string]interface{}{
+				"destination": map[string]interface{}{
+					"host":   serviceHost(modelName, isNewModel),
+					"subset": subsetName(isNewModel),
+					"port":   map[string]interface{}{"number": int64(8501)},
+				},
+				"weight": weight,
+			}
+			if isNewModel {
+				destination["headers"] = map[string]interface{}{
+					"response": map[string]interface{}{
+						"set": map[string]interface{}{constants.CanaryUpstreamHeader: upstreamName(modelName)},
+					},
+				}
+			}
+			route = append(route, destination)
+		}
+		if len(route) == 0 {
+			continue
+		}
+
+		match := map[string]interface{}{
+			"uri": map[string]interface{}{"prefix": registry.IngressPath(modelName)},
+		}
+		if rule.header != "" {
+			matchType := "exact"
+			if rule.regex {
+				matchType = "regex"
+			}
+			// Istio matches lower case header names
+			match["headers"] = map[string]interface{}{
+				strings.ToLower(rule.header): map[string]interface{}{matchType: rule.value},
+			}
+		}
+		http = append(http, map[string]interface{}{
+			"match":   []interface{}{match},
+			"rewrite": map[string]interface{}{"uri": predictPath(modelName)},
+			"route":   route,
+		})
+	}
+
+	host := state.Host
+	if host == "" {
+		host = "*"
+	}
+	return map[string]interface{}{
+		"hosts":    []interface{}{host},
+		"gateways": []interface{}{b.gateway},
+		"http":     http,
+	}
+}
+
+// destinationRule returns the DestinationRule defining the subset of slot
+func destinationRule(modelName string, isNewModel bool) *unstructured.Unstructured {
+	labels := map[string]interface{}{}
+	for key, value := range registry.Labels(modelName) {
+		labels[key] = value
+	}
+	rule := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"host": serviceHost(modelName, isNewModel),
+			"subsets": []interface{}{
+				map[string]interface{}{"name": subsetName(isNewModel), "labels": labels},
+			},
+		},
+	}}
+	rule.SetAPIVersion(DestinationRuleResource.GroupVersion().String())
+	rule.SetKind("DestinationRule")
+	rule.SetName(registry.DestinationRuleName(modelName))
+	rule.SetNamespace(slotNamespace(isNewModel))
+	return rule
+}
+
+func subsetName(isNewModel bool) string {
+	if isNewModel {
+		return "canary"
+	}
+	return "prod"
+}
@@ -0,0 +1,102 @@
+package traffic
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/registry"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+// destinationWeights returns the weight of each subset of VirtualService route
+func destinationWeights(route interface{}) map[string]int64 {
+	weights := make(map[string]int64)
+	destinations, _, _ := unstructured.NestedSlice(route.(map[string]interface{}), "route")
+	for _, destination := range destinations {
+		destination := destination.(map[string]interface{})
+		subset, _, _ := unstructured.NestedString(destination, "destination", "subset")
+		weights[subset] = destination["weight"].(int64)
+	}
+	return weights
+}
+
+func TestIstioBackend(t *testing.T) {
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	b, err := NewIstioBackend(client, "istio-system/mnist-gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.TODO()
+	routeName := registry.RouteName(testModelName)
+
+	for _, isNewModel := range []bool{false, true} {
+		if _, err := b.Deploy(ctx, testModelName, isNewModel, ""); err != nil {
+			t.Fatal(err)
+		}
+		subsets := getSpecSlice(t, client, DestinationRuleResource, slotNamespace(isNewModel), registry.DestinationRuleName(testModelName), "subsets")
+		if len(subsets) != 1 || subsets[0].(map[string]interface{})["name"] != subsetName(isNewModel) {
+			t.Errorf("Wrong subsets: %v", subsets)
+		}
+	}
+
+	obj, err := client.Resource(VirtualServiceResource).Namespace(constants.ProdNamespace).Get(ctx, routeName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "hosts")
+	gateways, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "gateways")
+	if !reflect.DeepEqual(hosts, []string{"*"}) || !reflect.DeepEqual(gateways, []string{"istio-system/mnist-gateway"}) {
+		t.Errorf("Wrong hosts and gateways: %v, %v", hosts, gateways)
+	}
+
+	// new model only matches the canary header first
+	if _, err := b.SetStrategy(ctx, testModelName, Strategy{Strategy: constants.NewModelOnly}); err != nil {
+		t.Fatal(err)
+	}
+	http := getSpecSlice(t, client, VirtualServiceResource, constants.ProdNamespace, routeName, "http")
+	if len(http) != 2 {
+		t.Fatalf("Wrong routes of new model only: %v", http)
+	}
+	headers, _, _ := unstructured.NestedMap(http[0].(map[string]interface{})["match"].([]interface{})[0].(map[string]interface{}), "headers")
+	if !reflect.DeepEqual(headers, map[string]interface{}{"usecanary": map[string]interface{}{"exact": "always"}}) {
+		t.Errorf("Wrong header match: %v", headers)
+	}
+	if !reflect.DeepEqual(destinationWeights(http[0]), map[string]int64{"canary": 100}) || !reflect.DeepEqual(destinationWeights(http[1]), map[string]int64{"prod": 100}) {
+		t.Errorf("Wrong destinations: %v", http)
+	}
+	upstream, _, _ := unstructured.NestedString(http[0].(map[string]interface{})["route"].([]interface{})[0].(map[string]interface{}), "headers", "response", "set", constants.CanaryUpstreamHeader)
+	if upstream != "mnist-canary-mnist-cnn-svc-8501" {
+		t.Errorf("Wrong upstream header: %v", upstream)
+	}
+
+	if _, err := b.SetStrategy(ctx, testModelName, Strategy{Strategy: constants.Canary, Weight: 100}); err != nil {
+		t.Fatal(err)
+	}
+	http = getSpecSlice(t, client, VirtualServiceResource, constants.ProdNamespace, routeName, "http")
+	if len(http) != 1 || !reflect.DeepEqual(destinationWeights(http[0]), map[string]int64{"canary": 100}) {
+		t.Errorf("Wrong routes of canary: %v", http)
+	}
+	strategy, err := b.GetStrategy(ctx, testModelName)
+	if err != nil || !reflect.DeepEqual(*strategy, Strategy{Strategy: constants.Canary, Weight: 100}) {
+		t.Errorf("Wrong strategy: %+v, %v", strategy, err)
+	}
+
+	deleted, err := b.Undeploy(ctx, testModelName, true)
+	if err != nil || !reflect.DeepEqual(deleted, []string{"destinationrule/mnist-cnn-dr"}) {
+		t.Errorf("Wrong deleted objects of new model: %v, %v", deleted, err)
+	}
+	if _, err := b.SetStrategy(ctx, testModelName, Strategy{Strategy: constants.NewModelOnly}); !errors.IsNotFound(err) {
+		t.Errorf("Strategy is set without new model: %v", err)
+	}
+	deleted, err = b.Undeploy(ctx, testModelName, false)
+	if err != nil || !reflect.DeepEqual(deleted, []string{"virtualservice/" + routeName, "destinationrule/mnist-cnn-dr"}) {
+		t.Errorf("Wrong deleted objects of current model: %v, %v", deleted, err)
+	}
+}
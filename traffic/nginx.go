@@ -0,0 +1,248 @@
+package traffic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/josh9191/mini-mnist-serving/clients"
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/registry"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// ShadowAnnotation marks the canary ingress of Shadow strategy, which nginx sees as "New Model Only"
+	ShadowAnnotation = "mini-mnist-serving/shadow"
+	// TargetAnnotation stores the target rule of Targeted strategy in JSON
+	TargetAnnotation = "mini-mnist-serving/target"
+)
+
+// NginxBackend routes requests by an ingress of each slot, and the canary annotations of the new model ingress
+type NginxBackend struct {
+	ingressClient clients.IngressClient
+	// upstreamHeader adds the configuration snippet setting constants.CanaryUpstreamHeader to the ingress
+	upstreamHeader bool
+}
+
+// NewNginxBackend returns NginxBackend managing Ingress objects through ingressClient
+func NewNginxBackend(ingressClient clients.IngressClient) *NginxBackend {
+	return &NginxBackend{ingressClient: ingressClient}
+}
+
+// Name returns the name of backend
+func (b *NginxBackend) Name() string {
+	return Nginx
+}
+
+// EnableUpstreamHeader makes the ingress report the new model serving the request by a configuration snippet.
+// ingress-nginx rejects or ignores the snippet unless allow-snippet-annotations is enabled.
+func (b *NginxBackend) EnableUpstreamHeader() {
+	b.upstreamHeader = true
+}
+
+// ReportsUpstream reports whether the ingress has the configuration snippet
+func (b *NginxBackend) ReportsUpstream() bool {
+	return b.upstreamHeader
+}
+
+// Deploy creates or updates the ingress of slot
+func (b *NginxBackend) Deploy(ctx context.Context, modelName string, isNewModel bool, host string) (string, error) {
+	var nginxAnnotations = make(map[string]string)
+	if isNewModel {
+		nginxAnnotations["nginx.ingress.kubernetes.io/canary"] = "true"
+	} else {
+		// If canary option is specified to true, other annotations are ignored
+		// We set rewrite option only to prod model
+		nginxAnnotations["nginx.ingress.kubernetes.io/rewrite-target"] = predictPath(modelName)
+		// The canary ingress inherits the snippet, and the variable is set only when the canary backend is selected,
+		// so the server can tell which model served the request.
+		if b.upstreamHeader {
+			nginxAnnotations["nginx.ingress.kubernetes.io/configuration-snippet"] = fmt.Sprintf("more_set_headers \"%s: $proxy_alternative_upstream_name\";", constants.CanaryUpstreamHeader)
+		}
+	}
+
+	ingress := &clients.Ingress{
+		Name:        registry.IngressName(modelName),
+		Namespace:   slotNamespace(isNewModel),
+		Annotations: nginxAnnotations,
+		ClassName:   constants.IngressClassName,
+		Host:        host,
+		Path:        registry.IngressPath(modelName),
+		ServiceName: registry.ServiceName(modelName),
+		ServicePort: 8501,
+	}
+
+	err := b.ingressClient.Create(ctx, ingress)
+	if !errors.IsAlreadyExists(err) {
+		return "Created", err
+	}
+	log.Printf("The ingress %v already exists.", ingress.Name)
+	return "Updated", b.ingressClient.Update(ctx, ingress)
+}
+
+// CheckSlot returns NotFound error when the ingress of slot doesn't exist
+func (b *NginxBackend) CheckSlot(ctx context.Context, modelName string, isNewModel bool) error {
+	_, err := b.ingressClient.Get(ctx, slotNamespace(isNewModel), registry.IngressName(modelName))
+	return err
+}
+
+// SetStrategy sets the canary annotations of the new model ingress
+func (b *NginxBackend) SetStrategy(ctx context.Context, modelName string, strategy Strategy) (string, error) {
+	if err := Validate(strategy); err != nil {
+		return "", err
+	}
+	ingress, err := b.ingressClient.Get(ctx, slotNamespace(true), registry.IngressName(modelName))
+	if err != nil {
+		return "", err
+	}
+
+	err = setStrategyAnnotations(ingress.Annotations, strategy)
+	if err != nil {
+		return "", err
+	}
+	return StrategyName(strategy.Strategy), b.ingressClient.Update(ctx, ingress)
+}
+
+// GetStrategy returns the strategy of the canary annotations of the new model ingress
+func (b *NginxBackend) GetStrategy(ctx context.Context, modelName string) (*Strategy, error) {
+	ingress, err := b.ingressClient.Get(ctx, slotNamespace(true), registry.IngressName(modelName))
+	if err != nil {
+		return nil, err
+	}
+	return strategyOfAnnotations(ingress.Annotations)
+}
+
+// Undeploy deletes the ingress of slot
+func (b *NginxBackend) Undeploy(ctx context.Context, modelName string, isNewModel bool) ([]string, error) {
+	namespace := slotNamespace(isNewModel)
+	ingressName := registry.IngressName(modelName)
+	deleted := []string{}
+
+	if isNewModel {
+		// send every request to the current model before removing the canary ingress
+		ingress, err := b.ingressClient.Get(ctx, namespace, ingressName)
+		if err == nil {
+			setStrategyAnnotations(ingress.Annotations, Strategy{Strategy: constants.CurrentModelOnly})
+			err = b.ingressClient.Update(ctx, ingress)
+			if err != nil {
+				return deleted, err
+			}
+		} else if !errors.IsNotFound(err) {
+			return deleted, err
+		}
+	}
+
+	err := b.ingressClient.Delete(ctx, namespace, ingressName)
+	if err == nil {
+		deleted = append(deleted, "ingress/"+ingressName)
+	} else if !errors.IsNotFound(err) {
+		return deleted, err
+	}
+	return deleted, nil
+}
+
+// setStrategyAnnotations sets nginx canary annotations of the canary ingress
+func setStrategyAnnotations(annotations map[string]string, strategy Strategy) error {
+	deleteMapKeyIfExists(annotations, "nginx.ingress.kubernetes.io/canary-by-header-value")
+	deleteMapKeyIfExists(annotations, "nginx.ingress.kubernetes.io/canary-by-header-pattern")
+	deleteMapKeyIfExists(annotations, "nginx.ingress.kubernetes.io/canary-by-cookie")
+	if strategy.Strategy == constants.CurrentModelOnly {
+		annotations["nginx.ingress.kubernetes.io/canary"] = "false"
+		deleteMapKeyIfExists(annotations, "nginx.ingress.kubernetes.io/canary-by-header")
+		deleteMapKeyIfExists(annotations, "nginx.ingress.kubernetes.io/canary-weight")
+	} else if strategy.Strategy == constants.NewModelOnly {
+		annotations["nginx.ingress.kubernetes.io/canary"] = "true"
+		annotations["nginx.ingress.kubernetes.io/canary-by-header"] = constants.CanaryHeader
+		deleteMapKeyIfExists(annotations, "nginx.ingress.kubernetes.io/canary-weight")
+	} else if strategy.Strategy == constants.Shadow {
+		// users are served by the current model, and the server sends the copies of requests with the header
+		annotations["nginx.ingress.kubernetes.io/canary"] = "true"
+		annotations["nginx.ingress.kubernetes.io/canary-by-header"] = constants.CanaryHeader
+		deleteMapKeyIfExists(annotations, "nginx.ingress.kubernetes.io/canary-weight")
+		deleteMapKeyIfExists(annotations, TargetAnnotation)
+		annotations[ShadowAnnotation] = "true"
+		return nil
+	} else if strategy.Strategy == constants.Targeted {
+		target := strategy.Target
+		targetJson, err := json.Marshal(target)
+		if err != nil {
+			return err
+		}
+		// requests not matching the rule are sent to the current model without canary weight
+		annotations["nginx.ingress.kubernetes.io/canary"] = "true"
+		deleteMapKeyIfExists(annotations, "nginx.ingress.kubernetes.io/canary-by-header")
+		deleteMapKeyIfExists(annotations, "nginx.ingress.kubernetes.io/canary-weight")
+		if target.Header != "" {
+			annotations["nginx.ingress.kubernetes.io/canary-by-header"] = target.Header
+			if len(target.Values) == 1 {
+				annotations["nginx.ingress.kubernetes.io/canary-by-header-value"] = target.Values[0]
+			} else {
+				annotations["nginx.ingress.kubernetes.io/canary-by-header-pattern"] = targetValuesPattern(target.Values)
+			}
+		}
+		if target.Cookie != "" {
+			annotations["nginx.ingress.kubernetes.io/canary-by-cookie"] = target.Cookie
+		}
+		deleteMapKeyIfExists(annotations, ShadowAnnotation)
+		annotations[TargetAnnotation] = string(targetJson)
+		return nil
+	} else { // else if strategy == constants.Canary
+		annotations["nginx.ingress.kubernetes.io/canary"] = "true"
+		deleteMapKeyIfExists(annotations, "nginx.ingress.kubernetes.io/canary-by-header")
+		annotations["nginx.ingress.kubernetes.io/canary-weight"] = strconv.Itoa(strategy.Weight)
+	}
+	deleteMapKeyIfExists(annotations, ShadowAnnotation)
+	deleteMapKeyIfExists(annotations, TargetAnnotation)
+	return nil
+}
+
+// strategyOfAnnotations returns the strategy set by setStrategyAnnotations
+func strategyOfAnnotations(annotations map[string]string) (*Strategy, error) {
+	if annotations[ShadowAnnotation] == "true" {
+		return &Strategy{Strategy: constants.Shadow}, nil
+	}
+	if targetJson, ok := annotations[TargetAnnotation]; ok {
+		var target registry.TargetRule
+		if err := json.Unmarshal([]byte(targetJson), &target); err != nil {
+			return nil, fmt.Errorf("Invalid target rule: %v", err)
+		}
+		return &Strategy{Strategy: constants.Targeted, Target: &target}, nil
+	}
+	if annotations["nginx.ingress.kubernetes.io/canary"] != "true" {
+		return &Strategy{Strategy: constants.CurrentModelOnly}, nil
+	}
+	if weight, ok := annotations["nginx.ingress.kubernetes.io/canary-weight"]; ok {
+		weightInt, err := strconv.Atoi(weight)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid canary weight: %v", weight)
+		}
+		return &Strategy{Strategy: constants.Canary, Weight: weightInt}, nil
+	}
+	// the canary ingress of newly deployed model has neither weight nor header, which nginx sees as weight 0
+	if annotations["nginx.ingress.kubernetes.io/canary-by-header"] == constants.CanaryHeader {
+		return &Strategy{Strategy: constants.NewModelOnly}, nil
+	}
+	return &Strategy{Strategy: constants.CurrentModelOnly}, nil
+}
+
+// targetValuesPattern returns the regular expression of nginx matching any of values
+func targetValuesPattern(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = regexp.QuoteMeta(value)
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
+
+func deleteMapKeyIfExists(m map[string]string, key string) {
+	_, ok := m[key]
+	if ok {
+		delete(m, key)
+	}
+}
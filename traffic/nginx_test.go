@@ -0,0 +1,103 @@
+package traffic
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/josh9191/mini-mnist-serving/clients"
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/registry"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testModelName = "mnist-cnn"
+
+func TestNginxBackend(t *testing.T) {
+	ingressClient := clients.NewNetworkingV1IngressClient(fake.NewSimpleClientset())
+	b := NewNginxBackend(ingressClient)
+	ctx := context.TODO()
+
+	if _, err := b.GetStrategy(ctx, testModelName); !errors.IsNotFound(err) {
+		t.Errorf("Strategy without canary ingress: %v", err)
+	}
+	for _, isNewModel := range []bool{false, true} {
+		if _, err := b.Deploy(ctx, testModelName, isNewModel, "mini-serving.example.com"); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.CheckSlot(ctx, testModelName, isNewModel); err != nil {
+			t.Errorf("Slot is not routed: %v", err)
+		}
+	}
+
+	// nginx sends no request to the canary ingress without weight and header
+	strategy, err := b.GetStrategy(ctx, testModelName)
+	if err != nil || strategy.Strategy != constants.CurrentModelOnly {
+		t.Errorf("Wrong strategy of new canary ingress: %+v, %v", strategy, err)
+	}
+
+	tests := []Strategy{
+		{Strategy: constants.NewModelOnly},
+		{Strategy: constants.Canary, Weight: 30},
+		{Strategy: constants.Shadow},
+		{Strategy: constants.Targeted, Target: &registry.TargetRule{Header: "X-Client-Id", Values: []string{"alice", "bob"}, Cookie: "beta"}},
+		{Strategy: constants.CurrentModelOnly},
+	}
+	for _, test := range tests {
+		strategyStr, err := b.SetStrategy(ctx, testModelName, test)
+		if err != nil || strategyStr != StrategyName(test.Strategy) {
+			t.Errorf("%v: %v, %v", StrategyName(test.Strategy), strategyStr, err)
+		}
+		strategy, err := b.GetStrategy(ctx, testModelName)
+		if err != nil || !reflect.DeepEqual(*strategy, test) {
+			t.Errorf("Wrong strategy: got %+v, want %+v (%v)", strategy, test, err)
+		}
+	}
+
+	ingress, err := ingressClient.Get(ctx, constants.CanaryNamespace, registry.IngressName(testModelName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingress.Annotations["nginx.ingress.kubernetes.io/canary"] != "false" || len(ingress.Annotations) != 1 {
+		t.Errorf("Annotations of other strategies are left: %v", ingress.Annotations)
+	}
+
+	deleted, err := b.Undeploy(ctx, testModelName, true)
+	if err != nil || !reflect.DeepEqual(deleted, []string{"ingress/mnist-cnn-ingress"}) {
+		t.Errorf("Wrong deleted objects: %v, %v", deleted, err)
+	}
+	if err := b.CheckSlot(ctx, testModelName, true); !errors.IsNotFound(err) {
+		t.Errorf("Canary ingress is not deleted: %v", err)
+	}
+}
+
+func TestTargetValuesPattern(t *testing.T) {
+	if pattern := targetValuesPattern([]string{"a", "b.c"}); pattern != `^(a|b\.c)$` {
+		t.Errorf("Wrong pattern: %v", pattern)
+	}
+}
+
+func TestNginxBackendUpstreamHeader(t *testing.T) {
+	ingressClient := clients.NewNetworkingV1IngressClient(fake.NewSimpleClientset())
+	ctx := context.TODO()
+	for _, upstreamHeader := range []bool{false, true} {
+		b := NewNginxBackend(ingressClient)
+		if upstreamHeader {
+			b.EnableUpstreamHeader()
+		}
+		if _, err := b.Deploy(ctx, testModelName, false, "mini-serving.example.com"); err != nil {
+			t.Fatal(err)
+		}
+		ingress, err := ingressClient.Get(ctx, constants.ProdNamespace, registry.IngressName(testModelName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// ingress-nginx rejects the snippet unless snippet annotations are allowed
+		_, ok := ingress.Annotations["nginx.ingress.kubernetes.io/configuration-snippet"]
+		if ok != upstreamHeader || b.ReportsUpstream() != upstreamHeader {
+			t.Errorf("Upstream header %v: wrong annotations %v", upstreamHeader, ingress.Annotations)
+		}
+	}
+}
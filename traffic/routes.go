@@ -0,0 +1,244 @@
+package traffic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/registry"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// routingAnnotation stores routeState of the route object in JSON
+const routingAnnotation = "mini-mnist-serving/routing"
+
+// routeState stores the routed slots and the strategy of model, from which the spec of route object is built
+type routeState struct {
+	Host     string               `json:"host,omitempty"`
+	Prod     bool                 `json:"prod"`
+	Canary   bool                 `json:"canary"`
+	Strategy constants.Strategy   `json:"strategy"`
+	Weight   int                  `json:"weight,omitempty"`
+	Target   *registry.TargetRule `json:"target,omitempty"`
+}
+
+// routeRule sends the requests matching the header to the slots
+// Rules are matched in order, and the rule without header matches every request.
+type routeRule struct {
+	header string
+	value  string
+	// regex is true when value is a regular expression
+	regex bool
+	// canaryWeight is the percentage of matched requests sent to the new model
+	canaryWeight int
+}
+
+// routeRules returns the rules of strategy, which select the slot in the same way as nginx canary annotations
+func routeRules(state *routeState) []routeRule {
+	rules := []routeRule{}
+	switch state.Strategy {
+	case constants.NewModelOnly, constants.Shadow:
+		rules = append(rules, routeRule{header: constants.CanaryHeader, value: "always", canaryWeight: 100})
+	case constants.Targeted:
+		if state.Target.Header != "" {
+			for _, value := range state.Target.Values {
+				rules = append(rules, routeRule{header: state.Target.Header, value: value, canaryWeight: 100})
+			}
+		}
+		if state.Target.Cookie != "" {
+			rules = append(rules, routeRule{header: "Cookie", value: cookiePattern(state.Target.Cookie), regex: true, canaryWeight: 100})
+		}
+	case constants.Canary:
+		return append(rules, routeRule{canaryWeight: state.Weight})
+	}
+	return append(rules, routeRule{canaryWeight: 0})
+}
+
+// slotWeights returns the weights of routed slots, and omits the slots receiving no requests
+func slotWeights(state *routeState, rule routeRule) map[bool]int64 {
+	weights := make(map[bool]int64)
+	if state.Prod && rule.canaryWeight < 100 {
+		weights[false] = int64(100 - rule.canaryWeight)
+	}
+	if state.Canary && rule.canaryWeight > 0 {
+		weights[true] = int64(rule.canaryWeight)
+	}
+	return weights
+}
+
+// routes manages the route object of each model in the production namespace,
+// whose spec is built from routeState by buildSpec
+type routes struct {
+	client    dynamic.Interface
+	resource  schema.GroupVersionResource
+	kind      string
+	buildSpec func(modelName string, state *routeState) map[string]interface{}
+}
+
+// get returns the route object of model and its state
+func (r *routes) get(ctx context.Context, modelName string) (*unstructured.Unstructured, *routeState, error) {
+	obj, err := r.client.Resource(r.resource).Namespace(constants.ProdNamespace).Get(ctx, registry.RouteName(modelName), metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state := &routeState{}
+	if err := json.Unmarshal([]byte(obj.GetAnnotations()[routingAnnotation]), state); err != nil {
+		return nil, nil, fmt.Errorf("Invalid routing state of %v: %v", obj.GetName(), err)
+	}
+	return obj, state, nil
+}
+
+// getRouted returns the state of model, or NotFound error when the slot is not routed
+func (r *routes) getRouted(ctx context.Context, modelName string, isNewModel bool) (*unstructured.Unstructured, *routeState, error) {
+	obj, state, err := r.get(ctx, modelName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if (isNewModel && !state.Canary) || (!isNewModel && !state.Prod) {
+		return nil, nil, errors.NewNotFound(r.resource.GroupResource(), registry.RouteName(modelName))
+	}
+	return obj, state, nil
+}
+
+// save creates the route object of state when obj is nil, otherwise updates it
+func (r *routes) save(ctx context.Context, modelName string, obj *unstructured.Unstructured, state *routeState) error {
+	stateJson, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	client := r.client.Resource(r.resource).Namespace(constants.ProdNamespace)
+	create := obj == nil
+	if create {
+		obj = &unstructured.Unstructured{}
+		obj.SetAPIVersion(r.resource.GroupVersion().String())
+		obj.SetKind(r.kind)
+		obj.SetName(registry.RouteName(modelName))
+		obj.SetNamespace(constants.ProdNamespace)
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[routingAnnotation] = string(stateJson)
+	obj.SetAnnotations(annotations)
+	obj.Object["spec"] = r.buildSpec(modelName, state)
+
+	if create {
+		_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+	} else {
+		_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+	}
+	return err
+}
+
+// deploy routes the slot of model at the host
+// The new model receives no requests until the strategy is changed.
+func (r *routes) deploy(ctx context.Context, modelName string, isNewModel bool, host string) (string, error) {
+	obj, state, err := r.get(ctx, modelName)
+	message := "Updated"
+	if errors.IsNotFound(err) {
+		obj, state, err = nil, &routeState{Strategy: constants.CurrentModelOnly}, nil
+		message = "Created"
+	}
+	if err != nil {
+		return "", err
+	}
+
+	state.Host = host
+	if isNewModel {
+		state.Canary = true
+		state.Strategy = constants.CurrentModelOnly
+		state.Weight = 0
+		state.Target = nil
+	} else {
+		state.Prod = true
+	}
+	return message, r.save(ctx, modelName, obj, state)
+}
+
+// undeploy stops routing the slot of model, and deletes the route object without routed slots
+// It returns the deleted route object.
+func (r *routes) undeploy(ctx context.Context, modelName string, isNewModel bool) ([]string, error) {
+	deleted := []string{}
+	obj, state, err := r.get(ctx, modelName)
+	if errors.IsNotFound(err) {
+		return deleted, nil
+	}
+	if err != nil {
+		return deleted, err
+	}
+
+	if isNewModel {
+		state.Canary = false
+		state.Strategy = constants.CurrentModelOnly
+		state.Weight = 0
+		state.Target = nil
+	} else {
+		state.Prod = false
+	}
+	if state.Prod || state.Canary {
+		return deleted, r.save(ctx, modelName, obj, state)
+	}
+
+	err = r.client.Resource(r.resource).Namespace(constants.ProdNamespace).Delete(ctx, obj.GetName(), metav1.DeleteOptions{})
+	if err == nil {
+		deleted = append(deleted, strings.ToLower(r.kind)+"/"+obj.GetName())
+	} else if !errors.IsNotFound(err) {
+		return deleted, err
+	}
+	return deleted, nil
+}
+
+// CheckSlot returns NotFound error when the slot is not routed
+func (r *routes) CheckSlot(ctx context.Context, modelName string, isNewModel bool) error {
+	_, _, err := r.getRouted(ctx, modelName, isNewModel)
+	return err
+}
+
+// SetStrategy rebuilds the route object of model with the strategy
+func (r *routes) SetStrategy(ctx context.Context, modelName string, strategy Strategy) (string, error) {
+	if err := Validate(strategy); err != nil {
+		return "", err
+	}
+	obj, state, err := r.getRouted(ctx, modelName, true)
+	if err != nil {
+		return "", err
+	}
+
+	state.Strategy = strategy.Strategy
+	state.Weight = 0
+	state.Target = nil
+	if strategy.Strategy == constants.Canary {
+		state.Weight = strategy.Weight
+	} else if strategy.Strategy == constants.Targeted {
+		state.Target = strategy.Target
+	}
+	return StrategyName(strategy.Strategy), r.save(ctx, modelName, obj, state)
+}
+
+// GetStrategy returns the strategy stored in the route object of model
+func (r *routes) GetStrategy(ctx context.Context, modelName string) (*Strategy, error) {
+	_, state, err := r.getRouted(ctx, modelName, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Strategy{Strategy: state.Strategy, Weight: state.Weight, Target: state.Target}, nil
+}
+
+// createIfNotExists creates obj unless the object of the same name exists
+func createIfNotExists(ctx context.Context, client dynamic.ResourceInterface, obj *unstructured.Unstructured) (string, error) {
+	_, err := client.Create(ctx, obj, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		return "Already exists", nil
+	}
+	return "Created", err
+}
@@ -0,0 +1,150 @@
+package traffic
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/josh9191/mini-mnist-serving/constants"
+	"github.com/josh9191/mini-mnist-serving/registry"
+)
+
+const (
+	// Nginx is the name of backend using nginx ingress canary annotations
+	Nginx = "nginx"
+	// Gateway is the name of backend using Gateway API HTTPRoute
+	Gateway = "gateway"
+	// Istio is the name of backend using Istio VirtualService and DestinationRule
+	Istio = "istio"
+)
+
+// Strategy stores how the prediction requests of a model are split between the current and new models
+type Strategy struct {
+	Strategy constants.Strategy
+	// Weight is the percentage of requests sent to the new model with Canary strategy
+	Weight int
+	// Target selects the requests sent to the new model with Targeted strategy
+	Target *registry.TargetRule
+}
+
+// Backend routes the prediction requests of models to their current (prod) and new (canary) slots.
+// Every backend serves registry.IngressPath of the model at the host, rewritten to the REST API of Tensorflow Serving,
+// and selects the slot by the strategy in the same way:
+//   - CurrentModelOnly: the current model
+//   - NewModelOnly and Shadow: the new model when constants.CanaryHeader is "always", otherwise the current model
+//   - Canary: the new model with the weight
+//   - Targeted: the new model when the target header or cookie matches, otherwise the current model
+//
+// The constants.CanaryUpstreamHeader response header is set only when the new model serves the request
+// by the backends reporting the upstream.
+// Missing objects are reported by errors satisfying k8s.io/apimachinery/pkg/api/errors.IsNotFound.
+type Backend interface {
+	// Name returns the name of backend
+	Name() string
+	// ReportsUpstream reports whether constants.CanaryUpstreamHeader tells which model served the request
+	// Otherwise the server selects the slot by itself to know which model serves the prediction.
+	ReportsUpstream() bool
+	// Deploy creates or updates the routing objects of the slot and returns what is done
+	Deploy(ctx context.Context, modelName string, isNewModel bool, host string) (string, error)
+	// CheckSlot returns NotFound error when the slot is not routed
+	CheckSlot(ctx context.Context, modelName string, isNewModel bool) error
+	// SetStrategy changes the strategy of model and returns its name
+	// It returns NotFound error when the new model is not routed.
+	SetStrategy(ctx context.Context, modelName string, strategy Strategy) (string, error)
+	// GetStrategy returns the strategy of model
+	// It returns NotFound error when the new model is not routed.
+	GetStrategy(ctx context.Context, modelName string) (*Strategy, error)
+	// Undeploy deletes the routing objects of the slot and returns the deleted objects
+	// Every request is sent to the current model before the new model is removed.
+	Undeploy(ctx context.Context, modelName string, isNewModel bool) ([]string, error)
+}
+
+// StrategyName returns the name of strategy shown to users
+func StrategyName(strategy constants.Strategy) string {
+	switch strategy {
+	case constants.CurrentModelOnly:
+		return "Current Model Only"
+	case constants.NewModelOnly:
+		return "New Model Only"
+	case constants.Canary:
+		return "Canary"
+	case constants.Shadow:
+		return "Shadow"
+	case constants.Targeted:
+		return "Targeted"
+	default:
+		return "None"
+	}
+}
+
+// Validate checks the weight and the target rule of strategy
+func Validate(strategy Strategy) error {
+	switch strategy.Strategy {
+	case constants.CurrentModelOnly, constants.NewModelOnly, constants.Shadow:
+		return nil
+	case constants.Canary:
+		if strategy.Weight < 0 || strategy.Weight > 100 {
+			return fmt.Errorf("Invalid weight: %d", strategy.Weight)
+		}
+		return nil
+	case constants.Targeted:
+		return ValidateTarget(strategy.Target)
+	default:
+		return fmt.Errorf("Unknown strategy: %d", strategy.Strategy)
+	}
+}
+
+// validTargetName matches the header and cookie names of target rules
+var validTargetName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidateTarget checks that the target rule selects requests by a header or a cookie
+func ValidateTarget(target *registry.TargetRule) error {
+	if target == nil || (target.Header == "" && target.Cookie == "") {
+		return fmt.Errorf("Target missing.")
+	}
+	if target.Header != "" {
+		if !validTargetName.MatchString(target.Header) {
+			return fmt.Errorf("Invalid target header: %v", target.Header)
+		}
+		if len(target.Values) == 0 {
+			return fmt.Errorf("Values of target header missing.")
+		}
+		for _, value := range target.Values {
+			if value == "" {
+				return fmt.Errorf("Empty value of target header.")
+			}
+		}
+	}
+	if target.Cookie != "" && !validTargetName.MatchString(target.Cookie) {
+		return fmt.Errorf("Invalid target cookie: %v", target.Cookie)
+	}
+	return nil
+}
+
+// cookiePattern returns the regular expression of Cookie header selecting the new model by cookie
+// It matches the whole header, because proxies differ in whether the regular expression matches a part of the value.
+func cookiePattern(cookie string) string {
+	return fmt.Sprintf(`^(.*;\s*)?%s=always(;.*)?$`, regexp.QuoteMeta(cookie))
+}
+
+// predictPath returns the path of the REST API of Tensorflow Serving
+func predictPath(modelName string) string {
+	return fmt.Sprintf("/v1/models/%s:predict", modelName)
+}
+
+// upstreamName returns the value of constants.CanaryUpstreamHeader set by backends
+func upstreamName(modelName string) string {
+	return fmt.Sprintf("%s-%s-8501", constants.CanaryNamespace, registry.ServiceName(modelName))
+}
+
+// serviceHost returns the cluster DNS name of the service of slot
+func serviceHost(modelName string, isNewModel bool) string {
+	return fmt.Sprintf("%s.%s.svc.cluster.local", registry.ServiceName(modelName), slotNamespace(isNewModel))
+}
+
+func slotNamespace(isNewModel bool) string {
+	if isNewModel {
+		return constants.CanaryNamespace
+	}
+	return constants.ProdNamespace
+}